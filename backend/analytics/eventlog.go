@@ -0,0 +1,523 @@
+/*
+================================================================================
+REPUTATION EVENT LOG - P2P Academic Library "The Knowledge Exchange"
+================================================================================
+This file implements the persisted, ordered log that reputation is derived
+from, plus snapshots for compaction and deterministic replay.
+
+Events store their raw inputs (rating score and weight, bytes delivered)
+rather than only the delta, so the same log can be replayed under a
+different ReputationPolicy.
+
+Files (in the data directory):
+- reputation_events.jsonl: Events after the latest snapshot, one per line
+- reputation_events.archive.jsonl: Events folded into a snapshot
+- reputation_snapshot.json: Scores as of the last compaction
+
+Go Concepts Used:
+- File I/O: Append-only JSON lines, synced to disk before use
+- bufio.Reader: Reading the log line by line
+- Mutex: Serializing appends and compaction
+- Pure functions: Replay without side effects
+================================================================================
+*/
+
+package analytics
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// CONSTANTS
+// ============================================================================
+
+const (
+	eventLogFile     = "reputation_events.jsonl"
+	eventArchiveFile = "reputation_events.archive.jsonl"
+	snapshotFile     = "reputation_snapshot.json"
+)
+
+// ============================================================================
+// LOG TYPES
+// ============================================================================
+
+// ReputationSnapshot holds every student's score up to a sequence number
+type ReputationSnapshot struct {
+	Seq       uint64                    `json:"seq"`
+	Scores    map[string]float64        `json:"scores"`
+	Activity  map[string]ActivityCounts `json:"activity,omitempty"`
+	CreatedAt time.Time                 `json:"created_at"`
+}
+
+// ActivityCounts tallies a student's shared and downloaded files
+type ActivityCounts struct {
+	Uploads   int `json:"uploads"`
+	Downloads int `json:"downloads"`
+}
+
+// AuditEntry is one event in a student's reputation trail
+type AuditEntry struct {
+	Event      ReputationEvent `json:"event"`
+	Delta      float64         `json:"delta"`       // Delta under the active policy
+	ScoreAfter float64         `json:"score_after"` // Score after the event
+}
+
+// ============================================================================
+// EVENT LOG
+// ============================================================================
+
+// EventLog is an append-only, ordered log of reputation events
+// With an empty directory the log is kept in memory only
+type EventLog struct {
+	dir string
+
+	// tail holds events after the snapshot
+	tail []ReputationEvent
+
+	// archive holds compacted events when running in memory only
+	archive []ReputationEvent
+
+	snapshot *ReputationSnapshot
+	lastSeq  uint64
+	file     *os.File
+	mutex    sync.Mutex
+}
+
+// NewMemoryEventLog creates an event log that is not persisted
+func NewMemoryEventLog() *EventLog {
+	return &EventLog{}
+}
+
+// OpenEventLog opens (or creates) the event log in a directory
+// A record cut off by a crash at the end of a file is truncated away with
+// a warning; a corrupt record anywhere else is an error
+// Returns:
+//   - *EventLog: The loaded log
+//   - error: If the directory or existing files can't be read
+func OpenEventLog(dir string) (*EventLog, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	l := &EventLog{dir: dir}
+
+	data, err := os.ReadFile(filepath.Join(dir, snapshotFile))
+	if err == nil {
+		var snapshot ReputationSnapshot
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			return nil, fmt.Errorf("invalid reputation snapshot: %w", err)
+		}
+		l.snapshot = &snapshot
+		l.lastSeq = snapshot.Seq
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if _, err := repairEvents(filepath.Join(dir, eventArchiveFile)); err != nil {
+		return nil, err
+	}
+	tail, err := repairEvents(filepath.Join(dir, eventLogFile))
+	if err != nil {
+		return nil, err
+	}
+	for _, event := range tail {
+		if event.Seq <= l.lastSeq {
+			continue // Already folded into the snapshot
+		}
+		l.tail = append(l.tail, event)
+		l.lastSeq = event.Seq
+	}
+
+	l.file, err = os.OpenFile(filepath.Join(dir, eventLogFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Append assigns the next sequence number to an event and persists it
+// Returns:
+//   - ReputationEvent: The event as stored
+//   - error: If the event couldn't be written (it is still kept in memory)
+func (l *EventLog) Append(event ReputationEvent) (ReputationEvent, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.lastSeq++
+	event.Seq = l.lastSeq
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	l.tail = append(l.tail, event)
+
+	if l.file == nil {
+		return event, nil
+	}
+
+	line, err := json.Marshal(event)
+	if err != nil {
+		return event, err
+	}
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		return event, err
+	}
+	return event, l.file.Sync()
+}
+
+// Tail returns the events recorded after the snapshot
+func (l *EventLog) Tail() []ReputationEvent {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	events := make([]ReputationEvent, len(l.tail))
+	copy(events, l.tail)
+	return events
+}
+
+// All returns every event ever recorded, archived ones included
+func (l *EventLog) All() ([]ReputationEvent, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var archived []ReputationEvent
+	if l.dir == "" {
+		archived = l.archive
+	} else {
+		var err error
+		archived, _, err = readEvents(filepath.Join(l.dir, eventArchiveFile))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// An interrupted compaction can leave an event in both files;
+	// keep only strictly increasing sequence numbers
+	events := make([]ReputationEvent, 0, len(archived)+len(l.tail))
+	var last uint64
+	for _, group := range [][]ReputationEvent{archived, l.tail} {
+		for _, event := range group {
+			if event.Seq > last {
+				events = append(events, event)
+				last = event.Seq
+			}
+		}
+	}
+	return events, nil
+}
+
+// Snapshot returns the latest snapshot, if any
+func (l *EventLog) Snapshot() (ReputationSnapshot, bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.snapshot == nil {
+		return ReputationSnapshot{}, false
+	}
+	return *l.snapshot, true
+}
+
+// LastSeq returns the sequence number of the newest event
+func (l *EventLog) LastSeq() uint64 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.lastSeq
+}
+
+// Compact records a snapshot of scores and activity as of the newest event
+// and moves the tail into the archive, so startup only replays later events
+func (l *EventLog) Compact(scores map[string]float64, activity map[string]ActivityCounts) (ReputationSnapshot, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	snapshot := ReputationSnapshot{
+		Seq:       l.lastSeq,
+		Scores:    make(map[string]float64, len(scores)),
+		Activity:  make(map[string]ActivityCounts, len(activity)),
+		CreatedAt: time.Now(),
+	}
+	for id, score := range scores {
+		snapshot.Scores[id] = score
+	}
+	for id, counts := range activity {
+		snapshot.Activity[id] = counts
+	}
+
+	if l.dir == "" {
+		l.archive = append(l.archive, l.tail...)
+		l.tail = nil
+		l.snapshot = &snapshot
+		return snapshot, nil
+	}
+
+	// Archive first so no event is ever only in the snapshot; duplicates
+	// from an interrupted compaction are dropped by sequence number
+	if err := appendEvents(filepath.Join(l.dir, eventArchiveFile), l.tail); err != nil {
+		return snapshot, err
+	}
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return snapshot, err
+	}
+	tmp := filepath.Join(l.dir, snapshotFile+".tmp")
+	if err := writeSynced(tmp, data); err != nil {
+		return snapshot, err
+	}
+	if err := os.Rename(tmp, filepath.Join(l.dir, snapshotFile)); err != nil {
+		return snapshot, err
+	}
+	if err := syncDir(l.dir); err != nil {
+		return snapshot, err
+	}
+
+	if err := l.file.Truncate(0); err != nil {
+		return snapshot, err
+	}
+	if err := l.file.Sync(); err != nil {
+		return snapshot, err
+	}
+
+	l.tail = nil
+	l.snapshot = &snapshot
+	return snapshot, nil
+}
+
+// Close closes the underlying log file
+func (l *EventLog) Close() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// ============================================================================
+// REPLAY
+// ============================================================================
+
+// ReplayEvents folds events into scores under a policy
+// The same events, baseline and policy always produce the same scores
+// Parameters:
+//   - events: Events in sequence order
+//   - policy: Policy used to turn raw inputs into deltas
+//   - baseline: Starting scores (e.g. a snapshot); nil starts everyone at the default
+func ReplayEvents(events []ReputationEvent, policy ReputationPolicy, baseline map[string]float64) map[string]float64 {
+	scores := make(map[string]float64, len(baseline))
+	for id, score := range baseline {
+		scores[id] = score
+	}
+
+	for _, event := range events {
+		current, known := scores[event.StudentID]
+		if !known {
+			current = policy.DefaultReputation
+		}
+		scores[event.StudentID] = policy.Clamp(current + policy.EventDelta(event, current))
+	}
+	return scores
+}
+
+// CountActivity folds upload and download events into per-student tallies
+// Parameters:
+//   - events: Events in sequence order
+//   - baseline: Starting tallies (e.g. a snapshot); may be nil
+func CountActivity(events []ReputationEvent, baseline map[string]ActivityCounts) map[string]ActivityCounts {
+	activity := make(map[string]ActivityCounts, len(baseline))
+	for id, counts := range baseline {
+		activity[id] = counts
+	}
+
+	for _, event := range events {
+		countActivity(activity, event)
+	}
+	return activity
+}
+
+// countActivity adds an upload or download event to the tallies
+// Deliveries count as uploads for the peer that served them and downloads
+// for the peer that received them
+func countActivity(activity map[string]ActivityCounts, event ReputationEvent) {
+	counts := activity[event.StudentID]
+	switch event.Type {
+	case EventUpload, EventContribution:
+		counts.Uploads++
+	case EventDownload, EventConsumption:
+		counts.Downloads++
+	default:
+		return
+	}
+	activity[event.StudentID] = counts
+}
+
+// EventDelta returns the reputation change an event causes under the policy
+// Parameters:
+//   - event: The event with its raw inputs
+//   - current: The student's score before the event (used by decay)
+func (p ReputationPolicy) EventDelta(event ReputationEvent, current float64) float64 {
+	switch event.Type {
+	case EventUpload:
+		return p.UploadBonus
+	case EventDownload:
+		return -p.DownloadPenalty
+	case EventContribution:
+		return capped(event.Value/bytesPerMB*p.ContributionBonusPerMB, p.MaxContributionBonus)
+	case EventConsumption:
+		return -capped(event.Value/bytesPerMB*p.ConsumptionPenaltyPerMB, p.MaxConsumptionPenalty)
+	case EventRating:
+		delta, _ := p.RatingDelta(event.Value)
+		return delta * event.Weight
+	case EventLeeching:
+		return -p.LeecherPenalty
+	case EventInactivity:
+		return p.DecayDelta(current)
+	case EventAdjustment:
+		return event.Value
+	default:
+		// Other event types carry their recorded delta directly
+		return event.Delta
+	}
+}
+
+// capped limits value to max
+func capped(value, max float64) float64 {
+	if value > max {
+		return max
+	}
+	return value
+}
+
+// ============================================================================
+// FILE HELPERS
+// ============================================================================
+
+// readEvents reads a JSON lines event file; a missing file is empty
+// A final record without its newline was cut off mid-write and is left out
+// Returns:
+//   - []ReputationEvent: The complete records
+//   - int64: Length of the file up to the end of the last complete record
+//   - error: If the file can't be read or a complete record is corrupt
+func readEvents(path string) ([]ReputationEvent, int64, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	var events []ReputationEvent
+	var size int64
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return events, size, nil // Anything in line is a torn record
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if record := bytes.TrimSpace(line); len(record) > 0 {
+			var event ReputationEvent
+			if err := json.Unmarshal(record, &event); err != nil {
+				return nil, 0, fmt.Errorf("corrupt event in %s at byte %d: %w", filepath.Base(path), size, err)
+			}
+			events = append(events, event)
+		}
+		size += int64(len(line))
+	}
+}
+
+// repairEvents reads an event file and truncates a record cut off by a
+// crash at its end, so later appends start on a fresh line
+func repairEvents(path string) ([]ReputationEvent, error) {
+	events, size, err := readEvents(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return events, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > size {
+		log.Printf("Warning: truncating %d bytes of an incomplete event at the end of %s",
+			info.Size()-size, filepath.Base(path))
+		if err := os.Truncate(path, size); err != nil {
+			return nil, err
+		}
+	}
+	return events, nil
+}
+
+// appendEvents appends events to a JSON lines file
+func appendEvents(path string, events []ReputationEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	for _, event := range events {
+		line, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		writer.Write(line)
+		writer.WriteByte('\n')
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// writeSynced writes a file and syncs it to disk
+func writeSynced(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// syncDir syncs a directory so a rename in it survives a crash
+func syncDir(dir string) error {
+	file, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return file.Sync()
+}
@@ -0,0 +1,433 @@
+/*
+================================================================================
+REPUTATION EVENT LOG TESTS - P2P Academic Library "The Knowledge Exchange"
+================================================================================
+Replays a fixed event sequence and checks that scores come out the same
+every time: live and replayed, before and after a snapshot and compaction,
+and after a restart from disk. Recomputing under a changed policy must
+give the scores that policy prescribes, and a record torn by a crash must
+not keep the log from opening.
+================================================================================
+*/
+
+package analytics
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"knowledge-exchange/models"
+)
+
+// ============================================================================
+// HELPERS
+// ============================================================================
+
+// compactAfter is how many fixedEvents are recorded before compacting
+const compactAfter = 8
+
+// fixedEvents is a sequence touching every policy-driven event type,
+// including caps, a reversal and clamping at the maximum
+func fixedEvents() []ReputationEvent {
+	at := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	event := func(i int, eventType, studentID string, value, weight float64) ReputationEvent {
+		return ReputationEvent{
+			Type:      eventType,
+			StudentID: studentID,
+			Value:     value,
+			Weight:    weight,
+			Timestamp: at.Add(time.Duration(i) * time.Minute),
+		}
+	}
+
+	return []ReputationEvent{
+		event(1, EventContribution, "alice", 4*bytesPerMB, 0),
+		event(2, EventConsumption, "bob", 4*bytesPerMB, 0),
+		event(3, EventRating, "alice", 5, 1),
+		event(4, EventRating, "bob", 1, 0.5),
+		event(5, EventLeeching, "carol", 0, 0),
+		event(6, EventAdjustment, "alice", 1.5, 0),
+		event(7, EventInactivity, "carol", 0, 0),
+		event(8, EventRating, "bob", 1, -0.5), // Reverses event 4
+		event(9, EventContribution, "alice", 40*bytesPerMB, 0),
+		event(10, EventConsumption, "bob", 40*bytesPerMB, 0),
+		event(11, EventAdjustment, "carol", -1, 0),
+		event(12, EventInactivity, "carol", 0, 0),
+	}
+}
+
+// changedPolicy raises every weight and switches to exponential decay
+func changedPolicy() ReputationPolicy {
+	policy := DefaultReputationPolicy()
+	policy.GoodRatingBonus = 1.0
+	policy.BadRatingPenalty = 0.5
+	policy.LeecherPenalty = 2.0
+	policy.ContributionBonusPerMB = 0.1
+	policy.MaxContributionBonus = 3.0
+	policy.ConsumptionPenaltyPerMB = 0.05
+	policy.MaxConsumptionPenalty = 1.0
+	policy.Decay.Curve = DecayExponential
+	policy.Decay.Fraction = 0.5
+	return policy
+}
+
+// openService creates a reputation service on the event log in dir
+func openService(t *testing.T, dir string) (*ReputationService, *EventLog) {
+	t.Helper()
+
+	eventLog, err := OpenEventLog(dir)
+	if err != nil {
+		t.Fatalf("open event log: %v", err)
+	}
+	t.Cleanup(func() { eventLog.Close() })
+
+	rs := NewReputationService(models.NewPeerRegistry())
+	rs.UseEventLog(eventLog)
+	return rs, eventLog
+}
+
+// record applies events in order, as the Record methods do
+func record(rs *ReputationService, events []ReputationEvent) {
+	for _, event := range events {
+		rs.applyEvent(event)
+	}
+}
+
+// currentState copies the service's derived scores and activity
+func currentState(rs *ReputationService) (map[string]float64, map[string]ActivityCounts) {
+	rs.mutex.RLock()
+	defer rs.mutex.RUnlock()
+
+	scores := make(map[string]float64, len(rs.scores))
+	for id, score := range rs.scores {
+		scores[id] = score
+	}
+	activity := make(map[string]ActivityCounts, len(rs.activity))
+	for id, counts := range rs.activity {
+		activity[id] = counts
+	}
+	return scores, activity
+}
+
+// assertIdentical fails unless both score sets are bit-for-bit equal
+func assertIdentical(t *testing.T, what string, got, want map[string]float64) {
+	t.Helper()
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("%s: scores differ\n got: %v\nwant: %v", what, got, want)
+	}
+}
+
+// assertScores fails unless scores match the expected values
+func assertScores(t *testing.T, what string, got, want map[string]float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: got scores for %d students, want %d: %v", what, len(got), len(want), got)
+	}
+	for id, score := range want {
+		if math.Abs(got[id]-score) > 1e-9 {
+			t.Errorf("%s: %s scored %.6f, want %.6f", what, id, got[id], score)
+		}
+	}
+}
+
+// Scores for fixedEvents under DefaultReputationPolicy:
+//
+//	alice 5 +0.2 +0.3 +1.5 +1.0 (capped)         = 8.0
+//	bob   5 -0.04 -0.1 +0.1 -0.2 (capped)        = 4.76
+//	carol 5 -0.5 -0.1 -1 -0.1 (linear decay)     = 3.3
+var defaultPolicyScores = map[string]float64{"alice": 8.0, "bob": 4.76, "carol": 3.3}
+
+// ============================================================================
+// TESTS
+// ============================================================================
+
+// TestReplayIsDeterministic records the sequence twice and replays it twice
+func TestReplayIsDeterministic(t *testing.T) {
+	events := fixedEvents()
+
+	first, firstLog := openService(t, t.TempDir())
+	record(first, events)
+	second, _ := openService(t, t.TempDir())
+	record(second, events)
+
+	live, _ := currentState(first)
+	other, _ := currentState(second)
+	assertIdentical(t, "second run", other, live)
+	assertScores(t, "live", live, defaultPolicyScores)
+
+	logged, err := firstLog.All()
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+	if len(logged) != len(events) {
+		t.Fatalf("log holds %d events, want %d", len(logged), len(events))
+	}
+	for i, event := range logged {
+		if event.Seq != uint64(i+1) {
+			t.Fatalf("event %d has sequence %d", i, event.Seq)
+		}
+	}
+
+	policy := first.GetPolicy()
+	assertIdentical(t, "first replay", ReplayEvents(logged, policy, nil), live)
+	assertIdentical(t, "second replay", ReplayEvents(logged, policy, nil), live)
+}
+
+// TestReplayAfterCompaction snapshots part-way, restarts from disk and
+// checks the snapshot plus tail gives the same scores as the full log
+func TestReplayAfterCompaction(t *testing.T) {
+	events := fixedEvents()
+	dir := t.TempDir()
+
+	rs, eventLog := openService(t, dir)
+	record(rs, events[:compactAfter])
+	snapshot, err := rs.Compact()
+	if err != nil {
+		t.Fatalf("compact: %v", err)
+	}
+	if snapshot.Seq != compactAfter {
+		t.Fatalf("snapshot at sequence %d, want %d", snapshot.Seq, compactAfter)
+	}
+	record(rs, events[compactAfter:])
+
+	live, liveActivity := currentState(rs)
+	assertScores(t, "live", live, defaultPolicyScores)
+	wantActivity := map[string]ActivityCounts{
+		"alice": {Uploads: 2},
+		"bob":   {Downloads: 2},
+	}
+	if !reflect.DeepEqual(liveActivity, wantActivity) {
+		t.Fatalf("activity %v, want %v", liveActivity, wantActivity)
+	}
+	eventLog.Close()
+
+	// Restart: the snapshot is the baseline and only the tail is replayed
+	restarted, reopened := openService(t, dir)
+	if tail := reopened.Tail(); len(tail) != len(events)-compactAfter {
+		t.Fatalf("tail holds %d events after compaction, want %d", len(tail), len(events)-compactAfter)
+	}
+	scores, activity := currentState(restarted)
+	assertIdentical(t, "after restart", scores, live)
+	if !reflect.DeepEqual(activity, liveActivity) {
+		t.Fatalf("activity after restart %v, want %v", activity, liveActivity)
+	}
+
+	// The archive plus tail still hold every event in order
+	all, err := reopened.All()
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+	if len(all) != len(events) {
+		t.Fatalf("log holds %d events after compaction, want %d", len(all), len(events))
+	}
+	assertIdentical(t, "full replay", ReplayEvents(all, restarted.GetPolicy(), nil), live)
+
+	replayed, err := restarted.Recompute()
+	if err != nil {
+		t.Fatalf("recompute: %v", err)
+	}
+	if replayed != len(events) {
+		t.Fatalf("recompute replayed %d events, want %d", replayed, len(events))
+	}
+	scores, _ = currentState(restarted)
+	assertIdentical(t, "recompute", scores, live)
+}
+
+// TestRecomputeUnderChangedPolicy swaps the policy and checks every score
+// is rebuilt from the raw event inputs
+func TestRecomputeUnderChangedPolicy(t *testing.T) {
+	events := fixedEvents()
+	dir := t.TempDir()
+
+	rs, eventLog := openService(t, dir)
+	record(rs, events[:compactAfter])
+	if _, err := rs.Compact(); err != nil {
+		t.Fatalf("compact: %v", err)
+	}
+	record(rs, events[compactAfter:])
+
+	if err := rs.SetPolicy(changedPolicy()); err != nil {
+		t.Fatalf("set policy: %v", err)
+	}
+
+	// Applied events keep their values until a recompute
+	scores, _ := currentState(rs)
+	assertScores(t, "before recompute", scores, defaultPolicyScores)
+
+	if _, err := rs.Recompute(); err != nil {
+		t.Fatalf("recompute: %v", err)
+	}
+
+	// alice 5 +0.4 +1 +1.5 +3 (capped) = 10.9, clamped to 10
+	// bob   5 -0.2 -0.25 +0.25 -1 (capped) = 3.8
+	// carol 5 -2 -1.5 (halved) -1 -0.25 (halved) = 0.25
+	want := map[string]float64{"alice": 10, "bob": 3.8, "carol": 0.25}
+	scores, _ = currentState(rs)
+	assertScores(t, "after recompute", scores, want)
+
+	// The recompute is snapshotted, so a restart keeps the new scores
+	eventLog.Close()
+	restarted, _ := openService(t, dir)
+	if err := restarted.SetPolicy(changedPolicy()); err != nil {
+		t.Fatalf("set policy: %v", err)
+	}
+	restartedScores, _ := currentState(restarted)
+	assertIdentical(t, "after restart", restartedScores, scores)
+}
+
+// TestConcurrentEventsAreNeverLost records far more events than any queue
+// would hold from many goroutines, stopping the service part-way, and
+// checks every one is logged and replays to the live scores
+func TestConcurrentEventsAreNeverLost(t *testing.T) {
+	rs, eventLog := openService(t, t.TempDir())
+	rs.Start()
+
+	const workers, perWorker = 8, 100
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				if w == 0 && i == perWorker/2 {
+					rs.Stop()
+				}
+				rs.RecordWeightedRating("alice", 5, 1)
+				rs.ReverseRating("alice", 5, 1, "Rating retracted")
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	logged, err := eventLog.All()
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+	if want := workers * perWorker * 2; len(logged) != want {
+		t.Fatalf("log holds %d events, want %d", len(logged), want)
+	}
+
+	live, _ := currentState(rs)
+	assertIdentical(t, "replay", ReplayEvents(logged, rs.GetPolicy(), nil), live)
+}
+
+// TestTornLastEventIsTruncated checks a record cut off at the end of the log
+// is dropped on open, while corruption before the end still fails
+func TestTornLastEventIsTruncated(t *testing.T) {
+	dir := t.TempDir()
+	events := fixedEvents()
+
+	rs, eventLog := openService(t, dir)
+	record(rs, events)
+	eventLog.Close()
+
+	// Simulate a crash partway through writing the next record
+	path := filepath.Join(dir, eventLogFile)
+	intact, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+	torn := append(append([]byte{}, intact...), []byte(`{"seq":13,"type":"rat`)...)
+	if err := os.WriteFile(path, torn, 0644); err != nil {
+		t.Fatalf("write torn log: %v", err)
+	}
+
+	rs, eventLog = openService(t, dir)
+	if got := len(eventLog.Tail()); got != len(events) {
+		t.Fatalf("loaded %d events, want the %d complete ones", got, len(events))
+	}
+	if repaired, _ := os.ReadFile(path); string(repaired) != string(intact) {
+		t.Fatalf("log is %d bytes after open, want the torn record truncated to %d", len(repaired), len(intact))
+	}
+	scores, _ := currentState(rs)
+	assertScores(t, "after torn record", scores, defaultPolicyScores)
+
+	// Appends carry on from the last complete record
+	event, err := eventLog.Append(ReputationEvent{Type: EventAdjustment, StudentID: "bob", Value: 0.5})
+	if err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if event.Seq != uint64(len(events)+1) {
+		t.Fatalf("appended event got seq %d, want %d", event.Seq, len(events)+1)
+	}
+	eventLog.Close()
+
+	reopened, err := OpenEventLog(dir)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if got := len(reopened.Tail()); got != len(events)+1 {
+		t.Fatalf("reopened log holds %d events, want %d", got, len(events)+1)
+	}
+	reopened.Close()
+
+	// A corrupt record followed by complete ones is not a crash artefact
+	lines, _ := os.ReadFile(path)
+	corrupt := append([]byte(`{"seq":0,"type":`+"\n"), lines...)
+	if err := os.WriteFile(path, corrupt, 0644); err != nil {
+		t.Fatalf("write corrupt log: %v", err)
+	}
+	if _, err := OpenEventLog(dir); err == nil {
+		t.Fatal("log with a corrupt record before the end opened without error")
+	}
+}
+
+// TestStudentFollowsActivePolicy checks registered students start at the
+// policy's default score and are marked leechers by the policy's rule
+func TestStudentFollowsActivePolicy(t *testing.T) {
+	rs, _ := openService(t, t.TempDir())
+	policy := DefaultReputationPolicy()
+	policy.DefaultReputation = 6.0
+	policy.LeecherMinDownloads = 1
+	if err := rs.SetPolicy(policy); err != nil {
+		t.Fatalf("set policy: %v", err)
+	}
+
+	dan := models.NewStudent("dan", "Dan", "127.0.0.1", 9000)
+	rs.peerRegistry.Register(dan)
+	rs.SyncStudent(dan)
+	if score := dan.Reputation(); score != policy.DefaultReputation {
+		t.Fatalf("new student scored %.2f, want the policy default %.2f", score, policy.DefaultReputation)
+	}
+
+	rs.RecordDelivery("alice", "dan", bytesPerMB)
+	if dan.Leecher() {
+		t.Fatal("one download with no uploads marked a leecher at leecher_min_downloads 1")
+	}
+	rs.RecordDelivery("alice", "dan", bytesPerMB)
+	if !dan.Leecher() {
+		t.Fatal("two downloads with no uploads not marked a leecher at leecher_min_downloads 1")
+	}
+}
+
+// TestStudentReadsDuringEvents reads registered students while events are
+// applied to them; run with -race to catch unguarded fields
+func TestStudentReadsDuringEvents(t *testing.T) {
+	rs, _ := openService(t, t.TempDir())
+	dan := models.NewStudent("dan", "Dan", "127.0.0.1", 9000)
+	rs.peerRegistry.Register(dan)
+	rs.SyncStudent(dan)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			rs.RecordDelivery("alice", "dan", bytesPerMB)
+		}
+	}()
+	for i := 0; i < 200; i++ {
+		rs.GetTopContributors(5)
+		rs.GetLeechers()
+		dan.Activity()
+	}
+	<-done
+
+	if _, downloads := dan.Activity(); downloads != 200 {
+		t.Fatalf("student shows %d downloads, want 200", downloads)
+	}
+}
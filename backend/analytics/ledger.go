@@ -0,0 +1,236 @@
+/*
+================================================================================
+TRANSFER LEDGER - P2P Academic Library "The Knowledge Exchange"
+================================================================================
+This file implements the bandwidth accounting ledger between peer pairs.
+
+Go Concepts Used:
+- Maps: Composite-key storage for (peer, counterparty) pairs
+- Structs: Ledger entries and per-peer totals
+- Mutex: Thread-safe accounting
+- Sorting: Ranking peers by contribution
+================================================================================
+*/
+
+package analytics
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// CONSTANTS
+// ============================================================================
+
+const (
+	// MaxShareRatio caps served/received ratios (and is used when a peer
+	// has served bytes but never received any)
+	MaxShareRatio = 10.0
+
+	// bytesPerMB converts byte counts for reputation weighting
+	bytesPerMB = 1024 * 1024
+)
+
+// ============================================================================
+// LEDGER TYPES
+// ============================================================================
+
+// LedgerEntry records traffic from one peer's point of view with one counterparty
+type LedgerEntry struct {
+	PeerID            string    `json:"peer_id"`
+	CounterpartyID    string    `json:"counterparty_id"`
+	BytesServed       int64     `json:"bytes_served"`
+	BytesReceived     int64     `json:"bytes_received"`
+	TransfersServed   int       `json:"transfers_served"`
+	TransfersReceived int       `json:"transfers_received"`
+	LastTransferAt    time.Time `json:"last_transfer_at"`
+}
+
+// PeerLedgerTotals aggregates a peer's traffic across all counterparties
+type PeerLedgerTotals struct {
+	PeerID         string  `json:"peer_id"`
+	BytesServed    int64   `json:"bytes_served"`
+	BytesReceived  int64   `json:"bytes_received"`
+	Counterparties int     `json:"counterparties"`
+	ShareRatio     float64 `json:"share_ratio"` // served / received, capped
+}
+
+// ============================================================================
+// TRANSFER LEDGER
+// ============================================================================
+
+// TransferLedger records bytes served and received per (peer, counterparty) pair
+type TransferLedger struct {
+	// entries maps peerID -> counterpartyID -> entry
+	entries map[string]map[string]*LedgerEntry
+
+	// mutex for thread-safe operations
+	mutex sync.RWMutex
+}
+
+// NewTransferLedger creates an empty ledger
+func NewTransferLedger() *TransferLedger {
+	return &TransferLedger{
+		entries: make(map[string]map[string]*LedgerEntry),
+	}
+}
+
+// RecordTransfer records a completed transfer between two peers
+// Both sides of the pair are updated so either peer's view is complete
+// Parameters:
+//   - serverID: Peer that sent the bytes
+//   - receiverID: Peer that received the bytes
+//   - bytes: Number of bytes delivered
+func (l *TransferLedger) RecordTransfer(serverID, receiverID string, bytes int64) {
+	if serverID == "" || receiverID == "" || serverID == receiverID || bytes <= 0 {
+		return
+	}
+
+	now := time.Now()
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	served := l.entryLocked(serverID, receiverID)
+	served.BytesServed += bytes
+	served.TransfersServed++
+	served.LastTransferAt = now
+
+	received := l.entryLocked(receiverID, serverID)
+	received.BytesReceived += bytes
+	received.TransfersReceived++
+	received.LastTransferAt = now
+}
+
+// entryLocked returns (creating if needed) the entry for a pair
+// Caller must hold l.mutex for writing
+func (l *TransferLedger) entryLocked(peerID, counterpartyID string) *LedgerEntry {
+	byCounterparty, exists := l.entries[peerID]
+	if !exists {
+		byCounterparty = make(map[string]*LedgerEntry)
+		l.entries[peerID] = byCounterparty
+	}
+
+	entry, exists := byCounterparty[counterpartyID]
+	if !exists {
+		entry = &LedgerEntry{PeerID: peerID, CounterpartyID: counterpartyID}
+		byCounterparty[counterpartyID] = entry
+	}
+	return entry
+}
+
+// ============================================================================
+// QUERY METHODS
+// ============================================================================
+
+// GetEntry returns the ledger entry for a pair from peerID's point of view
+func (l *TransferLedger) GetEntry(peerID, counterpartyID string) (LedgerEntry, bool) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	entry, exists := l.entries[peerID][counterpartyID]
+	if !exists {
+		return LedgerEntry{}, false
+	}
+	return *entry, true
+}
+
+// HasTransferred reports whether any bytes moved between two peers
+func (l *TransferLedger) HasTransferred(peerA, peerB string) bool {
+	entry, exists := l.GetEntry(peerA, peerB)
+	return exists && (entry.BytesServed > 0 || entry.BytesReceived > 0)
+}
+
+// GetCounterparties returns all entries for a peer, largest served first
+func (l *TransferLedger) GetCounterparties(peerID string) []LedgerEntry {
+	l.mutex.RLock()
+	entries := make([]LedgerEntry, 0, len(l.entries[peerID]))
+	for _, entry := range l.entries[peerID] {
+		entries = append(entries, *entry)
+	}
+	l.mutex.RUnlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].BytesServed > entries[j].BytesServed
+	})
+	return entries
+}
+
+// GetTotals returns a peer's aggregate traffic across all counterparties
+func (l *TransferLedger) GetTotals(peerID string) PeerLedgerTotals {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	return l.totalsLocked(peerID)
+}
+
+// totalsLocked aggregates a peer's entries
+// Caller must hold l.mutex
+func (l *TransferLedger) totalsLocked(peerID string) PeerLedgerTotals {
+	totals := PeerLedgerTotals{PeerID: peerID}
+	for _, entry := range l.entries[peerID] {
+		totals.BytesServed += entry.BytesServed
+		totals.BytesReceived += entry.BytesReceived
+		totals.Counterparties++
+	}
+	totals.ShareRatio = shareRatio(totals.BytesServed, totals.BytesReceived)
+	return totals
+}
+
+// GetAllTotals returns totals for every peer in the ledger, top servers first
+func (l *TransferLedger) GetAllTotals() []PeerLedgerTotals {
+	l.mutex.RLock()
+	result := make([]PeerLedgerTotals, 0, len(l.entries))
+	for peerID := range l.entries {
+		result = append(result, l.totalsLocked(peerID))
+	}
+	l.mutex.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].BytesServed > result[j].BytesServed
+	})
+	return result
+}
+
+// GetStats returns ledger statistics
+func (l *TransferLedger) GetStats() map[string]interface{} {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	var totalBytes int64
+	pairs := 0
+	for _, byCounterparty := range l.entries {
+		for _, entry := range byCounterparty {
+			totalBytes += entry.BytesServed
+			pairs++
+		}
+	}
+
+	return map[string]interface{}{
+		"peers":       len(l.entries),
+		"pairs":       pairs / 2,
+		"total_bytes": totalBytes,
+	}
+}
+
+// ============================================================================
+// HELPER FUNCTIONS
+// ============================================================================
+
+// shareRatio computes served/received capped at MaxShareRatio
+func shareRatio(served, received int64) float64 {
+	if received == 0 {
+		if served > 0 {
+			return MaxShareRatio
+		}
+		return 0
+	}
+
+	ratio := float64(served) / float64(received)
+	if ratio > MaxShareRatio {
+		ratio = MaxShareRatio
+	}
+	return ratio
+}
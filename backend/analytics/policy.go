@@ -0,0 +1,364 @@
+/*
+================================================================================
+REPUTATION POLICY - P2P Academic Library "The Knowledge Exchange"
+================================================================================
+This file defines the reputation policy: event weights, thresholds,
+bandwidth tier boundaries and inactivity decay, in one place.
+
+The package constants are the default policy. A policy can be loaded from
+a JSON file at startup and replaced at runtime by administrators.
+
+Go Concepts Used:
+- Structs: Grouped policy settings with JSON tags
+- Methods: Validation and derived values
+- JSON: Loading partial policies over the defaults
+- Error handling: Reporting every invalid setting
+================================================================================
+*/
+
+package analytics
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// CONSTANTS
+// ============================================================================
+
+// Inactivity decay curves
+const (
+	DecayNone        = "none"        // No inactivity decay
+	DecayLinear      = "linear"      // Fixed amount per interval
+	DecayExponential = "exponential" // Fixed fraction of the score above the minimum
+)
+
+const (
+	// Default inactivity settings
+	DefaultInactivityHours    = 24.0
+	DefaultDecayIntervalHours = 1.0
+	DefaultDecayFraction      = 0.02
+
+	// Default rating score boundaries for reputation effects
+	DefaultGoodRatingScore = 4.0
+	DefaultBadRatingScore  = 2.0
+
+	// Default upload/download ratio adjustments
+	DefaultLowRatio        = 0.5
+	DefaultLowRatioPenalty = 1.0
+	DefaultHighRatio       = 1.5
+	DefaultHighRatioBonus  = 0.5
+
+	// Default leecher rule: more than this many downloads per upload, or
+	// more than this many downloads with no uploads at all
+	DefaultLeecherDownloadRatio = 3.0
+	DefaultLeecherMinDownloads  = 5
+)
+
+// ============================================================================
+// POLICY TYPES
+// ============================================================================
+
+// TierPolicy defines the bandwidth tier boundaries and limits
+type TierPolicy struct {
+	// LeecherBelow is the reputation under which peers are throttled hardest
+	LeecherBelow float64 `json:"leecher_below"`
+
+	// PremiumAt is the reputation at which peers get premium bandwidth
+	PremiumAt float64 `json:"premium_at"`
+
+	// Bandwidth limits in bytes per second
+	LeecherBandwidth int64 `json:"leecher_bandwidth"`
+	NormalBandwidth  int64 `json:"normal_bandwidth"`
+	PremiumBandwidth int64 `json:"premium_bandwidth"`
+}
+
+// DecayPolicy defines how inactive peers lose reputation
+type DecayPolicy struct {
+	// Curve is one of DecayNone, DecayLinear, DecayExponential
+	Curve string `json:"curve"`
+
+	// InactiveAfterHours is how long a peer must be unseen before decaying
+	InactiveAfterHours float64 `json:"inactive_after_hours"`
+
+	// IntervalHours is how often decay is applied
+	IntervalHours float64 `json:"interval_hours"`
+
+	// Amount is the linear decay per interval
+	Amount float64 `json:"amount"`
+
+	// Fraction is the exponential decay per interval (0.0 - 1.0)
+	Fraction float64 `json:"fraction"`
+}
+
+// ReputationPolicy holds every tunable reputation setting
+type ReputationPolicy struct {
+	// Score bounds and access threshold
+	MinReputation     float64 `json:"min_reputation"`
+	MaxReputation     float64 `json:"max_reputation"`
+	DefaultReputation float64 `json:"default_reputation"`
+	DownloadThreshold float64 `json:"download_threshold"`
+
+	// Event weights
+	UploadBonus      float64 `json:"upload_bonus"`
+	DownloadPenalty  float64 `json:"download_penalty"`
+	GoodRatingBonus  float64 `json:"good_rating_bonus"`
+	BadRatingPenalty float64 `json:"bad_rating_penalty"`
+	LeecherPenalty   float64 `json:"leecher_penalty"`
+
+	// Rating scores at or beyond which a rating moves reputation
+	GoodRatingScore float64 `json:"good_rating_score"`
+	BadRatingScore  float64 `json:"bad_rating_score"`
+
+	// FileRatingShare is the fraction of a file rating passed to its owner
+	FileRatingShare float64 `json:"file_rating_share"`
+
+	// Byte-weighted contribution (per MB delivered, capped per transfer)
+	ContributionBonusPerMB  float64 `json:"contribution_bonus_per_mb"`
+	MaxContributionBonus    float64 `json:"max_contribution_bonus"`
+	ConsumptionPenaltyPerMB float64 `json:"consumption_penalty_per_mb"`
+	MaxConsumptionPenalty   float64 `json:"max_consumption_penalty"`
+
+	// Served/consumed ratio adjustments
+	LowRatio        float64 `json:"low_ratio"`
+	LowRatioPenalty float64 `json:"low_ratio_penalty"`
+	HighRatio       float64 `json:"high_ratio"`
+	HighRatioBonus  float64 `json:"high_ratio_bonus"`
+
+	// Download counts that mark a student as a leecher
+	LeecherDownloadRatio float64 `json:"leecher_download_ratio"`
+	LeecherMinDownloads  int     `json:"leecher_min_downloads"`
+
+	Tiers TierPolicy  `json:"tiers"`
+	Decay DecayPolicy `json:"decay"`
+}
+
+// ============================================================================
+// CONSTRUCTORS
+// ============================================================================
+
+// DefaultReputationPolicy returns the policy built from the package constants
+func DefaultReputationPolicy() ReputationPolicy {
+	return ReputationPolicy{
+		MinReputation:     MinReputation,
+		MaxReputation:     MaxReputation,
+		DefaultReputation: DefaultReputation,
+		DownloadThreshold: DownloadThreshold,
+
+		UploadBonus:      UploadBonus,
+		DownloadPenalty:  DownloadPenalty,
+		GoodRatingBonus:  GoodRatingBonus,
+		BadRatingPenalty: BadRatingPenalty,
+		LeecherPenalty:   LeecherPenalty,
+
+		GoodRatingScore: DefaultGoodRatingScore,
+		BadRatingScore:  DefaultBadRatingScore,
+		FileRatingShare: DefaultFileRatingShare,
+
+		ContributionBonusPerMB:  ContributionBonusPerMB,
+		MaxContributionBonus:    MaxContributionBonus,
+		ConsumptionPenaltyPerMB: ConsumptionPenaltyPerMB,
+		MaxConsumptionPenalty:   MaxConsumptionPenalty,
+
+		LowRatio:        DefaultLowRatio,
+		LowRatioPenalty: DefaultLowRatioPenalty,
+		HighRatio:       DefaultHighRatio,
+		HighRatioBonus:  DefaultHighRatioBonus,
+
+		LeecherDownloadRatio: DefaultLeecherDownloadRatio,
+		LeecherMinDownloads:  DefaultLeecherMinDownloads,
+
+		Tiers: TierPolicy{
+			LeecherBelow:     LeecherThreshold,
+			PremiumAt:        PremiumThreshold,
+			LeecherBandwidth: LeecherBandwidth,
+			NormalBandwidth:  NormalBandwidth,
+			PremiumBandwidth: PremiumBandwidth,
+		},
+		Decay: DecayPolicy{
+			Curve:              DecayLinear,
+			InactiveAfterHours: DefaultInactivityHours,
+			IntervalHours:      DefaultDecayIntervalHours,
+			Amount:             InactivityDecay,
+			Fraction:           DefaultDecayFraction,
+		},
+	}
+}
+
+// LoadReputationPolicy reads a policy from a JSON file
+// Settings missing from the file keep their default values
+func LoadReputationPolicy(path string) (ReputationPolicy, error) {
+	policy := DefaultReputationPolicy()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return policy, fmt.Errorf("failed to read policy: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return policy, fmt.Errorf("failed to parse policy: %w", err)
+	}
+
+	if err := policy.Validate(); err != nil {
+		return policy, err
+	}
+	return policy, nil
+}
+
+// ============================================================================
+// VALIDATION
+// ============================================================================
+
+// Validate checks that the policy is internally consistent
+// All problems are reported together
+func (p ReputationPolicy) Validate() error {
+	var problems []string
+	check := func(ok bool, msg string) {
+		if !ok {
+			problems = append(problems, msg)
+		}
+	}
+
+	check(p.MinReputation >= 0, "min_reputation must not be negative")
+	check(p.MaxReputation > p.MinReputation, "max_reputation must exceed min_reputation")
+	check(p.DefaultReputation >= p.MinReputation && p.DefaultReputation <= p.MaxReputation,
+		"default_reputation must lie within the reputation range")
+	check(p.DownloadThreshold >= p.MinReputation && p.DownloadThreshold <= p.MaxReputation,
+		"download_threshold must lie within the reputation range")
+
+	weights := []struct {
+		name  string
+		value float64
+	}{
+		{"upload_bonus", p.UploadBonus},
+		{"download_penalty", p.DownloadPenalty},
+		{"good_rating_bonus", p.GoodRatingBonus},
+		{"bad_rating_penalty", p.BadRatingPenalty},
+		{"leecher_penalty", p.LeecherPenalty},
+		{"contribution_bonus_per_mb", p.ContributionBonusPerMB},
+		{"max_contribution_bonus", p.MaxContributionBonus},
+		{"consumption_penalty_per_mb", p.ConsumptionPenaltyPerMB},
+		{"max_consumption_penalty", p.MaxConsumptionPenalty},
+		{"low_ratio_penalty", p.LowRatioPenalty},
+		{"high_ratio_bonus", p.HighRatioBonus},
+	}
+	for _, w := range weights {
+		check(w.value >= 0, w.name+" must not be negative (penalties are subtracted)")
+	}
+
+	check(p.BadRatingScore < p.GoodRatingScore, "bad_rating_score must be below good_rating_score")
+	check(p.FileRatingShare >= 0 && p.FileRatingShare <= 1, "file_rating_share must be between 0 and 1")
+	check(p.LowRatio >= 0 && p.LowRatio < p.HighRatio, "low_ratio must be below high_ratio")
+	check(p.LeecherDownloadRatio > 0, "leecher_download_ratio must be positive")
+	check(p.LeecherMinDownloads >= 0, "leecher_min_downloads must not be negative")
+
+	t := p.Tiers
+	check(t.LeecherBelow <= t.PremiumAt, "tiers.leecher_below must not exceed tiers.premium_at")
+	check(t.LeecherBandwidth > 0 && t.NormalBandwidth > 0 && t.PremiumBandwidth > 0,
+		"tier bandwidths must be positive")
+	check(t.LeecherBandwidth <= t.NormalBandwidth && t.NormalBandwidth <= t.PremiumBandwidth,
+		"tier bandwidths must increase from leecher to premium")
+
+	d := p.Decay
+	switch d.Curve {
+	case DecayNone:
+	case DecayLinear:
+		check(d.Amount >= 0, "decay.amount must not be negative")
+	case DecayExponential:
+		check(d.Fraction >= 0 && d.Fraction <= 1, "decay.fraction must be between 0 and 1")
+	default:
+		problems = append(problems, fmt.Sprintf("unknown decay curve: %s", d.Curve))
+	}
+	check(d.InactiveAfterHours > 0, "decay.inactive_after_hours must be positive")
+	check(d.IntervalHours > 0, "decay.interval_hours must be positive")
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid reputation policy: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// ============================================================================
+// DERIVED VALUES
+// ============================================================================
+
+// Clamp limits a score to the policy's reputation range
+func (p ReputationPolicy) Clamp(score float64) float64 {
+	if score < p.MinReputation {
+		return p.MinReputation
+	}
+	if score > p.MaxReputation {
+		return p.MaxReputation
+	}
+	return score
+}
+
+// IsLeecher reports whether a student's file counts make it a leecher
+// Parameters:
+//   - uploads: Files the student has shared
+//   - downloads: Files the student has downloaded
+func (p ReputationPolicy) IsLeecher(uploads, downloads int) bool {
+	if uploads == 0 {
+		return downloads > p.LeecherMinDownloads
+	}
+	return float64(downloads)/float64(uploads) > p.LeecherDownloadRatio
+}
+
+// RatingDelta returns the reputation change for a rating score
+// Neutral ratings return a zero delta
+func (p ReputationPolicy) RatingDelta(ratingScore float64) (float64, string) {
+	if ratingScore >= p.GoodRatingScore {
+		return p.GoodRatingBonus, "Received good rating"
+	} else if ratingScore <= p.BadRatingScore {
+		return -p.BadRatingPenalty, "Received bad rating"
+	}
+	return 0, ""
+}
+
+// DecayDelta returns the (negative) inactivity decay for a score
+func (p ReputationPolicy) DecayDelta(score float64) float64 {
+	switch p.Decay.Curve {
+	case DecayLinear:
+		return -p.Decay.Amount
+	case DecayExponential:
+		return -(score - p.MinReputation) * p.Decay.Fraction
+	default:
+		return 0
+	}
+}
+
+// InactiveAfter returns how long a peer must be unseen before decaying
+func (p ReputationPolicy) InactiveAfter() time.Duration {
+	return time.Duration(p.Decay.InactiveAfterHours * float64(time.Hour))
+}
+
+// DecayInterval returns how often inactivity decay runs
+func (p ReputationPolicy) DecayInterval() time.Duration {
+	return time.Duration(p.Decay.IntervalHours * float64(time.Hour))
+}
+
+// DetermineTier returns the bandwidth tier for a reputation score
+func (t TierPolicy) DetermineTier(reputation float64) BandwidthTier {
+	if reputation < t.LeecherBelow {
+		return TierLeecher
+	} else if reputation >= t.PremiumAt {
+		return TierPremium
+	}
+	return TierNormal
+}
+
+// Bandwidth returns the limit for a tier in bytes per second
+func (t TierPolicy) Bandwidth(tier BandwidthTier) int64 {
+	switch tier {
+	case TierLeecher:
+		return t.LeecherBandwidth
+	case TierPremium:
+		return t.PremiumBandwidth
+	default:
+		return t.NormalBandwidth
+	}
+}
@@ -0,0 +1,342 @@
+/*
+================================================================================
+RANKING - P2P Academic Library "The Knowledge Exchange"
+================================================================================
+This file implements confidence-aware ranking of files and contributors.
+
+Raw averages let a single 5-star rating outrank hundreds of 4.8-star ones.
+The ranking functions here shrink small samples toward a prior (Bayesian
+average) or use the lower bound of a confidence interval (Wilson score),
+and optionally decay old ratings so recent opinion counts more.
+
+Go Concepts Used:
+- Structs: Ranking options and ranked results
+- math package: Exponential decay and square roots
+- sort.SliceStable: Ordering results with stable tie-breaks
+- Slices: Pagination
+================================================================================
+*/
+
+package analytics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"knowledge-exchange/models"
+)
+
+// ============================================================================
+// CONSTANTS
+// ============================================================================
+
+// Ranking methods
+const (
+	RankAverage    = "average"    // Raw (decayed) average, no confidence adjustment
+	RankBayesian   = "bayesian"   // Average shrunk toward a prior mean
+	RankWilson     = "wilson"     // Lower bound of the Wilson score interval
+	RankReputation = "reputation" // Contributors only: raw reputation score
+)
+
+const (
+	// DefaultPriorWeight is how many pseudo-ratings the Bayesian prior counts as
+	DefaultPriorWeight = 5.0
+
+	// DefaultPriorMean is used when no ratings exist to derive a global mean
+	DefaultPriorMean = 3.0
+
+	// DefaultWilsonZ is the z-score for a 95% confidence interval
+	DefaultWilsonZ = 1.96
+
+	// DefaultRatingHalfLife is how long until a rating counts half as much
+	DefaultRatingHalfLife = 180 * 24 * time.Hour
+
+	// Pagination limits
+	DefaultPageSize = 10
+	MaxPageSize     = 100
+)
+
+// ============================================================================
+// RANKING TYPES
+// ============================================================================
+
+// RankingOptions configures how ratings are turned into a ranking score
+type RankingOptions struct {
+	// Method is one of RankAverage, RankBayesian, RankWilson (or RankReputation)
+	Method string `json:"method"`
+
+	// PriorWeight is the Bayesian prior's weight in pseudo-ratings
+	PriorWeight float64 `json:"prior_weight"`
+
+	// PriorMean is the Bayesian prior; 0 uses the global mean of the ranked set
+	PriorMean float64 `json:"prior_mean"`
+
+	// Z is the Wilson confidence z-score
+	Z float64 `json:"z"`
+
+	// HalfLife controls recency decay; 0 disables decay
+	HalfLife time.Duration `json:"half_life"`
+}
+
+// DefaultFileRankingOptions returns the options used for /api/files/top
+func DefaultFileRankingOptions() RankingOptions {
+	return RankingOptions{
+		Method:      RankWilson,
+		PriorWeight: DefaultPriorWeight,
+		Z:           DefaultWilsonZ,
+		HalfLife:    DefaultRatingHalfLife,
+	}
+}
+
+// DefaultContributorRankingOptions returns the options used for /api/reputation/top
+func DefaultContributorRankingOptions() RankingOptions {
+	return RankingOptions{
+		Method:      RankBayesian,
+		PriorWeight: DefaultPriorWeight,
+		Z:           DefaultWilsonZ,
+		HalfLife:    DefaultRatingHalfLife,
+	}
+}
+
+// Validate checks the options and fills in zero-valued defaults
+func (o *RankingOptions) Validate() error {
+	switch o.Method {
+	case RankAverage, RankBayesian, RankWilson, RankReputation:
+	default:
+		return fmt.Errorf("unknown ranking method: %s", o.Method)
+	}
+
+	if o.PriorWeight < 0 || o.PriorMean < 0 || o.Z < 0 || o.HalfLife < 0 {
+		return fmt.Errorf("ranking options must not be negative")
+	}
+	if o.PriorWeight == 0 {
+		o.PriorWeight = DefaultPriorWeight
+	}
+	if o.Z == 0 {
+		o.Z = DefaultWilsonZ
+	}
+	return nil
+}
+
+// RankedFile is a file with its ranking score
+type RankedFile struct {
+	File    *models.AcademicFile `json:"file"`
+	Score   float64              `json:"score"`
+	Ratings int                  `json:"ratings"`
+}
+
+// RankedContributor is a student with their ranking score
+type RankedContributor struct {
+	Student *models.Student `json:"student"`
+	Score   float64         `json:"score"`
+	Ratings int             `json:"ratings"`
+}
+
+// ============================================================================
+// SCORING
+// ============================================================================
+
+// weightedRatings sums decayed rating weights and scores
+// Returns:
+//   - float64: Sum of weight * score
+//   - float64: Sum of weights (the effective sample size)
+func weightedRatings(ratings []*models.Rating, halfLife time.Duration, now time.Time) (float64, float64) {
+	var scoreSum, weightSum float64
+	for _, r := range ratings {
+		weight := 1.0
+		if halfLife > 0 {
+			age := now.Sub(ratingTime(r))
+			if age > 0 {
+				weight = math.Pow(0.5, float64(age)/float64(halfLife))
+			}
+		}
+		scoreSum += weight * r.Score
+		weightSum += weight
+	}
+	return scoreSum, weightSum
+}
+
+// ratingTime returns when a rating last changed
+func ratingTime(r *models.Rating) time.Time {
+	if r.UpdatedAt.After(r.Timestamp) {
+		return r.UpdatedAt
+	}
+	return r.Timestamp
+}
+
+// ScoreRatings computes the ranking score of a set of ratings
+// Scores are on the 1-5 rating scale; 0 means no ratings
+// Parameters:
+//   - ratings: Ratings of a single target
+//   - opts: Validated ranking options
+//   - priorMean: Prior mean for the Bayesian method
+//   - now: Reference time for recency decay
+func ScoreRatings(ratings []*models.Rating, opts RankingOptions, priorMean float64, now time.Time) float64 {
+	scoreSum, n := weightedRatings(ratings, opts.HalfLife, now)
+
+	switch opts.Method {
+	case RankBayesian:
+		return (opts.PriorWeight*priorMean + scoreSum) / (opts.PriorWeight + n)
+	case RankWilson:
+		if n == 0 {
+			return 0
+		}
+		// Map the 1-5 average onto a 0-1 "positive" fraction
+		p := (scoreSum/n - models.MinRating) / (models.MaxRating - models.MinRating)
+		return models.MinRating + (models.MaxRating-models.MinRating)*wilsonLowerBound(p, n, opts.Z)
+	default:
+		if n == 0 {
+			return 0
+		}
+		return scoreSum / n
+	}
+}
+
+// wilsonLowerBound returns the lower bound of the Wilson score interval
+// for a positive fraction p observed over n trials
+func wilsonLowerBound(p, n, z float64) float64 {
+	z2 := z * z
+	centre := p + z2/(2*n)
+	margin := z * math.Sqrt(p*(1-p)/n+z2/(4*n*n))
+	return (centre - margin) / (1 + z2/n)
+}
+
+// priorMeanFor returns the configured prior or the mean of all given ratings
+func priorMeanFor(opts RankingOptions, byTarget map[string][]*models.Rating) float64 {
+	if opts.PriorMean > 0 {
+		return opts.PriorMean
+	}
+
+	var sum float64
+	count := 0
+	for _, ratings := range byTarget {
+		for _, r := range ratings {
+			sum += r.Score
+			count++
+		}
+	}
+	if count == 0 {
+		return DefaultPriorMean
+	}
+	return sum / float64(count)
+}
+
+// ============================================================================
+// RANKING
+// ============================================================================
+
+// RankFiles scores and orders all files in the index
+// Ties are broken by download count, then by name
+func (rs *RatingService) RankFiles(fileIndex *models.FileIndex, opts RankingOptions) ([]RankedFile, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if opts.Method == RankReputation {
+		return nil, fmt.Errorf("ranking method %s does not apply to files", opts.Method)
+	}
+
+	files := fileIndex.GetAllFiles()
+	byTarget := make(map[string][]*models.Rating, len(files))
+	for _, f := range files {
+		byTarget[f.CID] = rs.ratingStore.GetByTarget(f.CID)
+	}
+
+	prior := priorMeanFor(opts, byTarget)
+	now := time.Now()
+
+	ranked := make([]RankedFile, len(files))
+	for i, f := range files {
+		ratings := byTarget[f.CID]
+		ranked[i] = RankedFile{
+			File:    f,
+			Score:   ScoreRatings(ratings, opts, prior, now),
+			Ratings: len(ratings),
+		}
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		if ranked[i].File.DownloadCount != ranked[j].File.DownloadCount {
+			return ranked[i].File.DownloadCount > ranked[j].File.DownloadCount
+		}
+		return ranked[i].File.FileName < ranked[j].File.FileName
+	})
+	return ranked, nil
+}
+
+// RankContributors scores and orders all registered peers by the ratings
+// they received, or by raw reputation with RankReputation
+// Ties are broken by reputation score, then by name
+func (rs *RatingService) RankContributors(peerRegistry *models.PeerRegistry, opts RankingOptions) ([]RankedContributor, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	peers := peerRegistry.GetAllPeers()
+	byTarget := make(map[string][]*models.Rating, len(peers))
+	for _, p := range peers {
+		byTarget[p.ID] = rs.ratingStore.GetByTarget(p.ID)
+	}
+
+	prior := priorMeanFor(opts, byTarget)
+	now := time.Now()
+
+	ranked := make([]RankedContributor, len(peers))
+	reputations := make(map[string]float64, len(peers))
+	for i, p := range peers {
+		ratings := byTarget[p.ID]
+		reputations[p.ID] = p.Reputation()
+		score := reputations[p.ID]
+		if opts.Method != RankReputation {
+			score = ScoreRatings(ratings, opts, prior, now)
+		}
+		ranked[i] = RankedContributor{Student: p, Score: score, Ratings: len(ratings)}
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		if ri, rj := reputations[ranked[i].Student.ID], reputations[ranked[j].Student.ID]; ri != rj {
+			return ri > rj
+		}
+		return ranked[i].Student.Name < ranked[j].Student.Name
+	})
+	return ranked, nil
+}
+
+// ============================================================================
+// PAGINATION
+// ============================================================================
+
+// PageBounds returns the slice bounds for a 1-based page
+// Out-of-range pages yield an empty range; page sizes are clamped
+// Returns:
+//   - int: Start index (inclusive)
+//   - int: End index (exclusive)
+//   - int: Page size actually used
+func PageBounds(total, page, pageSize int) (int, int, int) {
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	if pageSize > MaxPageSize {
+		pageSize = MaxPageSize
+	}
+	if page < 1 {
+		page = 1
+	}
+
+	start := (page - 1) * pageSize
+	if start > total {
+		start = total
+	}
+	end := start + pageSize
+	if end > total {
+		end = total
+	}
+	return start, end, pageSize
+}
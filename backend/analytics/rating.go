@@ -32,6 +32,16 @@ const (
 	MaxRatingValue = 5.0
 )
 
+// ============================================================================
+// INTERFACES
+// ============================================================================
+
+// DownloadVerifier confirms that a peer actually obtained a file
+// Implemented by library.TransferManager
+type DownloadVerifier interface {
+	CompletedDownload(peerID, cid string) (transferID string, ok bool)
+}
+
 // ============================================================================
 // RATING SERVICE STRUCT
 // ============================================================================
@@ -44,6 +54,17 @@ type RatingService struct {
 	// reputationService for updating reputation on ratings
 	reputationService *ReputationService
 
+	// sybil discounts ratings from suspicious clusters (optional)
+	sybil *SybilDetector
+
+	// fileIndex and downloads gate file ratings on verified possession
+	fileIndex *models.FileIndex
+	downloads DownloadVerifier
+
+	// appliedEffects records who each rating's reputation effect went to
+	// and at what weight, so it can be reversed exactly
+	appliedEffects map[string]ratingEffect
+
 	// ratingChan for async rating submissions
 	ratingChan chan *models.Rating
 
@@ -56,11 +77,11 @@ type RatingService struct {
 	// stopChan signals the service to stop
 	stopChan chan struct{}
 
-	// Aggregated stats
-	totalFileRatings  int
-	totalPeerRatings  int
-	averageFileRating float64
-	averagePeerRating float64
+	// Aggregated stats, kept as sums so edits and deletions can be undone
+	totalFileRatings int
+	totalPeerRatings int
+	fileRatingSum    float64
+	peerRatingSum    float64
 }
 
 // ============================================================================
@@ -72,6 +93,7 @@ func NewRatingService(reputationService *ReputationService) *RatingService {
 	return &RatingService{
 		ratingStore:       models.NewRatingStore(),
 		reputationService: reputationService,
+		appliedEffects:    make(map[string]ratingEffect),
 		ratingChan:        make(chan *models.Rating, 100),
 		isRunning:         false,
 		stopChan:          make(chan struct{}),
@@ -143,6 +165,12 @@ func (rs *RatingService) RateFile(raterID, fileCID string, score float64, commen
 		return nil, fmt.Errorf("user has already rated this file")
 	}
 
+	// Require a completed download (or local copy) of a known file
+	transferID, err := rs.verifyFileAccess(raterID, fileCID)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create rating
 	rating := models.NewRating(
 		generateRatingID(raterID, fileCID),
@@ -152,6 +180,7 @@ func (rs *RatingService) RateFile(raterID, fileCID string, score float64, commen
 		score,
 		comment,
 	)
+	rating.TransferID = transferID
 
 	// Submit for async processing
 	rs.ratingChan <- rating
@@ -159,6 +188,58 @@ func (rs *RatingService) RateFile(raterID, fileCID string, score float64, commen
 	return rating, nil
 }
 
+// verifyFileAccess checks that the file exists and the rater obtained it
+// Returns:
+//   - string: ID of the completed transfer, or "" for local possession
+//   - error: Error if the file is unknown or the rater never obtained it
+func (rs *RatingService) verifyFileAccess(raterID, fileCID string) (string, error) {
+	rs.mutex.RLock()
+	fileIndex, downloads := rs.fileIndex, rs.downloads
+	rs.mutex.RUnlock()
+
+	if fileIndex == nil {
+		return "", nil // Verification not configured
+	}
+
+	file, exists := fileIndex.Get(fileCID)
+	if !exists {
+		return "", fmt.Errorf("file not found: %s", fileCID)
+	}
+
+	if file.OwnerID == raterID {
+		return "", fmt.Errorf("cannot rate your own file")
+	}
+
+	if downloads != nil {
+		if transferID, ok := downloads.CompletedDownload(raterID, fileCID); ok {
+			return transferID, nil
+		}
+	}
+
+	// Peers already seeding the file hold a verified local copy
+	for _, peerID := range file.PeerLocations {
+		if peerID == raterID {
+			return "", nil
+		}
+	}
+
+	return "", fmt.Errorf("you must download this file before rating it")
+}
+
+// SetFileIndex sets the index used to validate rated CIDs
+func (rs *RatingService) SetFileIndex(fileIndex *models.FileIndex) {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+	rs.fileIndex = fileIndex
+}
+
+// SetDownloadVerifier sets the source of completed-download records
+func (rs *RatingService) SetDownloadVerifier(verifier DownloadVerifier) {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+	rs.downloads = verifier
+}
+
 // RatePeer submits a rating for another peer
 func (rs *RatingService) RatePeer(raterID, targetPeerID string, score float64, comment string) (*models.Rating, error) {
 	// Validate score
@@ -209,31 +290,216 @@ func (rs *RatingService) applyRating(rating *models.Rating) error {
 	// Update statistics
 	if rating.TargetType == "file" {
 		rs.totalFileRatings++
-		rs.updateAverageFileRating(rating.Score)
+		rs.fileRatingSum += rating.Score
+		rs.syncFileAggregate(rating.TargetID)
 	} else {
 		rs.totalPeerRatings++
-		rs.updateAveragePeerRating(rating.Score)
+		rs.peerRatingSum += rating.Score
+	}
+
+	// Update reputation, discounted for suspicious raters
+	if rs.reputationService != nil {
+		if effect, ok := rs.resolveEffect(rating); ok {
+			rs.appliedEffects[rating.ID] = effect
+			rs.recordEffect(rating, effect)
+		}
+	}
+
+	return nil
+}
+
+// ratingEffect is the reputation impact a rating was applied with
+type ratingEffect struct {
+	studentID string  // Student whose reputation changed
+	weight    float64 // Multiplier the rating was applied with
+}
+
+// resolveEffect decides who a rating affects and at what weight
+// Peer ratings affect the rated peer; file ratings pass a share of their
+// effect on to the file's owner
+// Caller must hold rs.mutex
+func (rs *RatingService) resolveEffect(rating *models.Rating) (ratingEffect, bool) {
+	effect := ratingEffect{studentID: rating.TargetID, weight: 1.0}
+
+	if rating.TargetType == "file" {
+		if rs.fileIndex == nil {
+			return ratingEffect{}, false
+		}
+		file, exists := rs.fileIndex.Get(rating.TargetID)
+		if !exists || file.OwnerID == "" || file.OwnerID == rating.RaterID {
+			return ratingEffect{}, false
+		}
+		effect.studentID = file.OwnerID
+		effect.weight = rs.reputationService.GetFileRatingShare()
+	}
+
+	if rs.sybil != nil {
+		if rating.TargetType == "file" {
+			// File ratings are already backed by a verified download
+			effect.weight *= rs.sybil.RaterWeight(rating.RaterID)
+		} else {
+			effect.weight *= rs.sybil.RatingWeight(rating.RaterID, effect.studentID)
+		}
+	}
+	return effect, true
+}
+
+// recordEffect sends a rating's reputation event
+func (rs *RatingService) recordEffect(rating *models.Rating, effect ratingEffect) {
+	if rating.TargetType == "file" {
+		rs.reputationService.RecordFileRating(effect.studentID, rating.Score, effect.weight)
+	} else {
+		rs.reputationService.RecordWeightedRating(effect.studentID, rating.Score, effect.weight)
+	}
+}
+
+// SetSybilDetector enables Sybil-aware rating weights
+func (rs *RatingService) SetSybilDetector(detector *SybilDetector) {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+	rs.sybil = detector
+}
+
+// quarantineRatings reverses the reputation effect of the given peer ratings
+// Ratings stay in the store for auditing but no longer count
+func (rs *RatingService) quarantineRatings(ratingIDs []string) {
+	if len(ratingIDs) == 0 || rs.reputationService == nil {
+		return
+	}
+
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
 
-		// Update reputation for peer ratings
-		if rs.reputationService != nil {
-			rs.reputationService.RecordRating(rating.TargetID, rating.Score)
+	for _, id := range ratingIDs {
+		rating, exists := rs.ratingStore.Get(id)
+		if !exists {
+			continue
 		}
+
+		effect, applied := rs.appliedEffects[id]
+		if !applied || effect.weight == 0 {
+			continue
+		}
+
+		rs.reputationService.ReverseRating(effect.studentID, rating.Score, effect.weight, "Rating quarantined as suspicious")
+		effect.weight = 0
+		rs.appliedEffects[id] = effect
+	}
+}
+
+// ============================================================================
+// EDITING AND RETRACTION
+// ============================================================================
+
+// UpdateRating changes the score and comment of the rater's own rating
+// Aggregates are recomputed and the old reputation effect is replaced
+// Parameters:
+//   - raterID: ID of the student editing the rating
+//   - ratingID: ID of the rating to edit
+//   - score: New score (1-5)
+//   - comment: New comment
+//
+// Returns:
+//   - *models.Rating: The updated rating
+//   - error: If the rating doesn't exist, isn't the rater's or is invalid
+func (rs *RatingService) UpdateRating(raterID, ratingID string, score float64, comment string) (*models.Rating, error) {
+	if score < MinRatingValue || score > MaxRatingValue {
+		return nil, fmt.Errorf("score must be between %.0f and %.0f", MinRatingValue, MaxRatingValue)
+	}
+
+	if err := rs.checkRatingOwner(raterID, ratingID); err != nil {
+		return nil, err
+	}
+
+	old, updated, err := rs.ratingStore.Update(ratingID, score, comment)
+	if err != nil {
+		return nil, err
+	}
+
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+
+	delta := updated.Score - old.Score
+	if updated.TargetType == "file" {
+		rs.fileRatingSum += delta
+		rs.syncFileAggregate(updated.TargetID)
+	} else {
+		rs.peerRatingSum += delta
 	}
 
+	// Swap the old reputation effect for the new one at the same weight
+	if effect, applied := rs.appliedEffects[ratingID]; applied && effect.weight > 0 && rs.reputationService != nil {
+		rs.reputationService.ReverseRating(effect.studentID, old.Score, effect.weight, "Rating edited")
+		rs.recordEffect(updated, effect)
+	}
+
+	return updated, nil
+}
+
+// DeleteRating retracts the rater's own rating
+// Aggregates are recomputed and its reputation effect is reversed;
+// the rating's audit history is kept
+func (rs *RatingService) DeleteRating(raterID, ratingID string) error {
+	if err := rs.checkRatingOwner(raterID, ratingID); err != nil {
+		return err
+	}
+
+	old, err := rs.ratingStore.Delete(ratingID)
+	if err != nil {
+		return err
+	}
+
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+
+	if old.TargetType == "file" {
+		rs.totalFileRatings--
+		rs.fileRatingSum -= old.Score
+		rs.syncFileAggregate(old.TargetID)
+	} else {
+		rs.totalPeerRatings--
+		rs.peerRatingSum -= old.Score
+	}
+
+	if effect, applied := rs.appliedEffects[ratingID]; applied && effect.weight > 0 && rs.reputationService != nil {
+		rs.reputationService.ReverseRating(effect.studentID, old.Score, effect.weight, "Rating retracted")
+	}
+	delete(rs.appliedEffects, ratingID)
+
 	return nil
 }
 
-// updateAverageFileRating recalculates average file rating
-func (rs *RatingService) updateAverageFileRating(newScore float64) {
-	// Incremental average calculation
-	oldTotal := rs.averageFileRating * float64(rs.totalFileRatings-1)
-	rs.averageFileRating = (oldTotal + newScore) / float64(rs.totalFileRatings)
+// GetRatingHistory returns the audit trail of a rating
+func (rs *RatingService) GetRatingHistory(ratingID string) []models.RatingRevision {
+	return rs.ratingStore.GetHistory(ratingID)
 }
 
-// updateAveragePeerRating recalculates average peer rating
-func (rs *RatingService) updateAveragePeerRating(newScore float64) {
-	oldTotal := rs.averagePeerRating * float64(rs.totalPeerRatings-1)
-	rs.averagePeerRating = (oldTotal + newScore) / float64(rs.totalPeerRatings)
+// checkRatingOwner verifies a rating exists and was given by raterID
+func (rs *RatingService) checkRatingOwner(raterID, ratingID string) error {
+	rating, exists := rs.ratingStore.Get(ratingID)
+	if !exists {
+		return fmt.Errorf("rating not found: %s", ratingID)
+	}
+	if rating.RaterID != raterID {
+		return fmt.Errorf("you can only change your own ratings")
+	}
+	return nil
+}
+
+// syncFileAggregate copies a file's rating stats onto its AcademicFile
+// Caller must hold rs.mutex
+func (rs *RatingService) syncFileAggregate(fileCID string) {
+	if rs.fileIndex == nil {
+		return
+	}
+
+	file, exists := rs.fileIndex.Get(fileCID)
+	if !exists {
+		return
+	}
+
+	stats := rs.ratingStore.GetStats(fileCID)
+	file.SetRatingAggregate(stats.AverageScore, stats.TotalRatings)
 }
 
 // ============================================================================
@@ -265,6 +531,11 @@ func (rs *RatingService) GetRatingsByRater(raterID string) []*models.Rating {
 	return rs.ratingStore.GetByRater(raterID)
 }
 
+// GetRatingStore returns the underlying rating store
+func (rs *RatingService) GetRatingStore() *models.RatingStore {
+	return rs.ratingStore
+}
+
 // HasUserRated checks if a user has rated a specific target
 func (rs *RatingService) HasUserRated(raterID, targetID string) bool {
 	return rs.ratingStore.HasRated(raterID, targetID)
@@ -298,24 +569,23 @@ func (rs *RatingService) GetAggregatedRating(targetID, targetType string) *Aggre
 	}
 }
 
-// GetTopRatedFiles returns the highest rated files
+// GetTopRatedFiles returns the highest rated files using the default
+// confidence-aware ranking
 func (rs *RatingService) GetTopRatedFiles(fileIndex *models.FileIndex, limit int) []*models.AcademicFile {
-	files := fileIndex.GetAllFiles()
-
-	// Sort by average rating (bubble sort for simplicity)
-	for i := 0; i < len(files)-1; i++ {
-		for j := 0; j < len(files)-i-1; j++ {
-			if files[j].AverageRating < files[j+1].AverageRating {
-				files[j], files[j+1] = files[j+1], files[j]
-			}
-		}
+	ranked, err := rs.RankFiles(fileIndex, DefaultFileRankingOptions())
+	if err != nil {
+		return nil
 	}
 
-	if limit > len(files) {
-		limit = len(files)
+	if limit > len(ranked) {
+		limit = len(ranked)
 	}
 
-	return files[:limit]
+	files := make([]*models.AcademicFile, limit)
+	for i := range files {
+		files[i] = ranked[i].File
+	}
+	return files
 }
 
 // ============================================================================
@@ -330,8 +600,8 @@ func (rs *RatingService) GetGlobalStats() map[string]interface{} {
 	return map[string]interface{}{
 		"total_file_ratings":  rs.totalFileRatings,
 		"total_peer_ratings":  rs.totalPeerRatings,
-		"average_file_rating": rs.averageFileRating,
-		"average_peer_rating": rs.averagePeerRating,
+		"average_file_rating": average(rs.fileRatingSum, rs.totalFileRatings),
+		"average_peer_rating": average(rs.peerRatingSum, rs.totalPeerRatings),
 		"total_ratings":       rs.ratingStore.Count(),
 		"is_running":          rs.isRunning,
 	}
@@ -349,5 +619,21 @@ func (rs *RatingService) ExportRatings(targetID string) ([]byte, error) {
 
 // generateRatingID creates a unique ID for a rating
 func generateRatingID(raterID, targetID string) string {
-	return fmt.Sprintf("rating-%s-%s-%d", raterID[:8], targetID[:8], time.Now().UnixNano())
+	return fmt.Sprintf("rating-%s-%s-%d", shortID(raterID), shortID(targetID), time.Now().UnixNano())
+}
+
+// shortID truncates an ID to at most 8 characters
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+// average returns sum/count, or 0 when there is nothing to average
+func average(sum float64, count int) float64 {
+	if count <= 0 {
+		return 0
+	}
+	return sum / float64(count)
 }
@@ -5,8 +5,8 @@ REPUTATION SERVICE - P2P Academic Library "The Knowledge Exchange"
 This file implements the reputation calculation engine for fair access control.
 
 Go Concepts Used:
-- Goroutines: Concurrent reputation updates
-- Channels: Asynchronous event processing
+- Goroutines: Background inactivity decay
+- Channels: Stopping background goroutines
 - Interfaces: Reputation source abstraction
 - Mutex: Thread-safe reputation storage
 ================================================================================
@@ -17,6 +17,8 @@ package analytics
 import (
 	"encoding/json"
 	"fmt"
+	"log"
+	"sort"
 	"sync"
 	"time"
 
@@ -27,6 +29,8 @@ import (
 // CONSTANTS
 // ============================================================================
 
+// These constants form DefaultReputationPolicy; the running service reads
+// its values from the active ReputationPolicy instead
 const (
 	// Reputation thresholds
 	MinReputation     = 0.0
@@ -41,6 +45,16 @@ const (
 	BadRatingPenalty = 0.2
 	LeecherPenalty   = 0.5
 	InactivityDecay  = 0.1
+
+	// Byte-weighted contribution values (per MB delivered, capped per transfer)
+	ContributionBonusPerMB  = 0.05
+	MaxContributionBonus    = 1.0
+	ConsumptionPenaltyPerMB = 0.01
+	MaxConsumptionPenalty   = 0.2
+
+	// DefaultFileRatingShare is the fraction of a file rating's effect
+	// passed on to the file's owner
+	DefaultFileRatingShare = 0.5
 )
 
 // ============================================================================
@@ -48,9 +62,14 @@ const (
 // ============================================================================
 
 // ReputationEvent represents an event that affects reputation
+// Value and Weight hold the raw inputs so the delta can be recomputed
+// under a different policy; Delta is the change applied when recorded
 type ReputationEvent struct {
+	Seq       uint64    `json:"seq"`
 	Type      string    `json:"type"`
 	StudentID string    `json:"student_id"`
+	Value     float64   `json:"value,omitempty"`  // Rating score or bytes transferred
+	Weight    float64   `json:"weight,omitempty"` // Rating weight, negative for reversals
 	Delta     float64   `json:"delta"`
 	Reason    string    `json:"reason"`
 	Timestamp time.Time `json:"timestamp"`
@@ -58,12 +77,14 @@ type ReputationEvent struct {
 
 // Event types
 const (
-	EventUpload       = "UPLOAD"
-	EventDownload     = "DOWNLOAD"
+	EventUpload       = "UPLOAD"   // Flat bonus; only found in older logs
+	EventDownload     = "DOWNLOAD" // Flat penalty; only found in older logs
 	EventRating       = "RATING"
 	EventLeeching     = "LEECHING"
 	EventInactivity   = "INACTIVITY"
 	EventContribution = "CONTRIBUTION"
+	EventConsumption  = "CONSUMPTION"
+	EventAdjustment   = "ADJUSTMENT" // Manual change by an admin; Value is the delta
 )
 
 // ============================================================================
@@ -75,11 +96,23 @@ type ReputationService struct {
 	// peerRegistry for accessing peer data
 	peerRegistry *models.PeerRegistry
 
-	// eventChan receives reputation events for processing
-	eventChan chan ReputationEvent
+	// ledger records bytes served/received per peer pair
+	ledger *TransferLedger
+
+	// trust provides network-wide EigenTrust scores when enabled
+	trust *TrustManager
+
+	// policy holds the active event weights, thresholds and decay settings
+	policy ReputationPolicy
+
+	// eventLog is the ordered log reputation is derived from
+	eventLog *EventLog
+
+	// scores holds each student's score as derived from the event log
+	scores map[string]float64
 
-	// eventHistory stores all reputation events
-	eventHistory []ReputationEvent
+	// activity holds each student's upload/download tallies from the log
+	activity map[string]ActivityCounts
 
 	// mutex for thread-safe operations
 	mutex sync.RWMutex
@@ -87,6 +120,12 @@ type ReputationService struct {
 	// isRunning indicates if the service is active
 	isRunning bool
 
+	// stopped is set by Stop; the service can't be restarted afterwards
+	stopped bool
+
+	// runMutex guards isRunning and stopped
+	runMutex sync.RWMutex
+
 	// stopChan signals the service to stop
 	stopChan chan struct{}
 }
@@ -99,8 +138,11 @@ type ReputationService struct {
 func NewReputationService(peerRegistry *models.PeerRegistry) *ReputationService {
 	return &ReputationService{
 		peerRegistry: peerRegistry,
-		eventChan:    make(chan ReputationEvent, 100),
-		eventHistory: make([]ReputationEvent, 0),
+		ledger:       NewTransferLedger(),
+		policy:       DefaultReputationPolicy(),
+		eventLog:     NewMemoryEventLog(),
+		scores:       make(map[string]float64),
+		activity:     make(map[string]ActivityCounts),
 		isRunning:    false,
 		stopChan:     make(chan struct{}),
 	}
@@ -110,54 +152,45 @@ func NewReputationService(peerRegistry *models.PeerRegistry) *ReputationService
 // SERVICE LIFECYCLE
 // ============================================================================
 
-// Start begins the reputation processing goroutine
+// Start begins the inactivity decay goroutine
+// Events are applied as they are recorded, whether or not it is running
 func (rs *ReputationService) Start() {
-	if rs.isRunning {
+	rs.runMutex.Lock()
+	defer rs.runMutex.Unlock()
+
+	if rs.isRunning || rs.stopped {
 		return
 	}
 
 	rs.isRunning = true
 
-	// Start event processor goroutine
-	go rs.processEvents()
-
 	// Start decay checker goroutine
 	go rs.checkInactivityDecay()
 }
 
-// Stop stops the reputation service
+// Stop stops the inactivity decay goroutine
 func (rs *ReputationService) Stop() {
+	rs.runMutex.Lock()
+	defer rs.runMutex.Unlock()
+
 	if rs.isRunning {
 		rs.isRunning = false
+		rs.stopped = true
 		close(rs.stopChan)
-		close(rs.eventChan)
-	}
-}
-
-// processEvents handles reputation events from the channel
-func (rs *ReputationService) processEvents() {
-	for {
-		select {
-		case event, ok := <-rs.eventChan:
-			if !ok {
-				return
-			}
-			rs.applyEvent(event)
-		case <-rs.stopChan:
-			return
-		}
 	}
 }
 
 // checkInactivityDecay periodically applies decay to inactive peers
 func (rs *ReputationService) checkInactivityDecay() {
-	ticker := time.NewTicker(1 * time.Hour)
+	ticker := time.NewTicker(rs.GetPolicy().DecayInterval())
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
 			rs.applyInactivityDecay()
+			// Pick up interval changes from a swapped policy
+			ticker.Reset(rs.GetPolicy().DecayInterval())
 		case <-rs.stopChan:
 			return
 		}
@@ -170,30 +203,49 @@ func (rs *ReputationService) checkInactivityDecay() {
 
 // CalculateReputation calculates current reputation for a student
 // Based on upload/download ratio, ratings, and contributions
+// The ratio uses bytes actually delivered when the ledger has traffic for
+// the student, and falls back to upload/download counts otherwise.
+// When global trust is enabled and the student has a trust score, that
+// score replaces the local heuristic entirely.
 func (rs *ReputationService) CalculateReputation(student *models.Student) float64 {
-	base := student.ReputationScore
+	policy := rs.GetPolicy()
+
+	if trust := rs.getTrustManager(); trust != nil {
+		if score, ok := trust.TrustToReputation(student.ID); ok {
+			return policy.Clamp(score)
+		}
+	}
+
+	base := rs.scoreOf(student)
 
 	// Apply upload/download ratio factor
-	if student.TotalDownloads > 0 {
-		ratio := float64(student.TotalUploads) / float64(student.TotalDownloads)
-		if ratio < 0.5 {
+	ratio, hasRatio := rs.contributionRatio(student)
+	if hasRatio {
+		if ratio < policy.LowRatio {
 			// Downloading too much without uploading
-			base -= 1.0
-		} else if ratio > 1.5 {
+			base -= policy.LowRatioPenalty
+		} else if ratio > policy.HighRatio {
 			// Contributing more than taking
-			base += 0.5
+			base += policy.HighRatioBonus
 		}
 	}
 
 	// Clamp to valid range
-	if base < MinReputation {
-		base = MinReputation
-	}
-	if base > MaxReputation {
-		base = MaxReputation
+	return policy.Clamp(base)
+}
+
+// contributionRatio returns the student's served/consumed ratio
+// Returns false when there is no consumption to compare against
+func (rs *ReputationService) contributionRatio(student *models.Student) (float64, bool) {
+	totals := rs.ledger.GetTotals(student.ID)
+	if totals.BytesReceived > 0 {
+		return totals.ShareRatio, true
 	}
 
-	return base
+	if uploads, downloads := student.Activity(); downloads > 0 {
+		return float64(uploads) / float64(downloads), true
+	}
+	return 0, false
 }
 
 // CanDownload checks if a student has sufficient reputation
@@ -204,9 +256,10 @@ func (rs *ReputationService) CanDownload(studentID string) (bool, string) {
 	}
 
 	reputation := rs.CalculateReputation(student)
+	threshold := rs.GetPolicy().DownloadThreshold
 
-	if reputation < DownloadThreshold {
-		return false, fmt.Sprintf("Insufficient reputation (%.1f < %.1f)", reputation, DownloadThreshold)
+	if reputation < threshold {
+		return false, fmt.Sprintf("Insufficient reputation (%.1f < %.1f)", reputation, threshold)
 	}
 
 	return true, "Allowed"
@@ -216,53 +269,105 @@ func (rs *ReputationService) CanDownload(studentID string) (bool, string) {
 // EVENT SUBMISSION
 // ============================================================================
 
-// RecordUpload records an upload event
-func (rs *ReputationService) RecordUpload(studentID string) {
+// RecordDelivery records bytes delivered from one peer to another
+// The ledger is updated and reputation moves in proportion to the bytes,
+// rewarding the server and charging the receiver
+// Parameters:
+//   - serverID: Peer that served the file
+//   - receiverID: Peer that received the file
+//   - bytes: Number of bytes actually delivered
+func (rs *ReputationService) RecordDelivery(serverID, receiverID string, bytes int64) {
+	if bytes <= 0 || serverID == receiverID {
+		return
+	}
+
+	rs.ledger.RecordTransfer(serverID, receiverID, bytes)
+
+	megabytes := float64(bytes) / bytesPerMB
+	now := time.Now()
+
+	rs.applyEvent(ReputationEvent{
+		Type:      EventContribution,
+		StudentID: serverID,
+		Value:     float64(bytes),
+		Reason:    fmt.Sprintf("Served %.2f MB to %s", megabytes, receiverID),
+		Timestamp: now,
+	})
+
+	rs.applyEvent(ReputationEvent{
+		Type:      EventConsumption,
+		StudentID: receiverID,
+		Value:     float64(bytes),
+		Reason:    fmt.Sprintf("Received %.2f MB from %s", megabytes, serverID),
+		Timestamp: now,
+	})
+}
+
+// RecordRating records a rating event
+func (rs *ReputationService) RecordRating(studentID string, ratingScore float64) {
+	rs.RecordWeightedRating(studentID, ratingScore, 1.0)
+}
+
+// RecordWeightedRating records a rating event scaled by a trust weight
+// A weight of 0 (e.g. a quarantined rater) has no effect. Neutral ratings
+// are still logged so a later policy can give them weight.
+func (rs *ReputationService) RecordWeightedRating(studentID string, ratingScore, weight float64) {
+	if weight <= 0 {
+		return // Fully discounted rating, no effect
+	}
+
 	event := ReputationEvent{
-		Type:      EventUpload,
+		Type:      EventRating,
 		StudentID: studentID,
-		Delta:     UploadBonus,
-		Reason:    "Shared a file",
+		Value:     ratingScore,
+		Weight:    weight,
+		Reason:    "Received rating",
 		Timestamp: time.Now(),
 	}
-	rs.eventChan <- event
+	rs.applyEvent(event)
 }
 
-// RecordDownload records a download event
-func (rs *ReputationService) RecordDownload(studentID string) {
+// RecordFileRating passes a file rating on to the file's owner
+// Parameters:
+//   - ownerID: The student who shared the rated file
+//   - ratingScore: The rating value (1-5)
+//   - weight: Multiplier including the owner share (0.0 - 1.0)
+func (rs *ReputationService) RecordFileRating(ownerID string, ratingScore, weight float64) {
+	if weight <= 0 {
+		return
+	}
+
 	event := ReputationEvent{
-		Type:      EventDownload,
-		StudentID: studentID,
-		Delta:     -DownloadPenalty,
-		Reason:    "Downloaded a file",
+		Type:      EventRating,
+		StudentID: ownerID,
+		Value:     ratingScore,
+		Weight:    weight,
+		Reason:    "Received rating on shared file",
 		Timestamp: time.Now(),
 	}
-	rs.eventChan <- event
+	rs.applyEvent(event)
 }
 
-// RecordRating records a rating event
-func (rs *ReputationService) RecordRating(studentID string, ratingScore float64) {
-	var delta float64
-	var reason string
-
-	if ratingScore >= 4.0 {
-		delta = GoodRatingBonus
-		reason = "Received good rating"
-	} else if ratingScore <= 2.0 {
-		delta = -BadRatingPenalty
-		reason = "Received bad rating"
-	} else {
-		return // Neutral rating, no effect
+// ReverseRating undoes the effect of a previously recorded rating
+// Parameters:
+//   - studentID: The student who received the rating
+//   - ratingScore: The original rating score
+//   - weight: The weight the rating was originally applied with
+//   - reason: Why the rating is being reversed
+func (rs *ReputationService) ReverseRating(studentID string, ratingScore, weight float64, reason string) {
+	if weight <= 0 {
+		return
 	}
 
 	event := ReputationEvent{
 		Type:      EventRating,
 		StudentID: studentID,
-		Delta:     delta,
+		Value:     ratingScore,
+		Weight:    -weight,
 		Reason:    reason,
 		Timestamp: time.Now(),
 	}
-	rs.eventChan <- event
+	rs.applyEvent(event)
 }
 
 // RecordLeeching records a leeching penalty
@@ -270,44 +375,89 @@ func (rs *ReputationService) RecordLeeching(studentID string) {
 	event := ReputationEvent{
 		Type:      EventLeeching,
 		StudentID: studentID,
-		Delta:     -LeecherPenalty,
 		Reason:    "Detected as leecher",
 		Timestamp: time.Now(),
 	}
-	rs.eventChan <- event
+	rs.applyEvent(event)
+}
+
+// AdjustReputation applies a manual change made by an admin
+// Parameters:
+//   - studentID: The student whose score changes
+//   - delta: Amount to add (negative to subtract)
+//   - reason: Why the change was made; kept in the audit trail
+//
+// Returns:
+//   - float64: The student's score after the change
+func (rs *ReputationService) AdjustReputation(studentID string, delta float64, reason string) float64 {
+	return rs.applyEvent(ReputationEvent{
+		Type:      EventAdjustment,
+		StudentID: studentID,
+		Value:     delta,
+		Reason:    reason,
+		Timestamp: time.Now(),
+	})
 }
 
 // ============================================================================
 // EVENT APPLICATION
 // ============================================================================
 
-// applyEvent applies a reputation event to a student
-func (rs *ReputationService) applyEvent(event ReputationEvent) {
-	student, exists := rs.peerRegistry.Get(event.StudentID)
-	if !exists {
-		return
+// applyEvent appends an event to the log and folds it into the student's
+// derived score. Every event is applied as it is recorded, under the lock,
+// so none are lost and the log order is the order they took effect in.
+// Events for students not currently registered are still logged and take
+// effect when the student (re)joins.
+// Returns the student's score after the event
+func (rs *ReputationService) applyEvent(event ReputationEvent) float64 {
+	policy := rs.GetPolicy()
+
+	// Hold the lock across append and fold so log order matches apply order
+	rs.mutex.Lock()
+	current, known := rs.scores[event.StudentID]
+	if !known {
+		current = policy.DefaultReputation
 	}
+	event.Delta = policy.EventDelta(event, current)
 
-	// Apply the reputation change
-	student.UpdateReputation(event.Delta)
+	if _, err := rs.eventLog.Append(event); err != nil {
+		log.Printf("Reputation: failed to persist event: %v", err)
+	}
 
-	// Record in history
-	rs.mutex.Lock()
-	rs.eventHistory = append(rs.eventHistory, event)
+	score := policy.Clamp(current + event.Delta)
+	rs.scores[event.StudentID] = score
+	countActivity(rs.activity, event)
+	counts := rs.activity[event.StudentID]
+
+	// Mirror onto the student before unlocking so concurrent events for
+	// the same student can't leave an older score behind
+	if student, exists := rs.peerRegistry.Get(event.StudentID); exists {
+		student.SetReputation(score)
+		student.SetActivity(counts.Uploads, counts.Downloads, policy.IsLeecher(counts.Uploads, counts.Downloads))
+	}
 	rs.mutex.Unlock()
+
+	return score
 }
 
 // applyInactivityDecay applies reputation decay to inactive peers
 func (rs *ReputationService) applyInactivityDecay() {
+	policy := rs.GetPolicy()
+	if policy.Decay.Curve == DecayNone {
+		return
+	}
+
 	peers := rs.peerRegistry.GetAllPeers()
-	inactiveThreshold := 24 * time.Hour
+	inactiveThreshold := policy.InactiveAfter()
 
 	for _, peer := range peers {
-		if time.Since(peer.LastSeen) > inactiveThreshold {
+		if time.Since(peer.LastSeenAt()) > inactiveThreshold {
+			if policy.DecayDelta(rs.scoreOf(peer)) == 0 {
+				continue
+			}
 			event := ReputationEvent{
 				Type:      EventInactivity,
 				StudentID: peer.ID,
-				Delta:     -InactivityDecay,
 				Reason:    "Extended inactivity",
 				Timestamp: time.Now(),
 			}
@@ -316,6 +466,141 @@ func (rs *ReputationService) applyInactivityDecay() {
 	}
 }
 
+// ============================================================================
+// EVENT SOURCING
+// ============================================================================
+
+// UseEventLog replaces the event log and rebuilds every score from it
+// Scores start from the log's snapshot and replay the events after it
+// under the active policy
+func (rs *ReputationService) UseEventLog(eventLog *EventLog) {
+	var baseline map[string]float64
+	var baselineActivity map[string]ActivityCounts
+	if snapshot, ok := eventLog.Snapshot(); ok {
+		baseline = snapshot.Scores
+		baselineActivity = snapshot.Activity
+	}
+	tail := eventLog.Tail()
+	scores := ReplayEvents(tail, rs.GetPolicy(), baseline)
+	activity := CountActivity(tail, baselineActivity)
+
+	rs.mutex.Lock()
+	rs.eventLog = eventLog
+	rs.scores = scores
+	rs.activity = activity
+	rs.mutex.Unlock()
+
+	rs.syncStudents(scores, activity)
+}
+
+// Recompute replays the entire event log, archived events included, under
+// the active policy and snapshots the result
+// Returns:
+//   - int: Number of events replayed
+//   - error: If the log can't be read or the snapshot can't be written
+func (rs *ReputationService) Recompute() (int, error) {
+	policy := rs.GetPolicy()
+
+	// Block new events while the log is replayed
+	rs.mutex.Lock()
+	events, err := rs.eventLog.All()
+	if err != nil {
+		rs.mutex.Unlock()
+		return 0, err
+	}
+	scores := ReplayEvents(events, policy, nil)
+	activity := CountActivity(events, nil)
+	rs.scores = scores
+	rs.activity = activity
+	_, err = rs.eventLog.Compact(scores, activity)
+	rs.mutex.Unlock()
+
+	rs.syncStudents(scores, activity)
+	return len(events), err
+}
+
+// Compact snapshots current scores so startup replays fewer events
+func (rs *ReputationService) Compact() (ReputationSnapshot, error) {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+
+	return rs.eventLog.Compact(rs.scores, rs.activity)
+}
+
+// AuditStudent replays the full log under the active policy and returns
+// every event that touched the student with its delta and resulting score
+func (rs *ReputationService) AuditStudent(studentID string) ([]AuditEntry, error) {
+	events, err := rs.getEventLog().All()
+	if err != nil {
+		return nil, err
+	}
+
+	policy := rs.GetPolicy()
+	score := policy.DefaultReputation
+	var trail []AuditEntry
+	for _, event := range events {
+		if event.StudentID != studentID {
+			continue
+		}
+		delta := policy.EventDelta(event, score)
+		score = policy.Clamp(score + delta)
+		trail = append(trail, AuditEntry{Event: event, Delta: delta, ScoreAfter: score})
+	}
+	return trail, nil
+}
+
+// SyncStudent sets a (newly registered) student's score and activity
+// from the log; a student with no events starts at the policy's default
+func (rs *ReputationService) SyncStudent(student *models.Student) {
+	policy := rs.GetPolicy()
+
+	rs.mutex.RLock()
+	score, known := rs.scores[student.ID]
+	counts := rs.activity[student.ID]
+	rs.mutex.RUnlock()
+
+	if !known {
+		score = policy.DefaultReputation
+	}
+	student.SetReputation(score)
+	student.SetActivity(counts.Uploads, counts.Downloads, policy.IsLeecher(counts.Uploads, counts.Downloads))
+}
+
+// syncStudents mirrors derived scores and activity onto registered students
+func (rs *ReputationService) syncStudents(scores map[string]float64, activity map[string]ActivityCounts) {
+	policy := rs.GetPolicy()
+	for _, peer := range rs.peerRegistry.GetAllPeers() {
+		if score, known := scores[peer.ID]; known {
+			peer.SetReputation(score)
+		} else {
+			peer.SetReputation(policy.DefaultReputation)
+		}
+		counts := activity[peer.ID]
+		peer.SetActivity(counts.Uploads, counts.Downloads, policy.IsLeecher(counts.Uploads, counts.Downloads))
+	}
+}
+
+// scoreOf returns a student's derived score, falling back to the policy's
+// default for students with no events yet
+func (rs *ReputationService) scoreOf(student *models.Student) float64 {
+	policy := rs.GetPolicy()
+
+	rs.mutex.RLock()
+	defer rs.mutex.RUnlock()
+
+	if score, known := rs.scores[student.ID]; known {
+		return score
+	}
+	return policy.DefaultReputation
+}
+
+// getEventLog returns the active event log
+func (rs *ReputationService) getEventLog() *EventLog {
+	rs.mutex.RLock()
+	defer rs.mutex.RUnlock()
+	return rs.eventLog
+}
+
 // ============================================================================
 // QUERY METHODS
 // ============================================================================
@@ -329,13 +614,68 @@ func (rs *ReputationService) GetReputation(studentID string) (float64, error) {
 	return rs.CalculateReputation(student), nil
 }
 
-// GetEventHistory returns reputation events for a student
-func (rs *ReputationService) GetEventHistory(studentID string) []ReputationEvent {
+// Standing is the reputation and activity of a peer, or of all the peers
+// bound to one user account
+type Standing struct {
+	Reputation     float64 `json:"reputation"`
+	TotalUploads   int     `json:"total_uploads"`
+	TotalDownloads int     `json:"total_downloads"`
+}
+
+// GetStanding returns the combined standing of one or more peers
+// Counts are summed and reputation is the lowest of the peers, so an
+// account can't escape a low score by switching peers. Peers that aren't
+// registered right now are read straight from the event log.
+// With no peers the policy's default reputation is returned.
+func (rs *ReputationService) GetStanding(peerIDs ...string) Standing {
+	if len(peerIDs) == 0 {
+		return Standing{Reputation: rs.GetPolicy().DefaultReputation}
+	}
+
+	var standing Standing
+	for i, peerID := range peerIDs {
+		var reputation float64
+		if student, exists := rs.peerRegistry.Get(peerID); exists {
+			reputation = rs.CalculateReputation(student)
+		} else {
+			reputation = rs.loggedScore(peerID)
+		}
+
+		rs.mutex.RLock()
+		counts := rs.activity[peerID]
+		rs.mutex.RUnlock()
+
+		if i == 0 || reputation < standing.Reputation {
+			standing.Reputation = reputation
+		}
+		standing.TotalUploads += counts.Uploads
+		standing.TotalDownloads += counts.Downloads
+	}
+	return standing
+}
+
+// loggedScore returns a peer's score from the log, or the policy default
+func (rs *ReputationService) loggedScore(peerID string) float64 {
 	rs.mutex.RLock()
-	defer rs.mutex.RUnlock()
+	score, known := rs.scores[peerID]
+	rs.mutex.RUnlock()
+
+	if !known {
+		return rs.GetPolicy().DefaultReputation
+	}
+	return score
+}
+
+// GetEventHistory returns reputation events for a student, oldest first
+func (rs *ReputationService) GetEventHistory(studentID string) []ReputationEvent {
+	all, err := rs.getEventLog().All()
+	if err != nil {
+		log.Printf("Reputation: failed to read event log: %v", err)
+		all = rs.getEventLog().Tail()
+	}
 
 	var events []ReputationEvent
-	for _, event := range rs.eventHistory {
+	for _, event := range all {
 		if event.StudentID == studentID {
 			events = append(events, event)
 		}
@@ -347,15 +687,17 @@ func (rs *ReputationService) GetEventHistory(studentID string) []ReputationEvent
 func (rs *ReputationService) GetTopContributors(limit int) []*models.Student {
 	peers := rs.peerRegistry.GetAllPeers()
 
-	// Sort by reputation (simple bubble sort for demonstration)
-	for i := 0; i < len(peers)-1; i++ {
-		for j := 0; j < len(peers)-i-1; j++ {
-			if peers[j].ReputationScore < peers[j+1].ReputationScore {
-				peers[j], peers[j+1] = peers[j+1], peers[j]
-			}
-		}
+	// Read each score once so the sort sees a consistent order
+	scores := make(map[string]float64, len(peers))
+	for _, peer := range peers {
+		scores[peer.ID] = peer.Reputation()
 	}
 
+	// Sort by reputation, highest first
+	sort.SliceStable(peers, func(i, j int) bool {
+		return scores[peers[i].ID] > scores[peers[j].ID]
+	})
+
 	if limit > len(peers) {
 		limit = len(peers)
 	}
@@ -363,13 +705,58 @@ func (rs *ReputationService) GetTopContributors(limit int) []*models.Student {
 	return peers[:limit]
 }
 
+// SetTrustManager enables global trust scoring
+// Passing nil reverts to the local ratio heuristic
+func (rs *ReputationService) SetTrustManager(trust *TrustManager) {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+	rs.trust = trust
+}
+
+// getTrustManager returns the trust manager, or nil when disabled
+func (rs *ReputationService) getTrustManager() *TrustManager {
+	rs.mutex.RLock()
+	defer rs.mutex.RUnlock()
+	return rs.trust
+}
+
+// GetPolicy returns a copy of the active reputation policy
+func (rs *ReputationService) GetPolicy() ReputationPolicy {
+	rs.mutex.RLock()
+	defer rs.mutex.RUnlock()
+	return rs.policy
+}
+
+// SetPolicy validates and activates a new reputation policy
+// Events already applied keep the values they were applied with
+func (rs *ReputationService) SetPolicy(policy ReputationPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+	rs.policy = policy
+	return nil
+}
+
+// GetFileRatingShare returns the fraction of file ratings passed to owners
+func (rs *ReputationService) GetFileRatingShare() float64 {
+	return rs.GetPolicy().FileRatingShare
+}
+
+// GetLedger returns the transfer ledger
+func (rs *ReputationService) GetLedger() *TransferLedger {
+	return rs.ledger
+}
+
 // GetLeechers returns all students marked as leechers
 func (rs *ReputationService) GetLeechers() []*models.Student {
 	peers := rs.peerRegistry.GetAllPeers()
 	var leechers []*models.Student
 
 	for _, peer := range peers {
-		if peer.IsLeecher {
+		if peer.Leecher() {
 			leechers = append(leechers, peer)
 		}
 	}
@@ -381,23 +768,29 @@ func (rs *ReputationService) GetLeechers() []*models.Student {
 // SERIALIZATION
 // ============================================================================
 
-// ExportHistory exports reputation history as JSON
+// ExportHistory exports the full reputation event log as JSON
 func (rs *ReputationService) ExportHistory() ([]byte, error) {
-	rs.mutex.RLock()
-	defer rs.mutex.RUnlock()
-
-	return json.Marshal(rs.eventHistory)
+	events, err := rs.getEventLog().All()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(events)
 }
 
 // GetStats returns reputation service statistics
 func (rs *ReputationService) GetStats() map[string]interface{} {
+	rs.runMutex.RLock()
+	isRunning := rs.isRunning
+	rs.runMutex.RUnlock()
+
 	rs.mutex.RLock()
 	defer rs.mutex.RUnlock()
 
 	return map[string]interface{}{
-		"total_events":     len(rs.eventHistory),
-		"is_running":       rs.isRunning,
+		"total_events":     rs.eventLog.LastSeq(),
+		"is_running":       isRunning,
 		"top_contributors": len(rs.GetTopContributors(10)),
 		"leecher_count":    len(rs.GetLeechers()),
+		"ledger":           rs.ledger.GetStats(),
 	}
 }
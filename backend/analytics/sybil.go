@@ -0,0 +1,424 @@
+/*
+================================================================================
+SYBIL DETECTION - P2P Academic Library "The Knowledge Exchange"
+================================================================================
+This file detects suspicious rating clusters (Sybil accounts and rating rings)
+and down-weights or quarantines their ratings.
+
+Go Concepts Used:
+- Graphs: Rating relationships as adjacency maps
+- Union-Find: Grouping reciprocal raters into clusters
+- Goroutines: Periodic background analysis
+- Mutex: Thread-safe cluster and weight storage
+================================================================================
+*/
+
+package analytics
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"knowledge-exchange/models"
+)
+
+// ============================================================================
+// CONSTANTS
+// ============================================================================
+
+const (
+	// NewAccountAge is how long an account is considered freshly registered
+	NewAccountAge = 72 * time.Hour
+
+	// PositiveRatingScore is the minimum score counted as an endorsement
+	PositiveRatingScore = 4.0
+
+	// Cluster suspicion thresholds (score range 0.0 - 1.0)
+	DownWeightThreshold = 0.4
+	QuarantineThreshold = 0.7
+
+	// UnbackedRatingWeight scales ratings with no prior transfer between the pair
+	UnbackedRatingWeight = 0.5
+
+	// SybilScanInterval is how often the background analysis runs
+	SybilScanInterval = 15 * time.Minute
+
+	// Suspicion signal weights
+	ringSignalWeight     = 0.4
+	newAccountWeight     = 0.3
+	unbackedSignalWeight = 0.3
+)
+
+// Suspicion reasons
+const (
+	ReasonReciprocalRing  = "reciprocal_rating_ring"
+	ReasonNewAccounts     = "new_accounts"
+	ReasonUnbackedRatings = "ratings_without_transfer"
+)
+
+// ============================================================================
+// CLUSTER TYPES
+// ============================================================================
+
+// SuspiciousCluster is a group of peers whose ratings look coordinated
+type SuspiciousCluster struct {
+	ID          string    `json:"id"`
+	Members     []string  `json:"members"`
+	RatingIDs   []string  `json:"rating_ids"`
+	Reasons     []string  `json:"reasons"`
+	Score       float64   `json:"score"`
+	Weight      float64   `json:"weight"` // multiplier applied to members' ratings
+	Quarantined bool      `json:"quarantined"`
+	DetectedAt  time.Time `json:"detected_at"`
+}
+
+// ============================================================================
+// SYBIL DETECTOR STRUCT
+// ============================================================================
+
+// SybilDetector analyzes peer ratings for Sybil behaviour
+type SybilDetector struct {
+	ratingService *RatingService
+	ledger        *TransferLedger
+	peerRegistry  *models.PeerRegistry
+
+	// clusters from the last analysis
+	clusters []SuspiciousCluster
+
+	// raterWeights maps rater ID -> weight applied to their ratings
+	raterWeights map[string]float64
+
+	// quarantined holds rating IDs whose effect has already been reversed
+	quarantined map[string]bool
+
+	lastRun   time.Time
+	mutex     sync.RWMutex
+	isRunning bool
+	stopChan  chan struct{}
+}
+
+// NewSybilDetector creates a new SybilDetector
+func NewSybilDetector(ratingService *RatingService, ledger *TransferLedger, peerRegistry *models.PeerRegistry) *SybilDetector {
+	return &SybilDetector{
+		ratingService: ratingService,
+		ledger:        ledger,
+		peerRegistry:  peerRegistry,
+		raterWeights:  make(map[string]float64),
+		quarantined:   make(map[string]bool),
+		stopChan:      make(chan struct{}),
+	}
+}
+
+// ============================================================================
+// SERVICE LIFECYCLE
+// ============================================================================
+
+// Start begins periodic analysis
+func (sd *SybilDetector) Start() {
+	sd.mutex.Lock()
+	defer sd.mutex.Unlock()
+
+	if sd.isRunning {
+		return
+	}
+	sd.isRunning = true
+
+	go func() {
+		ticker := time.NewTicker(SybilScanInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				sd.Analyze()
+			case <-sd.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops periodic analysis
+func (sd *SybilDetector) Stop() {
+	sd.mutex.Lock()
+	defer sd.mutex.Unlock()
+
+	if sd.isRunning {
+		sd.isRunning = false
+		close(sd.stopChan)
+	}
+}
+
+// ============================================================================
+// ANALYSIS
+// ============================================================================
+
+// Analyze scans all peer ratings, rebuilds suspicious clusters and updates
+// rater weights. Ratings in newly quarantined clusters are reversed.
+// Returns:
+//   - []SuspiciousCluster: Clusters at or above DownWeightThreshold
+func (sd *SybilDetector) Analyze() []SuspiciousCluster {
+	var ratings []*models.Rating
+	for _, rating := range sd.ratingService.GetRatingStore().GetAll() {
+		if rating.TargetType == "student" {
+			ratings = append(ratings, rating)
+		}
+	}
+
+	// Build the endorsement graph and group reciprocal endorsers
+	endorses := make(map[string]map[string]bool)
+	for _, r := range ratings {
+		if r.Score >= PositiveRatingScore {
+			if endorses[r.RaterID] == nil {
+				endorses[r.RaterID] = make(map[string]bool)
+			}
+			endorses[r.RaterID][r.TargetID] = true
+		}
+	}
+
+	uf := newUnionFind()
+	for rater, targets := range endorses {
+		for target := range targets {
+			if endorses[target][rater] {
+				uf.union(rater, target)
+			}
+		}
+	}
+
+	// Raters with only unbacked ratings from new accounts form their own
+	// single-member groups so they can still be flagged
+	for _, r := range ratings {
+		uf.find(r.RaterID)
+	}
+
+	groups := uf.groups()
+	now := time.Now()
+
+	var clusters []SuspiciousCluster
+	weights := make(map[string]float64)
+
+	for _, members := range groups {
+		cluster := sd.scoreCluster(members, ratings)
+		if cluster.Score < DownWeightThreshold {
+			continue
+		}
+
+		cluster.ID = fmt.Sprintf("cluster-%s", cluster.Members[0])
+		cluster.DetectedAt = now
+		if cluster.Score >= QuarantineThreshold {
+			cluster.Quarantined = true
+			cluster.Weight = 0
+		} else {
+			cluster.Weight = 1 - cluster.Score
+		}
+
+		for _, member := range cluster.Members {
+			weights[member] = cluster.Weight
+		}
+		clusters = append(clusters, cluster)
+	}
+
+	sort.Slice(clusters, func(i, j int) bool {
+		return clusters[i].Score > clusters[j].Score
+	})
+
+	sd.mutex.Lock()
+	sd.clusters = clusters
+	sd.raterWeights = weights
+	sd.lastRun = now
+
+	// Collect ratings that need reversing outside the lock
+	var toReverse []string
+	for _, cluster := range clusters {
+		if !cluster.Quarantined {
+			continue
+		}
+		for _, id := range cluster.RatingIDs {
+			if !sd.quarantined[id] {
+				sd.quarantined[id] = true
+				toReverse = append(toReverse, id)
+			}
+		}
+	}
+	sd.mutex.Unlock()
+
+	sd.ratingService.quarantineRatings(toReverse)
+
+	return clusters
+}
+
+// scoreCluster computes the suspicion score for a group of raters
+func (sd *SybilDetector) scoreCluster(members []string, ratings []*models.Rating) SuspiciousCluster {
+	memberSet := make(map[string]bool, len(members))
+	for _, m := range members {
+		memberSet[m] = true
+	}
+	sort.Strings(members)
+
+	cluster := SuspiciousCluster{Members: members}
+
+	// Signal 1: reciprocal ring (only groups of 2+ come from unions)
+	isRing := len(members) > 1
+
+	// Signal 2: share of freshly registered members
+	newCount := 0
+	for _, m := range members {
+		if peer, exists := sd.peerRegistry.Get(m); exists && time.Since(peer.JoinedAt) < NewAccountAge {
+			newCount++
+		}
+	}
+	newFraction := float64(newCount) / float64(len(members))
+
+	// Signal 3: share of the group's ratings with no transfer behind them
+	total, unbacked := 0, 0
+	for _, r := range ratings {
+		if !memberSet[r.RaterID] {
+			continue
+		}
+		total++
+		cluster.RatingIDs = append(cluster.RatingIDs, r.ID)
+		if !sd.ledger.HasTransferred(r.RaterID, r.TargetID) {
+			unbacked++
+		}
+	}
+	unbackedFraction := 0.0
+	if total > 0 {
+		unbackedFraction = float64(unbacked) / float64(total)
+	}
+
+	if isRing {
+		cluster.Score += ringSignalWeight
+		cluster.Reasons = append(cluster.Reasons, ReasonReciprocalRing)
+	}
+	if newFraction > 0 {
+		cluster.Score += newAccountWeight * newFraction
+		cluster.Reasons = append(cluster.Reasons, ReasonNewAccounts)
+	}
+	if unbackedFraction > 0 {
+		cluster.Score += unbackedSignalWeight * unbackedFraction
+		cluster.Reasons = append(cluster.Reasons, ReasonUnbackedRatings)
+	}
+
+	return cluster
+}
+
+// ============================================================================
+// WEIGHTING
+// ============================================================================
+
+// RatingWeight returns the multiplier to apply to a peer rating
+// Flagged raters use their cluster weight; ratings without a prior
+// transfer between the pair are further discounted
+func (sd *SybilDetector) RatingWeight(raterID, targetID string) float64 {
+	weight := sd.RaterWeight(raterID)
+	if !sd.ledger.HasTransferred(raterID, targetID) {
+		weight *= UnbackedRatingWeight
+	}
+	return weight
+}
+
+// RaterWeight returns the cluster weight for a rater, 1.0 if not flagged
+func (sd *SybilDetector) RaterWeight(raterID string) float64 {
+	sd.mutex.RLock()
+	defer sd.mutex.RUnlock()
+
+	if weight, flagged := sd.raterWeights[raterID]; flagged {
+		return weight
+	}
+	return 1.0
+}
+
+// IsQuarantined reports whether a rater belongs to a quarantined cluster
+func (sd *SybilDetector) IsQuarantined(raterID string) bool {
+	sd.mutex.RLock()
+	defer sd.mutex.RUnlock()
+
+	weight, flagged := sd.raterWeights[raterID]
+	return flagged && weight == 0
+}
+
+// ============================================================================
+// QUERY METHODS
+// ============================================================================
+
+// GetClusters returns clusters from the last analysis
+func (sd *SybilDetector) GetClusters() []SuspiciousCluster {
+	sd.mutex.RLock()
+	defer sd.mutex.RUnlock()
+
+	clusters := make([]SuspiciousCluster, len(sd.clusters))
+	copy(clusters, sd.clusters)
+	return clusters
+}
+
+// GetStats returns detector statistics
+func (sd *SybilDetector) GetStats() map[string]interface{} {
+	sd.mutex.RLock()
+	defer sd.mutex.RUnlock()
+
+	quarantinedClusters := 0
+	for _, c := range sd.clusters {
+		if c.Quarantined {
+			quarantinedClusters++
+		}
+	}
+
+	return map[string]interface{}{
+		"flagged_clusters":     len(sd.clusters),
+		"quarantined_clusters": quarantinedClusters,
+		"flagged_raters":       len(sd.raterWeights),
+		"quarantined_ratings":  len(sd.quarantined),
+		"last_run":             sd.lastRun,
+		"is_running":           sd.isRunning,
+	}
+}
+
+// ============================================================================
+// UNION-FIND
+// ============================================================================
+
+// unionFind groups peer IDs into disjoint sets
+type unionFind struct {
+	parent map[string]string
+}
+
+func newUnionFind() *unionFind {
+	return &unionFind{parent: make(map[string]string)}
+}
+
+// find returns the set representative, adding id if unseen
+func (u *unionFind) find(id string) string {
+	if _, exists := u.parent[id]; !exists {
+		u.parent[id] = id
+	}
+	for u.parent[id] != id {
+		u.parent[id] = u.parent[u.parent[id]]
+		id = u.parent[id]
+	}
+	return id
+}
+
+// union merges the sets containing a and b
+func (u *unionFind) union(a, b string) {
+	rootA, rootB := u.find(a), u.find(b)
+	if rootA != rootB {
+		u.parent[rootB] = rootA
+	}
+}
+
+// groups returns all sets as member lists
+func (u *unionFind) groups() [][]string {
+	byRoot := make(map[string][]string)
+	for id := range u.parent {
+		root := u.find(id)
+		byRoot[root] = append(byRoot[root], id)
+	}
+
+	result := make([][]string, 0, len(byRoot))
+	for _, members := range byRoot {
+		result = append(result, members)
+	}
+	return result
+}
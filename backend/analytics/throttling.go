@@ -25,6 +25,7 @@ import (
 // CONSTANTS
 // ============================================================================
 
+// Bandwidth limits and tier thresholds below form the default TierPolicy
 const (
 	// Bandwidth limits in bytes per second
 	LeecherBandwidth = 50 * 1024       // 50 KB/s for leechers
@@ -67,18 +68,9 @@ func (t BandwidthTier) String() string {
 	}
 }
 
-// GetBandwidth returns the bandwidth limit for a tier
+// GetBandwidth returns the default-policy bandwidth limit for a tier
 func (t BandwidthTier) GetBandwidth() int64 {
-	switch t {
-	case TierLeecher:
-		return LeecherBandwidth
-	case TierNormal:
-		return NormalBandwidth
-	case TierPremium:
-		return PremiumBandwidth
-	default:
-		return NormalBandwidth
-	}
+	return DefaultReputationPolicy().Tiers.Bandwidth(t)
 }
 
 // ============================================================================
@@ -87,50 +79,49 @@ func (t BandwidthTier) GetBandwidth() int64 {
 
 // Throttler manages bandwidth allocation for a peer
 type Throttler struct {
-	peerID    string
-	tier      BandwidthTier
-	bandwidth int64 // bytes per second
-	tokens    int64 // current available tokens
-	maxTokens int64 // maximum tokens (bucket size)
-	tokenSize int64 // bytes per token
-	mutex     sync.Mutex
-	ticker    *time.Ticker
-	stopChan  chan struct{}
-	isRunning bool
+	peerID     string
+	reputation float64
+	tiers      TierPolicy
+	tier       BandwidthTier
+	bandwidth  int64 // bytes per second
+	tokens     int64 // current available tokens
+	maxTokens  int64 // maximum tokens (bucket size)
+	tokenSize  int64 // bytes per token
+	mutex      sync.Mutex
+	ticker     *time.Ticker
+	stopChan   chan struct{}
+	isRunning  bool
 }
 
-// NewThrottler creates a new throttler for a peer
+// NewThrottler creates a new throttler for a peer using the default tiers
 func NewThrottler(peerID string, reputation float64) *Throttler {
-	tier := determineTier(reputation)
-	bandwidth := tier.GetBandwidth()
+	return newThrottler(peerID, reputation, DefaultReputationPolicy().Tiers)
+}
+
+// newThrottler creates a throttler with the given tier policy
+func newThrottler(peerID string, reputation float64, tiers TierPolicy) *Throttler {
+	tier := tiers.DetermineTier(reputation)
+	bandwidth := tiers.Bandwidth(tier)
 
 	// Calculate token size (how many bytes per token)
 	tokenSize := bandwidth / TokenBucketSize
 
 	t := &Throttler{
-		peerID:    peerID,
-		tier:      tier,
-		bandwidth: bandwidth,
-		tokens:    TokenBucketSize, // Start with full bucket
-		maxTokens: TokenBucketSize,
-		tokenSize: tokenSize,
-		stopChan:  make(chan struct{}),
-		isRunning: false,
+		peerID:     peerID,
+		reputation: reputation,
+		tiers:      tiers,
+		tier:       tier,
+		bandwidth:  bandwidth,
+		tokens:     TokenBucketSize, // Start with full bucket
+		maxTokens:  TokenBucketSize,
+		tokenSize:  tokenSize,
+		stopChan:   make(chan struct{}),
+		isRunning:  false,
 	}
 
 	return t
 }
 
-// determineTier determines bandwidth tier based on reputation
-func determineTier(reputation float64) BandwidthTier {
-	if reputation < LeecherThreshold {
-		return TierLeecher
-	} else if reputation >= PremiumThreshold {
-		return TierPremium
-	}
-	return TierNormal
-}
-
 // ============================================================================
 // THROTTLER LIFECYCLE
 // ============================================================================
@@ -231,15 +222,28 @@ func (t *Throttler) GetBandwidth() int64 {
 
 // UpdateReputation updates the throttler based on new reputation
 func (t *Throttler) UpdateReputation(newReputation float64) {
-	newTier := determineTier(newReputation)
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
 
-	if newTier != t.tier {
-		t.mutex.Lock()
-		t.tier = newTier
-		t.bandwidth = newTier.GetBandwidth()
-		t.tokenSize = t.bandwidth / TokenBucketSize
-		t.mutex.Unlock()
-	}
+	t.reputation = newReputation
+	t.retierLocked()
+}
+
+// SetTierPolicy re-tiers the throttler under new tier boundaries
+func (t *Throttler) SetTierPolicy(tiers TierPolicy) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.tiers = tiers
+	t.retierLocked()
+}
+
+// retierLocked recomputes tier and bandwidth
+// Caller must hold t.mutex
+func (t *Throttler) retierLocked() {
+	t.tier = t.tiers.DetermineTier(t.reputation)
+	t.bandwidth = t.tiers.Bandwidth(t.tier)
+	t.tokenSize = t.bandwidth / TokenBucketSize
 }
 
 // ============================================================================
@@ -308,6 +312,7 @@ func (tw *ThrottledWriter) Write(p []byte) (n int, err error) {
 // ThrottlingManager manages throttlers for all peers
 type ThrottlingManager struct {
 	throttlers map[string]*Throttler
+	tiers      TierPolicy
 	mutex      sync.RWMutex
 	enabled    bool
 }
@@ -316,10 +321,22 @@ type ThrottlingManager struct {
 func NewThrottlingManager() *ThrottlingManager {
 	return &ThrottlingManager{
 		throttlers: make(map[string]*Throttler),
+		tiers:      DefaultReputationPolicy().Tiers,
 		enabled:    true,
 	}
 }
 
+// SetTierPolicy replaces the tier boundaries and re-tiers every peer
+func (tm *ThrottlingManager) SetTierPolicy(tiers TierPolicy) {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	tm.tiers = tiers
+	for _, throttler := range tm.throttlers {
+		throttler.SetTierPolicy(tiers)
+	}
+}
+
 // GetThrottler gets or creates a throttler for a peer
 func (tm *ThrottlingManager) GetThrottler(peerID string, reputation float64) *Throttler {
 	tm.mutex.Lock()
@@ -330,7 +347,7 @@ func (tm *ThrottlingManager) GetThrottler(peerID string, reputation float64) *Th
 		return throttler
 	}
 
-	throttler := NewThrottler(peerID, reputation)
+	throttler := newThrottler(peerID, reputation, tm.tiers)
 	throttler.Start()
 	tm.throttlers[peerID] = throttler
 	return throttler
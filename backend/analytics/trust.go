@@ -0,0 +1,591 @@
+/*
+================================================================================
+TRUST SERVICE - P2P Academic Library "The Knowledge Exchange"
+================================================================================
+This file implements EigenTrust-style global trust computed from signed local
+trust vectors exchanged between nodes.
+
+Go Concepts Used:
+- crypto/ed25519: Signing and verifying gossiped trust reports
+- Maps: Sparse trust matrices
+- Iteration: Power iteration until convergence
+- Mutex: Thread-safe report storage
+================================================================================
+*/
+
+package analytics
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"knowledge-exchange/models"
+)
+
+// ============================================================================
+// CONSTANTS
+// ============================================================================
+
+const (
+	// TrustAlpha is the weight of the pre-trusted distribution in each iteration
+	TrustAlpha = 0.15
+
+	// TrustEpsilon is the L1 convergence threshold
+	TrustEpsilon = 1e-6
+
+	// TrustMaxIterations bounds the power iteration
+	TrustMaxIterations = 100
+
+	// TrustReportMaxAge discards gossip older than this
+	TrustReportMaxAge = 24 * time.Hour
+
+	// TrustMaxNodes caps how many other nodes' reports are kept
+	TrustMaxNodes = 256
+
+	// TrustReportMaxBytes caps the size of a gossiped report
+	TrustReportMaxBytes = 1 << 20
+
+	// Local trust weights: one neutral-or-better rating point vs one MB served
+	trustPerRatingPoint = 1.0
+	trustPerMBServed    = 0.1
+)
+
+// ============================================================================
+// TRUST TYPES
+// ============================================================================
+
+// TrustVector holds one peer's normalized local trust in other peers
+type TrustVector struct {
+	SourceID string             `json:"source_id"`
+	Entries  map[string]float64 `json:"entries"` // targetID -> normalized trust
+}
+
+// TrustReport is a signed set of local trust vectors published by a node
+type TrustReport struct {
+	NodeID    string        `json:"node_id"`
+	Vectors   []TrustVector `json:"vectors"`
+	Issued    time.Time     `json:"issued"`
+	PublicKey []byte        `json:"public_key"`
+	Signature []byte        `json:"signature"`
+}
+
+// signingPayload returns the canonical bytes covered by the signature
+// encoding/json sorts map keys, so equal reports encode identically
+func (r *TrustReport) signingPayload() ([]byte, error) {
+	return json.Marshal(struct {
+		NodeID    string        `json:"node_id"`
+		Vectors   []TrustVector `json:"vectors"`
+		Issued    time.Time     `json:"issued"`
+		PublicKey []byte        `json:"public_key"`
+	}{r.NodeID, r.Vectors, r.Issued.UTC(), r.PublicKey})
+}
+
+// Verify checks the report's signature against its embedded key
+func (r *TrustReport) Verify() error {
+	if len(r.PublicKey) != ed25519.PublicKeySize {
+		return errors.New("invalid public key")
+	}
+
+	payload, err := r.signingPayload()
+	if err != nil {
+		return err
+	}
+
+	if !ed25519.Verify(ed25519.PublicKey(r.PublicKey), payload, r.Signature) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+// ============================================================================
+// TRUST MANAGER
+// ============================================================================
+
+// TrustManager builds local trust vectors, exchanges them and computes
+// global trust with EigenTrust power iteration
+type TrustManager struct {
+	// nodeID and key identify this node in gossip
+	nodeID     string
+	privateKey ed25519.PrivateKey
+
+	// Local evidence sources
+	ratingStore *models.RatingStore
+	ledger      *TransferLedger
+
+	// preTrusted seeds the teleport distribution
+	preTrusted []string
+
+	// reports holds the latest verified report per node (including our own)
+	reports map[string]*TrustReport
+
+	// nodeKeys pins the first public key seen for each node
+	nodeKeys map[string][]byte
+
+	// sourceNodes maps each vector source to the node that speaks for it:
+	// this node for sources it has local evidence about, otherwise the
+	// first node to report the source. A node always speaks for itself
+	sourceNodes map[string]string
+
+	// isKnownNode limits gossip to known nodes (nil accepts any node)
+	isKnownNode func(nodeID string) bool
+
+	// globalTrust is the result of the last computation
+	globalTrust map[string]float64
+	computedAt  time.Time
+
+	mutex sync.RWMutex
+}
+
+// NewTrustManager creates a new TrustManager
+// Parameters:
+//   - nodeID: This node's peer ID
+//   - privateKey: Ed25519 key used to sign reports
+//   - ratingStore: Source of peer ratings
+//   - ledger: Source of bytes served between peers
+//   - preTrusted: Peer IDs trusted a priori (may be empty)
+func NewTrustManager(nodeID string, privateKey ed25519.PrivateKey, ratingStore *models.RatingStore, ledger *TransferLedger, preTrusted []string) *TrustManager {
+	return &TrustManager{
+		nodeID:      nodeID,
+		privateKey:  privateKey,
+		ratingStore: ratingStore,
+		ledger:      ledger,
+		preTrusted:  preTrusted,
+		reports:     make(map[string]*TrustReport),
+		nodeKeys:    make(map[string][]byte),
+		sourceNodes: make(map[string]string),
+		globalTrust: make(map[string]float64),
+	}
+}
+
+// SetNodeCheck sets the function used to reject reports from unknown nodes
+// Pre-trusted nodes are always accepted
+func (tm *TrustManager) SetNodeCheck(isKnownNode func(nodeID string) bool) {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+	tm.isKnownNode = isKnownNode
+}
+
+// LoadOrCreateNodeKey loads an Ed25519 private key from path,
+// generating and saving a new one if the file does not exist
+func LoadOrCreateNodeKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		if len(data) != ed25519.PrivateKeySize {
+			return nil, fmt.Errorf("invalid node key in %s", path)
+		}
+		return ed25519.PrivateKey(data), nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, privateKey, 0600); err != nil {
+		return nil, err
+	}
+	return privateKey, nil
+}
+
+// ============================================================================
+// LOCAL TRUST
+// ============================================================================
+
+// buildLocalVectors derives normalized local trust from ratings and the ledger
+// A source trusts a target in proportion to its above-neutral ratings of the
+// target plus the megabytes the target has served it
+func (tm *TrustManager) buildLocalVectors() []TrustVector {
+	raw := make(map[string]map[string]float64)
+	add := func(source, target string, value float64) {
+		if source == target || value <= 0 {
+			return
+		}
+		if raw[source] == nil {
+			raw[source] = make(map[string]float64)
+		}
+		raw[source][target] += value
+	}
+
+	// Ratings: only peer ratings above neutral contribute positive trust
+	for _, rating := range tm.ratingStore.GetAll() {
+		if rating.TargetType != "student" {
+			continue
+		}
+		add(rating.RaterID, rating.TargetID, (rating.Score-3.0)*trustPerRatingPoint)
+	}
+
+	// Ledger: bytes a counterparty served to the source
+	for _, totals := range tm.ledger.GetAllTotals() {
+		for _, entry := range tm.ledger.GetCounterparties(totals.PeerID) {
+			add(entry.PeerID, entry.CounterpartyID, float64(entry.BytesReceived)/bytesPerMB*trustPerMBServed)
+		}
+	}
+
+	vectors := make([]TrustVector, 0, len(raw))
+	for source, targets := range raw {
+		vectors = append(vectors, TrustVector{
+			SourceID: source,
+			Entries:  normalize(targets),
+		})
+	}
+
+	sort.Slice(vectors, func(i, j int) bool {
+		return vectors[i].SourceID < vectors[j].SourceID
+	})
+	return vectors
+}
+
+// LocalReport builds and signs this node's current trust report
+// The report is also stored as this node's contribution to global trust
+func (tm *TrustManager) LocalReport() (*TrustReport, error) {
+	report := &TrustReport{
+		NodeID:    tm.nodeID,
+		Vectors:   tm.buildLocalVectors(),
+		Issued:    time.Now().UTC(),
+		PublicKey: tm.privateKey.Public().(ed25519.PublicKey),
+	}
+
+	payload, err := report.signingPayload()
+	if err != nil {
+		return nil, err
+	}
+	report.Signature = ed25519.Sign(tm.privateKey, payload)
+
+	tm.mutex.Lock()
+	tm.reports[tm.nodeID] = report
+	for _, vector := range report.Vectors {
+		tm.sourceNodes[vector.SourceID] = tm.nodeID
+	}
+	tm.mutex.Unlock()
+
+	return report, nil
+}
+
+// ============================================================================
+// GOSSIP
+// ============================================================================
+
+// AcceptReport verifies and stores a report received from another node
+// Reports must be signed, fresh, newer than the stored one, and signed by
+// the key first seen for that node. A signature only proves who the node
+// is, so only vectors for sources the node speaks for are kept
+func (tm *TrustManager) AcceptReport(report *TrustReport) error {
+	if report.NodeID == "" || report.NodeID == tm.nodeID {
+		return errors.New("invalid node ID")
+	}
+
+	if err := report.Verify(); err != nil {
+		return err
+	}
+
+	if time.Since(report.Issued) > TrustReportMaxAge {
+		return errors.New("report expired")
+	}
+
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	pinned, known := tm.nodeKeys[report.NodeID]
+	if known {
+		if string(pinned) != string(report.PublicKey) {
+			return errors.New("public key does not match known key for node")
+		}
+	} else if !tm.isPreTrusted(report.NodeID) {
+		if tm.isKnownNode != nil && !tm.isKnownNode(report.NodeID) {
+			return errors.New("unknown node")
+		}
+		if len(tm.nodeKeys) >= TrustMaxNodes {
+			return errors.New("too many nodes")
+		}
+	}
+
+	if existing, exists := tm.reports[report.NodeID]; exists && !report.Issued.After(existing.Issued) {
+		return errors.New("stale report")
+	}
+
+	// Keep only the vectors this node speaks for
+	accepted := make([]TrustVector, 0, len(report.Vectors))
+	for _, vector := range report.Vectors {
+		if tm.speaksForLocked(report.NodeID, vector.SourceID) {
+			accepted = append(accepted, vector)
+		}
+	}
+	if len(accepted) == 0 && len(report.Vectors) > 0 {
+		return errors.New("report has no vectors the node speaks for")
+	}
+
+	if !known {
+		tm.nodeKeys[report.NodeID] = report.PublicKey
+	}
+	for _, vector := range accepted {
+		tm.sourceNodes[vector.SourceID] = report.NodeID
+	}
+
+	stored := *report
+	stored.Vectors = accepted
+	tm.reports[report.NodeID] = &stored
+	return nil
+}
+
+// speaksForLocked reports whether a node may publish a source's vector
+// A node speaks for itself and for sources no other node has claimed;
+// this node and pre-trusted peers only speak for themselves. Caller must
+// hold the lock
+func (tm *TrustManager) speaksForLocked(nodeID, sourceID string) bool {
+	if sourceID == nodeID {
+		return true
+	}
+	if sourceID == tm.nodeID {
+		return false
+	}
+	if owner, claimed := tm.sourceNodes[sourceID]; claimed {
+		return owner == nodeID
+	}
+	return !tm.isPreTrusted(sourceID)
+}
+
+// isPreTrusted reports whether a peer is one of the pre-trusted seeds
+func (tm *TrustManager) isPreTrusted(peerID string) bool {
+	for _, id := range tm.preTrusted {
+		if id == peerID {
+			return true
+		}
+	}
+	return false
+}
+
+// ============================================================================
+// GLOBAL TRUST
+// ============================================================================
+
+// Recompute runs EigenTrust over all stored reports
+// Each source's vector comes from the node that speaks for it, weighted by
+// that node's global trust from the previous computation: a vector from a
+// node with average trust or better counts fully, one from a less trusted
+// node only in part, with the rest of the source's trust going to the
+// pre-trusted peers. This node and pre-trusted nodes always count fully
+func (tm *TrustManager) Recompute() map[string]float64 {
+	tm.mutex.RLock()
+	merged := make(map[string]map[string]float64)
+	for nodeID, report := range tm.reports {
+		if nodeID != tm.nodeID && time.Since(report.Issued) > TrustReportMaxAge {
+			continue
+		}
+		weight := tm.nodeWeightLocked(nodeID)
+		if weight == 0 {
+			continue
+		}
+		for _, vector := range report.Vectors {
+			if tm.sourceNodes[vector.SourceID] != nodeID && vector.SourceID != nodeID {
+				continue // Claimed since by another node
+			}
+			if merged[vector.SourceID] == nil {
+				merged[vector.SourceID] = make(map[string]float64)
+			}
+			for target, value := range vector.Entries {
+				merged[vector.SourceID][target] += weight * value
+			}
+		}
+	}
+	tm.mutex.RUnlock()
+
+	// Collect all peers appearing in the matrix
+	peerSet := make(map[string]bool)
+	for source, targets := range merged {
+		peerSet[source] = true
+		for target := range targets {
+			peerSet[target] = true
+		}
+		if rowSum(targets) > 1 {
+			merged[source] = normalize(targets)
+		}
+	}
+	for _, id := range tm.preTrusted {
+		peerSet[id] = true
+	}
+
+	trust := eigenTrust(merged, peerSet, tm.preTrusted)
+
+	tm.mutex.Lock()
+	tm.globalTrust = trust
+	tm.computedAt = time.Now()
+	tm.mutex.Unlock()
+
+	return trust
+}
+
+// nodeWeightLocked returns how much a node's report counts, from 0 to 1
+// Caller must hold the lock
+func (tm *TrustManager) nodeWeightLocked(nodeID string) float64 {
+	if nodeID == tm.nodeID || tm.isPreTrusted(nodeID) {
+		return 1
+	}
+	// Average trust (1/N) or better counts fully
+	return math.Min(1, tm.globalTrust[nodeID]*float64(len(tm.globalTrust)))
+}
+
+// eigenTrust performs t = (1-a) * C^T * t + a * p until convergence
+// A row summing to less than 1 sends the rest of its weight to p
+func eigenTrust(matrix map[string]map[string]float64, peerSet map[string]bool, preTrusted []string) map[string]float64 {
+	n := len(peerSet)
+	if n == 0 {
+		return map[string]float64{}
+	}
+
+	// Teleport distribution: pre-trusted seeds, or uniform if none are known
+	p := make(map[string]float64)
+	for _, id := range preTrusted {
+		p[id] = 1.0 / float64(len(preTrusted))
+	}
+	if len(p) == 0 {
+		for id := range peerSet {
+			p[id] = 1.0 / float64(n)
+		}
+	}
+
+	t := make(map[string]float64, n)
+	for id, v := range p {
+		t[id] = v
+	}
+
+	for iter := 0; iter < TrustMaxIterations; iter++ {
+		next := make(map[string]float64, n)
+		for source := range peerSet {
+			weight := t[source]
+			if weight == 0 {
+				continue
+			}
+
+			// Trust a row doesn't place (no opinions, or opinions only
+			// partly counted) defers to the teleport distribution
+			row := matrix[source]
+			for target, c := range row {
+				next[target] += (1 - TrustAlpha) * c * weight
+			}
+			if rest := 1 - rowSum(row); rest > 0 {
+				for target, c := range p {
+					next[target] += (1 - TrustAlpha) * rest * c * weight
+				}
+			}
+		}
+		for id, v := range p {
+			next[id] += TrustAlpha * v
+		}
+
+		delta := 0.0
+		for id := range peerSet {
+			delta += math.Abs(next[id] - t[id])
+		}
+		t = next
+		if delta < TrustEpsilon {
+			break
+		}
+	}
+
+	return t
+}
+
+// GlobalTrust returns the last computed global trust for a peer
+func (tm *TrustManager) GlobalTrust(peerID string) (float64, bool) {
+	tm.mutex.RLock()
+	defer tm.mutex.RUnlock()
+
+	value, exists := tm.globalTrust[peerID]
+	return value, exists
+}
+
+// TrustToReputation maps a global trust value onto the reputation scale
+// A peer with exactly average trust (1/N) maps to DefaultReputation
+func (tm *TrustManager) TrustToReputation(peerID string) (float64, bool) {
+	tm.mutex.RLock()
+	value, exists := tm.globalTrust[peerID]
+	n := len(tm.globalTrust)
+	tm.mutex.RUnlock()
+
+	if !exists || n == 0 {
+		return 0, false
+	}
+
+	score := DefaultReputation * value * float64(n)
+	if score > MaxReputation {
+		score = MaxReputation
+	}
+	if score < MinReputation {
+		score = MinReputation
+	}
+	return score, true
+}
+
+// GetAllTrust returns a copy of the global trust vector
+func (tm *TrustManager) GetAllTrust() map[string]float64 {
+	tm.mutex.RLock()
+	defer tm.mutex.RUnlock()
+
+	result := make(map[string]float64, len(tm.globalTrust))
+	for id, v := range tm.globalTrust {
+		result[id] = v
+	}
+	return result
+}
+
+// GetStats returns trust computation statistics
+func (tm *TrustManager) GetStats() map[string]interface{} {
+	tm.mutex.RLock()
+	defer tm.mutex.RUnlock()
+
+	return map[string]interface{}{
+		"node_id":       tm.nodeID,
+		"reports":       len(tm.reports),
+		"known_nodes":   len(tm.nodeKeys),
+		"sources":       len(tm.sourceNodes),
+		"ranked_peers":  len(tm.globalTrust),
+		"pre_trusted":   len(tm.preTrusted),
+		"last_computed": tm.computedAt,
+	}
+}
+
+// ============================================================================
+// HELPER FUNCTIONS
+// ============================================================================
+
+// rowSum adds up a row's positive values
+func rowSum(values map[string]float64) float64 {
+	var sum float64
+	for _, v := range values {
+		if v > 0 {
+			sum += v
+		}
+	}
+	return sum
+}
+
+// normalize scales positive values so they sum to 1
+func normalize(values map[string]float64) map[string]float64 {
+	var sum float64
+	for _, v := range values {
+		if v > 0 {
+			sum += v
+		}
+	}
+
+	result := make(map[string]float64, len(values))
+	if sum == 0 {
+		return result
+	}
+	for id, v := range values {
+		if v > 0 {
+			result[id] = v / sum
+		}
+	}
+	return result
+}
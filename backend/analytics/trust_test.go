@@ -0,0 +1,218 @@
+/*
+================================================================================
+GLOBAL TRUST TESTS - P2P Academic Library "The Knowledge Exchange"
+================================================================================
+Gossips signed reports into a trust manager and checks that a node can only
+publish opinions for peers it speaks for, that reports count by the
+reporting node's own trust, and that unknown nodes are turned away.
+================================================================================
+*/
+
+package analytics
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"knowledge-exchange/models"
+)
+
+// ============================================================================
+// HELPERS
+// ============================================================================
+
+// newTestTrustManager creates the local node's trust manager; bob has
+// received 10 MB from alice, so bob's vector belongs to the local node
+func newTestTrustManager(t *testing.T, preTrusted ...string) *TrustManager {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	ledger := NewTransferLedger()
+	ledger.RecordTransfer("alice", "bob", 10*bytesPerMB)
+
+	tm := NewTrustManager("local", key, models.NewRatingStore(), ledger, preTrusted)
+	if _, err := tm.LocalReport(); err != nil {
+		t.Fatalf("local report: %v", err)
+	}
+	return tm
+}
+
+// testNode is a remote node that signs its reports with one key
+type testNode struct {
+	id     string
+	key    ed25519.PrivateKey
+	issued time.Time
+}
+
+// newTestNode creates a remote node with a fresh key
+func newTestNode(t *testing.T, id string) *testNode {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	return &testNode{id: id, key: key, issued: time.Now().UTC().Add(-time.Hour)}
+}
+
+// report signs a report newer than the node's previous one
+func (n *testNode) report(t *testing.T, vectors ...TrustVector) *TrustReport {
+	t.Helper()
+
+	n.issued = n.issued.Add(time.Second)
+	report := &TrustReport{
+		NodeID:    n.id,
+		Vectors:   vectors,
+		Issued:    n.issued,
+		PublicKey: n.key.Public().(ed25519.PublicKey),
+	}
+	payload, err := report.signingPayload()
+	if err != nil {
+		t.Fatalf("signing payload: %v", err)
+	}
+	report.Signature = ed25519.Sign(n.key, payload)
+	return report
+}
+
+// signedReport builds a one-off report from nodeID
+func signedReport(t *testing.T, nodeID string, vectors ...TrustVector) *TrustReport {
+	t.Helper()
+	return newTestNode(t, nodeID).report(t, vectors...)
+}
+
+// trusts is a vector in which source fully trusts target
+func trusts(source, target string) TrustVector {
+	return TrustVector{SourceID: source, Entries: map[string]float64{target: 1}}
+}
+
+// storedSources lists the sources kept from a node's report
+func storedSources(tm *TrustManager, nodeID string) []string {
+	tm.mutex.RLock()
+	defer tm.mutex.RUnlock()
+
+	var sources []string
+	if report, exists := tm.reports[nodeID]; exists {
+		for _, vector := range report.Vectors {
+			sources = append(sources, vector.SourceID)
+		}
+	}
+	return sources
+}
+
+// ============================================================================
+// TESTS
+// ============================================================================
+
+// TestForgedVectorsAreDropped checks a node can't publish opinions for a
+// pre-trusted seed, this node, or a peer another node already speaks for
+func TestForgedVectorsAreDropped(t *testing.T) {
+	tm := newTestTrustManager(t, "seed")
+
+	if err := tm.AcceptReport(signedReport(t, "honest", trusts("carol", "alice"))); err != nil {
+		t.Fatalf("honest report: %v", err)
+	}
+
+	forged := signedReport(t, "mallory",
+		trusts("seed", "mallory"),    // A pre-trusted seed
+		trusts("local", "mallory"),   // This node
+		trusts("bob", "mallory"),     // Local evidence
+		trusts("carol", "mallory"),   // Claimed by another node
+		trusts("mallory", "mallory"), // Itself
+		trusts("dave", "mallory"),    // Unclaimed
+	)
+	if err := tm.AcceptReport(forged); err != nil {
+		t.Fatalf("forged report: %v", err)
+	}
+	if got := fmt.Sprint(storedSources(tm, "mallory")); got != "[mallory dave]" {
+		t.Fatalf("kept vectors for %s, want only mallory's own and dave's", got)
+	}
+
+	// A report with nothing the node may speak for is refused outright
+	if err := tm.AcceptReport(signedReport(t, "eve", trusts("seed", "eve"))); err == nil {
+		t.Fatal("report forging only a seed's vector was accepted")
+	}
+
+	// The seed has no opinions, so its trust stays with the seeds and
+	// mallory, which no one trusts, ends up with none
+	tm.Recompute()
+	if trust, _ := tm.GlobalTrust("mallory"); trust > 1e-9 {
+		t.Fatalf("mallory gained global trust %.6f through forged vectors", trust)
+	}
+	if trust, _ := tm.GlobalTrust("seed"); trust < 0.99 {
+		t.Fatalf("seed kept global trust %.6f, want all of it", trust)
+	}
+}
+
+// TestReportsWeightedByNodeTrust checks a report only counts in proportion
+// to the reporting node's global trust
+func TestReportsWeightedByNodeTrust(t *testing.T) {
+	tm := newTestTrustManager(t, "seed")
+	seed := newTestNode(t, "seed")
+	minted := newTestNode(t, "minted")
+
+	// The seed trusts alice; a node no one trusts vouches for a sybil it
+	// speaks for, and the sybil vouches back
+	if err := tm.AcceptReport(seed.report(t, trusts("seed", "alice"))); err != nil {
+		t.Fatalf("seed report: %v", err)
+	}
+	if err := tm.AcceptReport(minted.report(t, trusts("minted", "sybil"), trusts("sybil", "minted"))); err != nil {
+		t.Fatalf("minted report: %v", err)
+	}
+	for round := 0; round < 3; round++ {
+		tm.Recompute()
+	}
+
+	// alice holds no opinions, so her trust cycles back to the seed:
+	// alice = (1-a) * seed and seed = a + (1-a) * alice
+	want := (1 - TrustAlpha) * TrustAlpha / (1 - (1-TrustAlpha)*(1-TrustAlpha))
+	if alice, _ := tm.GlobalTrust("alice"); math.Abs(alice-want) > 1e-4 {
+		t.Fatalf("alice has global trust %.6f, want %.6f from the seed", alice, want)
+	}
+	for _, id := range []string{"minted", "sybil"} {
+		if trust, _ := tm.GlobalTrust(id); trust > 1e-9 {
+			t.Fatalf("%s has global trust %.6f from an untrusted node's report", id, trust)
+		}
+	}
+
+	// Once the seed trusts the node, its report counts
+	if err := tm.AcceptReport(seed.report(t, trusts("seed", "minted"))); err != nil {
+		t.Fatalf("seed report: %v", err)
+	}
+	for round := 0; round < 3; round++ {
+		tm.Recompute()
+	}
+	if trust, _ := tm.GlobalTrust("sybil"); trust < 0.1 {
+		t.Fatalf("sybil has global trust %.6f after its node became trusted", trust)
+	}
+}
+
+// TestUnknownAndExcessNodesRejected checks gossip is limited to known nodes
+// and capped at TrustMaxNodes, while pre-trusted nodes are always heard
+func TestUnknownAndExcessNodesRejected(t *testing.T) {
+	tm := newTestTrustManager(t, "seed")
+	tm.SetNodeCheck(func(nodeID string) bool { return nodeID != "stranger" })
+
+	if err := tm.AcceptReport(signedReport(t, "stranger", trusts("stranger", "alice"))); err == nil {
+		t.Fatal("report from an unknown node was accepted")
+	}
+
+	for i := 0; i < TrustMaxNodes; i++ {
+		nodeID := fmt.Sprintf("node-%d", i)
+		if err := tm.AcceptReport(signedReport(t, nodeID, trusts(nodeID, "alice"))); err != nil {
+			t.Fatalf("report %d: %v", i, err)
+		}
+	}
+	if err := tm.AcceptReport(signedReport(t, "one-too-many", trusts("one-too-many", "alice"))); err == nil {
+		t.Fatalf("report from node %d was accepted past the cap", TrustMaxNodes+1)
+	}
+	if err := tm.AcceptReport(signedReport(t, "seed", trusts("seed", "alice"))); err != nil {
+		t.Fatalf("pre-trusted report past the cap: %v", err)
+	}
+}
@@ -0,0 +1,188 @@
+/*
+================================================================================
+API KEYS - P2P Academic Library "The Knowledge Exchange"
+================================================================================
+This file implements API keys: named, scoped, expiring credentials that
+scripts and headless peers send in the X-API-Key header instead of logging
+in with a password.
+
+A key is "kx_" followed by 32 random bytes. Only its SHA-256 hash is stored,
+so a key can't be shown again after it is created. A key acts as its owner
+but only reaches endpoints its scopes allow, and never account management
+(password, two-factor or API key changes).
+
+Go Concepts Used:
+- Interfaces: Pluggable key storage
+- crypto/rand + SHA-256: Opaque keys stored hashed
+- Error handling
+================================================================================
+*/
+
+package auth
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"knowledge-exchange/models"
+
+	"github.com/google/uuid"
+)
+
+// ============================================================================
+// CONSTANTS
+// ============================================================================
+
+const (
+	// APIKeyPrefix marks API keys so they are easy to spot (and to scan
+	// for in leaked code)
+	APIKeyPrefix = "kx_"
+
+	// API key lifetimes
+	DefaultAPIKeyExpiration = 90 * 24 * time.Hour
+	MaxAPIKeyExpiration     = 365 * 24 * time.Hour
+
+	// apiKeyDisplayLength is how much of a key is kept to tell keys apart
+	apiKeyDisplayLength = len(APIKeyPrefix) + 6
+)
+
+// API key errors
+var (
+	ErrNoAPIKeyStore   = errors.New("API keys are not enabled")
+	ErrInvalidAPIKey   = errors.New("invalid, revoked or expired API key")
+	ErrAPIKeyNotFound  = errors.New("API key not found")
+	ErrInvalidScope    = errors.New("unknown scope")
+	ErrScopeNotAllowed = errors.New("only admins can create keys with the admin scope")
+)
+
+// ============================================================================
+// API KEY STORE INTERFACE
+// ============================================================================
+
+// APIKeyStore persists API keys
+// Implemented by storage.APIKeyStore
+type APIKeyStore interface {
+	SaveAPIKey(key models.APIKey) error
+	GetAPIKey(hash string) (models.APIKey, bool)
+	ListAPIKeys(userID string) []models.APIKey
+	RevokeAPIKey(userID, keyID string) (bool, error)
+	TouchAPIKey(hash string, at time.Time) error
+}
+
+// ============================================================================
+// API KEY METHODS
+// ============================================================================
+
+// CreateAPIKey issues a new API key for a user
+// Parameters:
+//   - user: The key's owner
+//   - name: Label to tell keys apart
+//   - scopes: What the key may do; at least one
+//   - ttl: How long the key works (0 for the default, capped at the maximum)
+//
+// Returns:
+//   - string: The key itself; it can't be recovered later
+//   - models.APIKey: The stored record
+//   - error: If a scope is unknown or not allowed for the user
+func (s *Service) CreateAPIKey(user *models.User, name string, scopes []string, ttl time.Duration) (string, models.APIKey, error) {
+	if s.apiKeys == nil {
+		return "", models.APIKey{}, ErrNoAPIKeyStore
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", models.APIKey{}, errors.New("name is required")
+	}
+	if len(scopes) == 0 {
+		return "", models.APIKey{}, errors.New("at least one scope is required")
+	}
+
+	granted := make([]string, 0, len(scopes))
+	seen := make(map[string]bool)
+	for _, scope := range scopes {
+		if !models.IsValidScope(scope) {
+			return "", models.APIKey{}, ErrInvalidScope
+		}
+		if scope == models.ScopeAdmin && !user.IsAdmin() {
+			return "", models.APIKey{}, ErrScopeNotAllowed
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			granted = append(granted, scope)
+		}
+	}
+
+	if ttl <= 0 {
+		ttl = DefaultAPIKeyExpiration
+	}
+	if ttl > MaxAPIKeyExpiration {
+		ttl = MaxAPIKeyExpiration
+	}
+
+	secret, err := randomToken()
+	if err != nil {
+		return "", models.APIKey{}, err
+	}
+	key := APIKeyPrefix + secret
+
+	now := time.Now()
+	record := models.APIKey{
+		ID:        uuid.New().String(),
+		UserID:    user.ID,
+		Name:      name,
+		Prefix:    key[:apiKeyDisplayLength],
+		KeyHash:   hashToken(key),
+		Scopes:    granted,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+	if err := s.apiKeys.SaveAPIKey(record); err != nil {
+		return "", models.APIKey{}, err
+	}
+	return key, record, nil
+}
+
+// AuthenticateAPIKey looks up a presented key and records its use
+// The caller still checks that the owner exists and is active
+func (s *Service) AuthenticateAPIKey(key string) (models.APIKey, error) {
+	if s.apiKeys == nil {
+		return models.APIKey{}, ErrNoAPIKeyStore
+	}
+	if !strings.HasPrefix(key, APIKeyPrefix) {
+		return models.APIKey{}, ErrInvalidAPIKey
+	}
+
+	hash := hashToken(key)
+	record, ok := s.apiKeys.GetAPIKey(hash)
+	if !ok || record.IsRevoked() || record.IsExpired() {
+		return models.APIKey{}, ErrInvalidAPIKey
+	}
+
+	s.apiKeys.TouchAPIKey(hash, time.Now())
+	return record, nil
+}
+
+// ListAPIKeys returns a user's keys, newest first
+func (s *Service) ListAPIKeys(userID string) []models.APIKey {
+	if s.apiKeys == nil {
+		return []models.APIKey{}
+	}
+	return s.apiKeys.ListAPIKeys(userID)
+}
+
+// RevokeAPIKey revokes one of a user's keys
+func (s *Service) RevokeAPIKey(userID, keyID string) error {
+	if s.apiKeys == nil {
+		return ErrNoAPIKeyStore
+	}
+
+	found, err := s.apiKeys.RevokeAPIKey(userID, keyID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
@@ -4,10 +4,17 @@ AUTHENTICATION SERVICE - P2P Academic Library "The Knowledge Exchange"
 ================================================================================
 This file implements JWT-based authentication and authorization.
 
+Access tokens are short-lived JWTs. Refresh tokens are random strings that
+rotate on every use; only their SHA-256 hash is stored. Presenting a refresh
+token that was already rotated revokes its whole family (reuse detection).
+Action tokens (password reset, email verification) are stored the same way
+and are deleted when redeemed.
+
 Go Concepts Used:
 - JWT tokens: Secure authentication
 - bcrypt: Password hashing
-- Middleware: Request authentication
+- crypto/rand + SHA-256: Opaque refresh and action tokens
+- Interfaces: Pluggable token storage
 - Error handling
 ================================================================================
 */
@@ -15,12 +22,17 @@ Go Concepts Used:
 package auth
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"time"
 
 	"knowledge-exchange/models"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -29,12 +41,24 @@ import (
 // ============================================================================
 
 const (
-	// JWT secret key (in production, this should be in environment variables)
-	jwtSecret = "your-secret-key-change-this-in-production"
-
 	// Token expiration times
-	tokenExpiration   = 24 * time.Hour     // 24 hours
+	accessExpiration  = 15 * time.Minute   // 15 minutes
 	refreshExpiration = 7 * 24 * time.Hour // 7 days
+
+	// tokenBytes is the amount of randomness in refresh and action tokens
+	tokenBytes = 32
+
+	// Emailed token lifetimes
+	PasswordResetExpiration = 1 * time.Hour
+	VerifyEmailExpiration   = 48 * time.Hour
+)
+
+// Refresh token errors
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected; all sessions from this login were revoked")
+	ErrNoTokenStore        = errors.New("refresh tokens are not enabled")
+	ErrInvalidActionToken  = errors.New("invalid, used or expired link")
 )
 
 // ============================================================================
@@ -50,22 +74,71 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
+// TokenPair is what a successful login or refresh returns
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"` // Access token lifetime in seconds
+}
+
+// ============================================================================
+// TOKEN STORE INTERFACE
+// ============================================================================
+
+// TokenStore persists refresh tokens and access token revocations
+// Implemented by storage.TokenStore
+type TokenStore interface {
+	SaveRefreshToken(token models.RefreshToken) error
+	GetRefreshToken(hash string) (models.RefreshToken, bool)
+	RotateRefreshToken(usedHash string, next models.RefreshToken) (bool, error)
+	RevokeRefreshFamily(familyID string) error
+	RevokeUser(userID string, before time.Time) error
+	RevokeAccessToken(jti string, expiresAt time.Time) error
+	IsAccessRevoked(jti, userID string, issuedAt time.Time) bool
+	SaveActionToken(token models.ActionToken) error
+	ConsumeActionToken(hash, purpose string) (models.ActionToken, bool, error)
+}
+
 // ============================================================================
 // AUTHENTICATION SERVICE
 // ============================================================================
 
 // Service provides authentication functionality
 type Service struct {
-	secret []byte
+	// keys signs new tokens and verifies tokens by their kid header
+	keys *KeyRing
+
+	// tokens stores refresh tokens and revocations (nil disables both)
+	tokens TokenStore
+
+	// apiKeys stores API keys (nil disables them)
+	apiKeys APIKeyStore
 }
 
 // NewService creates a new authentication service
-func NewService() *Service {
+// Parameters:
+//   - keys: Key ring used to sign and verify tokens
+func NewService(keys *KeyRing) *Service {
 	return &Service{
-		secret: []byte(jwtSecret),
+		keys: keys,
 	}
 }
 
+// Keys returns the signing key ring
+func (s *Service) Keys() *KeyRing {
+	return s.keys
+}
+
+// SetTokenStore enables refresh tokens and revocation
+func (s *Service) SetTokenStore(store TokenStore) {
+	s.tokens = store
+}
+
+// SetAPIKeyStore enables API keys
+func (s *Service) SetAPIKeyStore(store APIKeyStore) {
+	s.apiKeys = store
+}
+
 // ============================================================================
 // PASSWORD METHODS
 // ============================================================================
@@ -93,7 +166,8 @@ func (s *Service) VerifyPassword(hash, password string) error {
 // TOKEN METHODS
 // ============================================================================
 
-// GenerateToken generates a JWT token for a user
+// GenerateToken generates a short-lived JWT access token for a user
+// Each token gets a unique ID (jti) so it can be revoked individually
 func (s *Service) GenerateToken(user *models.User) (string, error) {
 	claims := Claims{
 		UserID:   user.ID,
@@ -101,14 +175,17 @@ func (s *Service) GenerateToken(user *models.User) (string, error) {
 		Username: user.Username,
 		Role:     user.Role,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(tokenExpiration)),
+			ID:        uuid.New().String(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(accessExpiration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(s.secret)
+	key := s.keys.Active()
+	token := jwt.NewWithClaims(key.method(), claims)
+	token.Header["kid"] = key.ID
+	tokenString, err := token.SignedString(key.signingKey())
 	if err != nil {
 		return "", err
 	}
@@ -119,22 +196,39 @@ func (s *Service) GenerateToken(user *models.User) (string, error) {
 // ValidateToken validates a JWT token and returns the claims
 func (s *Service) ValidateToken(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		// Verify signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		// Find the key by ID and make sure the token uses its algorithm
+		kid, _ := token.Header["kid"].(string)
+		key, ok := s.keys.Get(kid)
+		if !ok {
+			return nil, errors.New("unknown signing key")
+		}
+		if token.Method.Alg() != key.Algorithm {
 			return nil, errors.New("invalid signing method")
 		}
-		return s.secret, nil
+		return key.verificationKey(), nil
 	})
 
 	if err != nil {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return claims, nil
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	// Check the revocation list
+	if s.tokens != nil {
+		var issuedAt time.Time
+		if claims.IssuedAt != nil {
+			issuedAt = claims.IssuedAt.Time
+		}
+		if s.tokens.IsAccessRevoked(claims.ID, claims.UserID, issuedAt) {
+			return nil, errors.New("token has been revoked")
+		}
 	}
 
-	return nil, errors.New("invalid token")
+	return claims, nil
 }
 
 // ExtractUserID extracts the user ID from a token
@@ -147,6 +241,199 @@ func (s *Service) ExtractUserID(tokenString string) (string, error) {
 	return claims.UserID, nil
 }
 
+// ============================================================================
+// REFRESH TOKENS
+// ============================================================================
+
+// IssueTokens starts a new session: an access token plus the first refresh
+// token of a new family
+func (s *Service) IssueTokens(user *models.User) (*TokenPair, error) {
+	if s.tokens == nil {
+		return nil, ErrNoTokenStore
+	}
+
+	pair, record, err := s.newTokenPair(user, uuid.New().String())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.tokens.SaveRefreshToken(record); err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// Refresh exchanges a refresh token for a new token pair
+// The presented token is used up; presenting it again revokes the family
+// Parameters:
+//   - refreshToken: The refresh token from the client
+//   - lookup: Loads the token's user (and may reject e.g. inactive users)
+func (s *Service) Refresh(refreshToken string, lookup func(userID string) (*models.User, error)) (*TokenPair, error) {
+	if s.tokens == nil {
+		return nil, ErrNoTokenStore
+	}
+
+	hash := hashToken(refreshToken)
+	record, exists := s.tokens.GetRefreshToken(hash)
+	if !exists || record.Revoked || record.IsExpired() {
+		return nil, ErrInvalidRefreshToken
+	}
+	if record.IsUsed() {
+		s.tokens.RevokeRefreshFamily(record.FamilyID)
+		return nil, ErrRefreshTokenReused
+	}
+
+	user, err := lookup(record.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	pair, next, err := s.newTokenPair(user, record.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+
+	rotated, err := s.tokens.RotateRefreshToken(hash, next)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		// Lost a race with another use of the same token
+		s.tokens.RevokeRefreshFamily(record.FamilyID)
+		return nil, ErrRefreshTokenReused
+	}
+	return pair, nil
+}
+
+// RevokeRefreshToken ends the session a refresh token belongs to
+func (s *Service) RevokeRefreshToken(refreshToken string) error {
+	if s.tokens == nil {
+		return ErrNoTokenStore
+	}
+
+	record, exists := s.tokens.GetRefreshToken(hashToken(refreshToken))
+	if !exists {
+		return ErrInvalidRefreshToken
+	}
+	return s.tokens.RevokeRefreshFamily(record.FamilyID)
+}
+
+// RevokeAccessToken revokes a single access token until it expires
+func (s *Service) RevokeAccessToken(claims *Claims) error {
+	if s.tokens == nil {
+		return ErrNoTokenStore
+	}
+
+	expiresAt := time.Now().Add(accessExpiration)
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+	return s.tokens.RevokeAccessToken(claims.ID, expiresAt)
+}
+
+// RevokeUser revokes every session and access token a user holds
+// Used after a password change or by an admin
+func (s *Service) RevokeUser(userID string) error {
+	if s.tokens == nil {
+		return ErrNoTokenStore
+	}
+	return s.tokens.RevokeUser(userID, time.Now())
+}
+
+// newTokenPair creates an access token and a refresh token in a family
+// Returns the pair for the client and the record to store
+func (s *Service) newTokenPair(user *models.User, familyID string) (*TokenPair, models.RefreshToken, error) {
+	accessToken, err := s.GenerateToken(user)
+	if err != nil {
+		return nil, models.RefreshToken{}, err
+	}
+
+	refreshToken, err := randomToken()
+	if err != nil {
+		return nil, models.RefreshToken{}, err
+	}
+
+	now := time.Now()
+	record := models.RefreshToken{
+		TokenHash: hashToken(refreshToken),
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(refreshExpiration),
+	}
+
+	pair := &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(accessExpiration / time.Second),
+	}
+	return pair, record, nil
+}
+
+// randomToken returns a URL-safe random string for refresh and action tokens
+func randomToken() (string, error) {
+	raw := make([]byte, tokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// hashToken returns the hex SHA-256 of a token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ============================================================================
+// ACTION TOKENS
+// ============================================================================
+
+// IssueActionToken creates a single-use token to email to a user
+// Any earlier token the user holds for the same purpose stops working
+// Parameters:
+//   - user: The user the token acts for
+//   - purpose: models.PurposePasswordReset or models.PurposeVerifyEmail
+//   - ttl: How long the token stays valid
+func (s *Service) IssueActionToken(user *models.User, purpose string, ttl time.Duration) (string, error) {
+	if s.tokens == nil {
+		return "", ErrNoTokenStore
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	record := models.ActionToken{
+		TokenHash: hashToken(token),
+		UserID:    user.ID,
+		Purpose:   purpose,
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := s.tokens.SaveActionToken(record); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// ConsumeActionToken redeems a token for its purpose; it can't be used again
+// Returns the redeemed token so the caller can check the user and email
+func (s *Service) ConsumeActionToken(token, purpose string) (models.ActionToken, error) {
+	if s.tokens == nil {
+		return models.ActionToken{}, ErrNoTokenStore
+	}
+
+	record, ok, err := s.tokens.ConsumeActionToken(hashToken(token), purpose)
+	if err != nil {
+		return models.ActionToken{}, err
+	}
+	if !ok {
+		return models.ActionToken{}, ErrInvalidActionToken
+	}
+	return record, nil
+}
+
 // ============================================================================
 // AUTHORIZATION HELPERS
 // ============================================================================
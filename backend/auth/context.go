@@ -0,0 +1,76 @@
+/*
+================================================================================
+REQUEST CONTEXT - P2P Academic Library "The Knowledge Exchange"
+================================================================================
+This file carries the authenticated user through a request's context.
+
+Middleware validates the token (or API key) once and stores the result;
+handlers read it back with UserFromContext / ClaimsFromContext /
+APIKeyFromContext instead of re-parsing headers.
+
+Go Concepts Used:
+- context.Context: Request-scoped values
+- Unexported key types: Collision-free context keys
+- Comma-ok idiom: Optional values
+================================================================================
+*/
+
+package auth
+
+import (
+	"context"
+
+	"knowledge-exchange/models"
+)
+
+// ============================================================================
+// CONTEXT KEYS
+// ============================================================================
+
+// contextKey is unexported so no other package can collide with our keys
+type contextKey int
+
+const (
+	userContextKey contextKey = iota
+	claimsContextKey
+	apiKeyContextKey
+)
+
+// ============================================================================
+// CONTEXT HELPERS
+// ============================================================================
+
+// WithIdentity returns a copy of ctx carrying the authenticated user
+// Parameters:
+//   - ctx: Parent context (usually the request's)
+//   - user: The user the token belongs to
+//   - claims: The validated token claims
+func WithIdentity(ctx context.Context, user *models.User, claims *Claims) context.Context {
+	ctx = context.WithValue(ctx, userContextKey, user)
+	return context.WithValue(ctx, claimsContextKey, claims)
+}
+
+// WithAPIKey returns a copy of ctx carrying a user authenticated by API key
+// Requests made with a key carry no token claims
+func WithAPIKey(ctx context.Context, user *models.User, key *models.APIKey) context.Context {
+	ctx = context.WithValue(ctx, userContextKey, user)
+	return context.WithValue(ctx, apiKeyContextKey, key)
+}
+
+// UserFromContext returns the authenticated user, if any
+func UserFromContext(ctx context.Context) (*models.User, bool) {
+	user, ok := ctx.Value(userContextKey).(*models.User)
+	return user, ok && user != nil
+}
+
+// ClaimsFromContext returns the validated token claims, if any
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsContextKey).(*Claims)
+	return claims, ok && claims != nil
+}
+
+// APIKeyFromContext returns the API key the request was made with, if any
+func APIKeyFromContext(ctx context.Context) (*models.APIKey, bool) {
+	key, ok := ctx.Value(apiKeyContextKey).(*models.APIKey)
+	return key, ok && key != nil
+}
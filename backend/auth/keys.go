@@ -0,0 +1,562 @@
+/*
+================================================================================
+SIGNING KEYS - P2P Academic Library "The Knowledge Exchange"
+================================================================================
+This file implements the key ring used to sign and verify JWTs.
+
+Every key has an ID (kid) that is written into the token header, so tokens
+signed by an older key keep validating after a rotation until they expire.
+Retired keys are dropped once no token they signed can still be valid.
+
+Supported algorithms:
+- HS256: Shared secret (never published)
+- EdDSA: Ed25519 key pair
+- RS256: 2048-bit RSA key pair
+
+Asymmetric public keys are published as a JWKS so other nodes can verify
+tokens without holding the signing key.
+
+Go Concepts Used:
+- crypto/ed25519, crypto/rsa: Asymmetric signing
+- x509/PKCS#8: Key serialization
+- Sync.RWMutex: Safe rotation while requests are validated
+- File I/O: Persisting keys with restrictive permissions
+================================================================================
+*/
+
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ============================================================================
+// CONSTANTS
+// ============================================================================
+
+// Signing algorithms
+const (
+	AlgHS256 = "HS256"
+	AlgEdDSA = "EdDSA"
+	AlgRS256 = "RS256"
+)
+
+const (
+	// hmacSecretBytes is the size of generated HS256 secrets
+	hmacSecretBytes = 32
+
+	// rsaKeyBits is the size of generated RSA keys
+	rsaKeyBits = 2048
+)
+
+// ============================================================================
+// SIGNING KEY
+// ============================================================================
+
+// SigningKey is one key in the ring
+type SigningKey struct {
+	ID        string
+	Algorithm string
+	CreatedAt time.Time
+	RetiredAt time.Time // Zero while the key may still sign
+
+	secret  []byte        // HS256
+	private crypto.Signer // EdDSA / RS256
+}
+
+// KeyInfo describes a key without exposing key material
+type KeyInfo struct {
+	ID        string    `json:"kid"`
+	Algorithm string    `json:"alg"`
+	CreatedAt time.Time `json:"created_at"`
+	RetiredAt time.Time `json:"retired_at,omitempty"`
+	Active    bool      `json:"active"`
+}
+
+// method returns the jwt signing method for the key
+func (k *SigningKey) method() jwt.SigningMethod {
+	switch k.Algorithm {
+	case AlgEdDSA:
+		return jwt.SigningMethodEdDSA
+	case AlgRS256:
+		return jwt.SigningMethodRS256
+	default:
+		return jwt.SigningMethodHS256
+	}
+}
+
+// signingKey returns the key material passed to jwt when signing
+func (k *SigningKey) signingKey() interface{} {
+	if k.Algorithm == AlgHS256 {
+		return k.secret
+	}
+	return k.private
+}
+
+// verificationKey returns the key material passed to jwt when verifying
+func (k *SigningKey) verificationKey() interface{} {
+	if k.Algorithm == AlgHS256 {
+		return k.secret
+	}
+	return k.private.Public()
+}
+
+// GenerateSigningKey creates a new random key
+// Parameters:
+//   - algorithm: HS256, EdDSA or RS256
+func GenerateSigningKey(algorithm string) (*SigningKey, error) {
+	key := &SigningKey{Algorithm: algorithm, CreatedAt: time.Now()}
+
+	switch algorithm {
+	case AlgHS256:
+		key.secret = make([]byte, hmacSecretBytes)
+		if _, err := rand.Read(key.secret); err != nil {
+			return nil, err
+		}
+	case AlgEdDSA:
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		key.private = private
+	case AlgRS256:
+		private, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+		if err != nil {
+			return nil, err
+		}
+		key.private = private
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm: %s", algorithm)
+	}
+
+	id, err := randomKeyID()
+	if err != nil {
+		return nil, err
+	}
+	key.ID = id
+	return key, nil
+}
+
+// SecretSigningKey wraps a configured HS256 secret
+// The key ID is derived from the secret so every node configured with the
+// same secret agrees on it
+func SecretSigningKey(secret string) *SigningKey {
+	sum := sha256.Sum256([]byte(secret))
+	return &SigningKey{
+		ID:        "hs-" + hex.EncodeToString(sum[:6]),
+		Algorithm: AlgHS256,
+		CreatedAt: time.Now(),
+		secret:    []byte(secret),
+	}
+}
+
+// randomKeyID returns a short random key ID
+func randomKeyID() (string, error) {
+	raw := make([]byte, 8)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+// ============================================================================
+// KEY RING
+// ============================================================================
+
+// KeyRing holds the active signing key and older keys still trusted for
+// verification
+type KeyRing struct {
+	keys   map[string]*SigningKey
+	active string
+	path   string // Empty keeps generated keys in memory only
+
+	// retention is how long a retired key keeps verifying tokens
+	retention time.Duration
+
+	// pinned rings hold a configured secret and can't be rotated at runtime
+	pinned bool
+
+	mutex sync.RWMutex
+}
+
+// Key ring errors
+var ErrKeyRingPinned = errors.New("signing key is set by configuration; change the configured secret to rotate")
+
+// NewKeyRing creates an in-memory key ring with a single active key
+func NewKeyRing(key *SigningKey) *KeyRing {
+	return &KeyRing{
+		keys:      map[string]*SigningKey{key.ID: key},
+		active:    key.ID,
+		retention: accessExpiration,
+	}
+}
+
+// NewPinnedKeyRing creates a key ring for a configured HS256 secret
+func NewPinnedKeyRing(secret string) *KeyRing {
+	ring := NewKeyRing(SecretSigningKey(secret))
+	ring.pinned = true
+	return ring
+}
+
+// LoadKeyRing loads the key ring persisted at path, generating and saving a
+// first key of the given algorithm when there is none
+// Parameters:
+//   - path: JSON file holding the keys
+//   - algorithm: Algorithm for a newly generated key
+func LoadKeyRing(path, algorithm string) (*KeyRing, error) {
+	ring := &KeyRing{
+		keys:      make(map[string]*SigningKey),
+		path:      path,
+		retention: accessExpiration,
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if err := ring.decode(data); err != nil {
+			return nil, fmt.Errorf("invalid key file %s: %w", filepath.Base(path), err)
+		}
+	}
+
+	ring.mutex.Lock()
+	defer ring.mutex.Unlock()
+
+	ring.pruneLocked()
+	if _, ok := ring.keys[ring.active]; !ok {
+		key, err := GenerateSigningKey(algorithm)
+		if err != nil {
+			return nil, err
+		}
+		ring.keys[key.ID] = key
+		ring.active = key.ID
+	}
+	return ring, ring.saveLocked()
+}
+
+// Active returns the key new tokens are signed with
+func (r *KeyRing) Active() *SigningKey {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.keys[r.active]
+}
+
+// Get returns a key by ID, if it is still trusted
+func (r *KeyRing) Get(kid string) (*SigningKey, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	key, ok := r.keys[kid]
+	if !ok {
+		return nil, false
+	}
+	if !key.RetiredAt.IsZero() && time.Since(key.RetiredAt) > r.retention {
+		return nil, false
+	}
+	return key, true
+}
+
+// Rotate generates a new active key and retires the previous one
+// The previous key keeps verifying tokens until they have all expired
+func (r *KeyRing) Rotate(algorithm string) (*SigningKey, error) {
+	if r.pinned {
+		return nil, ErrKeyRingPinned
+	}
+
+	key, err := GenerateSigningKey(algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if previous, ok := r.keys[r.active]; ok {
+		previous.RetiredAt = time.Now()
+	}
+	r.keys[key.ID] = key
+	r.active = key.ID
+
+	r.pruneLocked()
+	return key, r.saveLocked()
+}
+
+// List describes every trusted key, newest first
+func (r *KeyRing) List() []KeyInfo {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	infos := make([]KeyInfo, 0, len(r.keys))
+	for _, key := range r.keys {
+		infos = append(infos, KeyInfo{
+			ID:        key.ID,
+			Algorithm: key.Algorithm,
+			CreatedAt: key.CreatedAt,
+			RetiredAt: key.RetiredAt,
+			Active:    key.ID == r.active,
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].CreatedAt.After(infos[j].CreatedAt)
+	})
+	return infos
+}
+
+// pruneLocked drops retired keys whose tokens have all expired
+// Caller must hold r.mutex
+func (r *KeyRing) pruneLocked() {
+	for kid, key := range r.keys {
+		if kid != r.active && !key.RetiredAt.IsZero() && time.Since(key.RetiredAt) > r.retention {
+			delete(r.keys, kid)
+		}
+	}
+}
+
+// ============================================================================
+// JWKS
+// ============================================================================
+
+// JWK is a public key in JSON Web Key format
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv,omitempty"`
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	X         string `json:"x,omitempty"` // Ed25519 public key, or EC x coordinate
+	Y         string `json:"y,omitempty"` // EC y coordinate
+	N         string `json:"n,omitempty"` // RSA modulus
+	E         string `json:"e,omitempty"` // RSA exponent
+}
+
+// JWKSet is the document served at the JWKS endpoint
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public halves of all trusted asymmetric keys
+// HS256 secrets are never published
+func (r *KeyRing) JWKS() JWKSet {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range r.keys {
+		if !key.RetiredAt.IsZero() && time.Since(key.RetiredAt) > r.retention {
+			continue
+		}
+
+		switch public := key.verificationKey().(type) {
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				KeyType:   "OKP",
+				Curve:     "Ed25519",
+				Algorithm: AlgEdDSA,
+				KeyID:     key.ID,
+				Use:       "sig",
+				X:         base64.RawURLEncoding.EncodeToString(public),
+			})
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				KeyType:   "RSA",
+				Algorithm: AlgRS256,
+				KeyID:     key.ID,
+				Use:       "sig",
+				N:         base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+				E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+			})
+		}
+	}
+
+	sort.Slice(set.Keys, func(i, j int) bool {
+		return set.Keys[i].KeyID < set.Keys[j].KeyID
+	})
+	return set
+}
+
+// PublicKey decodes a key published by another issuer (e.g. an OpenID
+// Connect provider)
+// Supports RSA, Ed25519 and P-256 keys
+func (k JWK) PublicKey() (crypto.PublicKey, error) {
+	decode := base64.RawURLEncoding.DecodeString
+
+	switch k.KeyType {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if len(n) == 0 || !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31 {
+			return nil, errors.New("invalid RSA key")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+
+	case "OKP":
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if k.Curve != "Ed25519" || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+
+	case "EC":
+		if k.Curve != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Curve)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("invalid EC key")
+		}
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %q", k.KeyType)
+}
+
+// ============================================================================
+// PERSISTENCE
+// ============================================================================
+
+// storedKey is the on-disk form of a signing key
+type storedKey struct {
+	ID        string    `json:"kid"`
+	Algorithm string    `json:"alg"`
+	CreatedAt time.Time `json:"created_at"`
+	RetiredAt time.Time `json:"retired_at,omitempty"`
+	Secret    string    `json:"secret,omitempty"`      // HS256, base64
+	Private   string    `json:"private_key,omitempty"` // PKCS#8 DER, base64
+}
+
+// storedRing is the on-disk form of the key ring
+type storedRing struct {
+	Active string      `json:"active"`
+	Keys   []storedKey `json:"keys"`
+}
+
+// decode loads keys from their stored form
+func (r *KeyRing) decode(data []byte) error {
+	var stored storedRing
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return err
+	}
+
+	for _, sk := range stored.Keys {
+		key := &SigningKey{
+			ID:        sk.ID,
+			Algorithm: sk.Algorithm,
+			CreatedAt: sk.CreatedAt,
+			RetiredAt: sk.RetiredAt,
+		}
+
+		if sk.Algorithm == AlgHS256 {
+			secret, err := base64.StdEncoding.DecodeString(sk.Secret)
+			if err != nil {
+				return err
+			}
+			key.secret = secret
+		} else {
+			der, err := base64.StdEncoding.DecodeString(sk.Private)
+			if err != nil {
+				return err
+			}
+			parsed, err := x509.ParsePKCS8PrivateKey(der)
+			if err != nil {
+				return err
+			}
+			signer, ok := parsed.(crypto.Signer)
+			if !ok {
+				return errors.New("unsupported private key type")
+			}
+			key.private = signer
+		}
+
+		r.keys[key.ID] = key
+	}
+	r.active = stored.Active
+	return nil
+}
+
+// saveLocked writes the key ring to disk (owner-readable only)
+// Caller must hold r.mutex
+func (r *KeyRing) saveLocked() error {
+	if r.path == "" {
+		return nil
+	}
+
+	stored := storedRing{Active: r.active}
+	for _, key := range r.keys {
+		sk := storedKey{
+			ID:        key.ID,
+			Algorithm: key.Algorithm,
+			CreatedAt: key.CreatedAt,
+			RetiredAt: key.RetiredAt,
+		}
+		if key.Algorithm == AlgHS256 {
+			sk.Secret = base64.StdEncoding.EncodeToString(key.secret)
+		} else {
+			der, err := x509.MarshalPKCS8PrivateKey(key.private)
+			if err != nil {
+				return err
+			}
+			sk.Private = base64.StdEncoding.EncodeToString(der)
+		}
+		stored.Keys = append(stored.Keys, sk)
+	}
+	sort.Slice(stored.Keys, func(i, j int) bool {
+		return stored.Keys[i].CreatedAt.Before(stored.Keys[j].CreatedAt)
+	})
+
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return err
+	}
+	tmp := r.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, r.path)
+}
@@ -0,0 +1,374 @@
+/*
+================================================================================
+LOGIN LOCKOUT - P2P Academic Library "The Knowledge Exchange"
+================================================================================
+This file implements brute-force protection for password logins.
+
+Failed logins are counted per account and per client address. Past a number
+of free attempts every further failure locks the key for twice as long as
+the last one, up to a maximum. A success clears the account's counter; the
+address counter only expires, so one valid account can't be used to reset
+it. Each lockout and each admin unlock is written to an audit trail.
+
+Logins for one account are also serialized (and capped per address) while
+the password is checked, so a burst of parallel requests can't all slip in
+before the first failure is counted. A login arriving while another for the
+same account is being checked waits its turn rather than being refused, so
+a stream of requests for someone else's account can't keep them out.
+
+Go Concepts Used:
+- Interfaces: Pluggable attempt storage
+- Mutex: Atomic read-modify-write of counters
+- Channels: Waking logins queued behind one for the same account
+- Bit shifts: Exponential backoff
+================================================================================
+*/
+
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"knowledge-exchange/models"
+)
+
+// ============================================================================
+// LOCKOUT POLICY
+// ============================================================================
+
+// LockoutPolicy configures how failed logins are throttled
+type LockoutPolicy struct {
+	// AccountAttempts is how many failures an account gets before lockouts;
+	// the failure after them is the first to lock
+	AccountAttempts int
+
+	// AddressAttempts is the same for a client address (higher, since many
+	// users may share one address)
+	AddressAttempts int
+
+	// BaseDelay is the first lockout; each further failure doubles it
+	BaseDelay time.Duration
+
+	// MaxDelay caps a single lockout
+	MaxDelay time.Duration
+
+	// FailureWindow forgets failures older than this
+	FailureWindow time.Duration
+
+	// AddressConcurrency caps logins in progress from one address
+	AddressConcurrency int
+}
+
+// DefaultLockoutPolicy returns the default lockout settings
+func DefaultLockoutPolicy() LockoutPolicy {
+	return LockoutPolicy{
+		AccountAttempts:    5,
+		AddressAttempts:    20,
+		BaseDelay:          30 * time.Second,
+		MaxDelay:           time.Hour,
+		FailureWindow:      24 * time.Hour,
+		AddressConcurrency: 4,
+	}
+}
+
+// Delay returns the lockout after a number of failures
+// Parameters:
+//   - failures: Consecutive failures including the latest
+//   - free: Failures allowed before lockouts begin
+func (p LockoutPolicy) Delay(failures, free int) time.Duration {
+	if failures <= free {
+		return 0
+	}
+
+	// Cap the shift so large counts can't overflow
+	shift := failures - free - 1
+	if shift > 30 {
+		shift = 30
+	}
+	delay := p.BaseDelay << uint(shift)
+	if delay > p.MaxDelay || delay <= 0 {
+		return p.MaxDelay
+	}
+	return delay
+}
+
+// ============================================================================
+// ATTEMPT STORE INTERFACE
+// ============================================================================
+
+// AttemptStore persists failed-login counters and the security audit trail
+// Implemented by storage.AttemptStore
+type AttemptStore interface {
+	GetAttempts(key string) (models.LoginAttempts, bool)
+	SaveAttempts(attempts models.LoginAttempts) error
+	ClearAttempts(key string) (bool, error)
+	ListLocked(now time.Time) []models.LoginAttempts
+	RecordEvent(event models.SecurityEvent) error
+	RecentEvents(limit int) []models.SecurityEvent
+}
+
+// ============================================================================
+// LOGIN GUARD
+// ============================================================================
+
+// ErrUnknownLockKey is returned when unlocking a key with no failures
+var ErrUnknownLockKey = errors.New("no failed logins recorded for this key")
+
+// busyRetry is the wait suggested when a login is refused because others
+// for the same account or address are still in progress
+const busyRetry = time.Second
+
+// accountQueueWait is how long a login waits for one already in progress
+// for the same account before it is refused
+const accountQueueWait = 10 * time.Second
+
+// LoginGuard tracks failed logins and decides when to refuse attempts
+type LoginGuard struct {
+	policy LockoutPolicy
+	store  AttemptStore
+
+	// inFlight counts logins being checked per key (memory only)
+	inFlight map[string]int
+
+	// turns is closed when an account's login in progress finishes, waking
+	// the logins queued behind it
+	turns map[string]chan struct{}
+
+	mu sync.Mutex
+}
+
+// NewLoginGuard creates a login guard
+// Parameters:
+//   - store: Where counters and audit events are kept
+//   - policy: Attempt limits and backoff
+func NewLoginGuard(store AttemptStore, policy LockoutPolicy) *LoginGuard {
+	return &LoginGuard{
+		policy:   policy,
+		store:    store,
+		inFlight: make(map[string]int),
+		turns:    make(map[string]chan struct{}),
+	}
+}
+
+// AccountKey returns the counter key for an account
+func AccountKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// AddressKey returns the counter key for a client address
+func AddressKey(address string) string {
+	return "ip:" + address
+}
+
+// Begin starts a login for the email from the address
+// It is refused while the account or address is locked out, or while too
+// many other logins from the address are in progress. While another login
+// for the account is being checked it waits its turn, up to
+// accountQueueWait. Every allowed Begin must be followed by Finish once the
+// attempt has been recorded.
+// Returns:
+//   - time.Duration: How long to wait before retrying (when refused)
+//   - bool: True if the login may proceed
+func (g *LoginGuard) Begin(email, address string) (time.Duration, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	accountKey, addressKey := AccountKey(email), AddressKey(address)
+
+	// Queued logins hold an address slot, so one address can't pile up
+	// waiters behind an account
+	if g.inFlight[addressKey] >= g.policy.AddressConcurrency {
+		return busyRetry, false
+	}
+	g.inFlight[addressKey]++
+
+	deadline := time.Now().Add(accountQueueWait)
+	for {
+		// Checked again after every wait: the login ahead may have failed
+		// and locked the account
+		if wait := g.lockedFor(accountKey, addressKey); wait > 0 {
+			g.release(addressKey)
+			return wait, false
+		}
+		if g.inFlight[accountKey] == 0 {
+			break
+		}
+		if !g.waitTurn(accountKey, deadline) {
+			g.release(addressKey)
+			return busyRetry, false
+		}
+	}
+
+	g.inFlight[accountKey]++
+	return 0, true
+}
+
+// Finish ends a login started with Begin
+func (g *LoginGuard) Finish(email, address string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	accountKey := AccountKey(email)
+	g.release(accountKey)
+	g.release(AddressKey(address))
+
+	if turn, exists := g.turns[accountKey]; exists {
+		close(turn)
+		delete(g.turns, accountKey)
+	}
+}
+
+// lockedFor returns the longest lockout in force on any of the keys
+// Caller must hold g.mu
+func (g *LoginGuard) lockedFor(keys ...string) time.Duration {
+	now := time.Now()
+	var wait time.Duration
+	for _, key := range keys {
+		attempts, exists := g.store.GetAttempts(key)
+		if exists && attempts.IsLocked(now) {
+			if remaining := attempts.LockedUntil.Sub(now); remaining > wait {
+				wait = remaining
+			}
+		}
+	}
+	return wait
+}
+
+// waitTurn releases g.mu until the account's login in progress finishes
+// Caller must hold g.mu; it is held again on return
+// Returns false if the deadline passed first
+func (g *LoginGuard) waitTurn(accountKey string, deadline time.Time) bool {
+	remaining := time.Until(deadline)
+	if remaining <= 0 {
+		return false
+	}
+
+	turn, exists := g.turns[accountKey]
+	if !exists {
+		turn = make(chan struct{})
+		g.turns[accountKey] = turn
+	}
+
+	g.mu.Unlock()
+	defer g.mu.Lock()
+
+	timer := time.NewTimer(remaining)
+	defer timer.Stop()
+	select {
+	case <-turn:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// release drops one in-flight login for a key
+// Caller must hold g.mu
+func (g *LoginGuard) release(key string) {
+	if g.inFlight[key] <= 1 {
+		delete(g.inFlight, key)
+	} else {
+		g.inFlight[key]--
+	}
+}
+
+// RecordFailure counts a failed login against the account and the address
+// Unknown emails are counted too, so a lockout says nothing about whether
+// an account exists
+// Returns the lockout now in force (0 if none)
+func (g *LoginGuard) RecordFailure(email, address string) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	accountWait := g.fail(AccountKey(email), g.policy.AccountAttempts)
+	addressWait := g.fail(AddressKey(address), g.policy.AddressAttempts)
+	if addressWait > accountWait {
+		return addressWait
+	}
+	return accountWait
+}
+
+// RecordSuccess clears the account's failures after a successful login
+func (g *LoginGuard) RecordSuccess(email string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, err := g.store.ClearAttempts(AccountKey(email)); err != nil {
+		log.Printf("Warning: failed to clear login attempts: %v", err)
+	}
+}
+
+// Unlock clears a key's failures and lockout, and audits who did it
+// Parameters:
+//   - key: An AccountKey or AddressKey
+//   - by: Username of the admin unlocking
+func (g *LoginGuard) Unlock(key, by string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	cleared, err := g.store.ClearAttempts(key)
+	if err != nil {
+		return err
+	}
+	if !cleared {
+		return ErrUnknownLockKey
+	}
+
+	g.audit(models.SecurityUnlock, key, fmt.Sprintf("Unlocked by %s", by))
+	return nil
+}
+
+// Locked returns every account and address currently locked out
+func (g *LoginGuard) Locked() []models.LoginAttempts {
+	return g.store.ListLocked(time.Now())
+}
+
+// Audit returns up to limit recent security events, newest first
+func (g *LoginGuard) Audit(limit int) []models.SecurityEvent {
+	return g.store.RecentEvents(limit)
+}
+
+// fail increments a key's counter and applies the backoff
+// Caller must hold g.mu
+func (g *LoginGuard) fail(key string, free int) time.Duration {
+	now := time.Now()
+	attempts, exists := g.store.GetAttempts(key)
+	if !exists || now.Sub(attempts.LastFailure) > g.policy.FailureWindow {
+		attempts = models.LoginAttempts{Key: key}
+	}
+
+	attempts.Failures++
+	attempts.LastFailure = now
+	delay := g.policy.Delay(attempts.Failures, free)
+	if delay > 0 {
+		attempts.LockedUntil = now.Add(delay)
+	}
+
+	if err := g.store.SaveAttempts(attempts); err != nil {
+		log.Printf("Warning: failed to save login attempts: %v", err)
+	}
+	if delay > 0 {
+		g.audit(models.SecurityLockout, key,
+			fmt.Sprintf("Locked for %s after %d failed logins", delay, attempts.Failures))
+	}
+	return delay
+}
+
+// audit records and logs a security event
+func (g *LoginGuard) audit(eventType, key, detail string) {
+	log.Printf("Security: %s %s: %s", eventType, key, detail)
+	err := g.store.RecordEvent(models.SecurityEvent{
+		Type:      eventType,
+		Key:       key,
+		Detail:    detail,
+		Timestamp: time.Now(),
+	})
+	if err != nil {
+		log.Printf("Warning: failed to record security event: %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+/*
+================================================================================
+LOGIN LOCKOUT TESTS - P2P Academic Library "The Knowledge Exchange"
+================================================================================
+Checks when failed logins start locking an account, and that a login for an
+account already being checked waits its turn instead of being refused.
+================================================================================
+*/
+
+package auth
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"knowledge-exchange/storage"
+)
+
+// newTestGuard creates a login guard on an attempt store in a temp directory
+func newTestGuard(t *testing.T) *LoginGuard {
+	t.Helper()
+
+	store, err := storage.NewAttemptStore(filepath.Join(t.TempDir(), "attempts.json"))
+	if err != nil {
+		t.Fatalf("attempt store: %v", err)
+	}
+	return NewLoginGuard(store, DefaultLockoutPolicy())
+}
+
+// TestLockoutAfterFreeAttempts checks every free attempt can fail without
+// a lockout and the one after them locks for BaseDelay
+func TestLockoutAfterFreeAttempts(t *testing.T) {
+	guard := newTestGuard(t)
+	policy := DefaultLockoutPolicy()
+
+	for i := 1; i <= policy.AccountAttempts; i++ {
+		if wait := guard.RecordFailure("victim@university.edu", "10.0.0.1"); wait != 0 {
+			t.Fatalf("failure %d of %d free locked for %s", i, policy.AccountAttempts, wait)
+		}
+	}
+	if wait := guard.RecordFailure("victim@university.edu", "10.0.0.1"); wait != policy.BaseDelay {
+		t.Fatalf("failure %d locked for %s, want %s", policy.AccountAttempts+1, wait, policy.BaseDelay)
+	}
+	if wait := policy.Delay(policy.AccountAttempts+2, policy.AccountAttempts); wait != 2*policy.BaseDelay {
+		t.Fatalf("next failure locks for %s, want %s", wait, 2*policy.BaseDelay)
+	}
+}
+
+// TestConcurrentLoginWaitsItsTurn checks a login arriving while another for
+// the same account is in progress proceeds once that one finishes
+func TestConcurrentLoginWaitsItsTurn(t *testing.T) {
+	guard := newTestGuard(t)
+
+	if _, ok := guard.Begin("victim@university.edu", "10.0.0.66"); !ok {
+		t.Fatal("first login refused")
+	}
+
+	started := make(chan bool)
+	go func() {
+		_, ok := guard.Begin("victim@university.edu", "10.0.0.1")
+		started <- ok
+	}()
+
+	select {
+	case <-started:
+		t.Fatal("second login started while the first was in progress")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	guard.Finish("victim@university.edu", "10.0.0.66")
+	select {
+	case ok := <-started:
+		if !ok {
+			t.Fatal("queued login refused after the first finished")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("queued login never started")
+	}
+	guard.Finish("victim@university.edu", "10.0.0.1")
+}
@@ -0,0 +1,453 @@
+/*
+================================================================================
+OPENID CONNECT PROVIDER - P2P Academic Library "The Knowledge Exchange"
+================================================================================
+This file implements an identity provider client for OpenID Connect, so
+students and staff can sign in with their university's single sign-on.
+
+The provider's endpoints and signing keys are discovered from
+{issuer}/.well-known/openid-configuration the first time they are needed,
+so the node starts even while the provider is unreachable. Logins use the
+authorization code flow with PKCE (RFC 7636). The ID token returned by the
+token endpoint is verified here: signature against the provider's JWKS,
+issuer, audience, expiry and the nonce sent with the login.
+
+Go Concepts Used:
+- Interfaces: Pluggable identity providers
+- net/http: Discovery, JWKS and token requests
+- Sync.Mutex: Caching provider metadata and keys
+- JWT: ID token verification
+================================================================================
+*/
+
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ============================================================================
+// CONSTANTS
+// ============================================================================
+
+const (
+	// oidcTimeout bounds every request to the identity provider
+	oidcTimeout = 10 * time.Second
+
+	// oidcMaxResponse caps how much of a provider response is read
+	oidcMaxResponse = 1 << 20
+
+	// jwksRefreshInterval limits how often an unknown key ID triggers a
+	// JWKS refetch, so forged tokens can't hammer the provider
+	jwksRefreshInterval = time.Minute
+
+	// idTokenLeeway tolerates clock skew with the provider
+	idTokenLeeway = time.Minute
+)
+
+// idTokenAlgorithms are the ID token signatures accepted; "none" and
+// shared-secret algorithms never are
+var idTokenAlgorithms = []string{AlgRS256, "ES256", AlgEdDSA}
+
+// ============================================================================
+// IDENTITY PROVIDER INTERFACE
+// ============================================================================
+
+// ExternalIdentity is a person as vouched for by an identity provider
+type ExternalIdentity struct {
+	Provider          string // IdentityProvider.Name()
+	Issuer            string
+	Subject           string // Stable ID of the person at the issuer
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+}
+
+// IdentityProvider signs people in through an external service
+// Implemented by OIDCProvider; other providers (or a fake in tests) can be
+// registered with SingleSignOn the same way
+type IdentityProvider interface {
+	// Name identifies the provider in API requests ("university")
+	Name() string
+
+	// DisplayName labels the provider's login button
+	DisplayName() string
+
+	// AuthorizationURL returns the URL to send the browser to
+	// Parameters:
+	//   - state: Opaque value echoed back to the redirect URL
+	//   - nonce: Value the ID token must carry
+	//   - codeChallenge: S256 PKCE challenge
+	AuthorizationURL(ctx context.Context, state, nonce, codeChallenge string) (string, error)
+
+	// Exchange redeems an authorization code and returns the verified person
+	// Parameters:
+	//   - code: Code from the redirect URL
+	//   - codeVerifier: PKCE verifier the challenge was derived from
+	//   - nonce: The nonce sent with AuthorizationURL
+	Exchange(ctx context.Context, code, codeVerifier, nonce string) (*ExternalIdentity, error)
+}
+
+// ============================================================================
+// OIDC PROVIDER
+// ============================================================================
+
+// OIDCConfig configures an OpenID Connect provider
+type OIDCConfig struct {
+	Name         string   // Provider name in API requests
+	DisplayName  string   // Login button label
+	Issuer       string   // e.g. https://sso.university.edu
+	ClientID     string   // Registered with the provider
+	ClientSecret string   // Empty for a public client (PKCE only)
+	RedirectURL  string   // Frontend page that receives ?code&state
+	Scopes       []string // "openid" is always requested
+
+	// HTTPClient makes provider requests; nil uses a client with a timeout
+	HTTPClient *http.Client
+}
+
+// oidcMetadata is the part of the discovery document used here
+type oidcMetadata struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	CodeChallengeMethods  []string `json:"code_challenge_methods_supported"`
+}
+
+// OIDCProvider is an OpenID Connect identity provider
+type OIDCProvider struct {
+	config OIDCConfig
+	client *http.Client
+
+	mu          sync.Mutex
+	metadata    *oidcMetadata               // nil until discovered
+	keys        map[string]crypto.PublicKey // kid -> ID token signing key
+	keysFetched time.Time
+}
+
+// NewOIDCProvider creates a provider; nothing is fetched until first use
+// Parameters:
+//   - config: Issuer, client registration and redirect URL
+//
+// Returns:
+//   - *OIDCProvider: The provider
+//   - error: If the configuration is incomplete
+func NewOIDCProvider(config OIDCConfig) (*OIDCProvider, error) {
+	if config.Issuer == "" || config.ClientID == "" || config.RedirectURL == "" {
+		return nil, errors.New("OIDC provider needs an issuer, client ID and redirect URL")
+	}
+	if _, err := url.ParseRequestURI(config.Issuer); err != nil {
+		return nil, fmt.Errorf("invalid OIDC issuer: %w", err)
+	}
+	if config.Name == "" {
+		config.Name = "oidc"
+	}
+	if config.DisplayName == "" {
+		config.DisplayName = "Single sign-on"
+	}
+
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: oidcTimeout}
+	}
+
+	return &OIDCProvider{
+		config: config,
+		client: client,
+		keys:   make(map[string]crypto.PublicKey),
+	}, nil
+}
+
+// Name identifies the provider in API requests
+func (p *OIDCProvider) Name() string {
+	return p.config.Name
+}
+
+// DisplayName labels the provider's login button
+func (p *OIDCProvider) DisplayName() string {
+	return p.config.DisplayName
+}
+
+// AuthorizationURL builds the authorization request for a login
+func (p *OIDCProvider) AuthorizationURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	scopes := []string{"openid"}
+	for _, scope := range p.config.Scopes {
+		if scope != "openid" {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", p.config.ClientID)
+	params.Set("redirect_uri", p.config.RedirectURL)
+	params.Set("scope", strings.Join(scopes, " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", codeChallenge)
+	params.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return metadata.AuthorizationEndpoint + separator + params.Encode(), nil
+}
+
+// Exchange redeems an authorization code at the token endpoint and
+// verifies the ID token it returns
+func (p *OIDCProvider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*ExternalIdentity, error) {
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.config.RedirectURL)
+	form.Set("client_id", p.config.ClientID)
+	form.Set("code_verifier", codeVerifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.config.ClientSecret != "" {
+		// client_secret_basic, which every provider must support
+		req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	status, err := p.doJSON(req, &body)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	if status != http.StatusOK || body.Error != "" {
+		if body.ErrorDescription != "" {
+			return nil, fmt.Errorf("token request refused: %s (%s)", body.Error, body.ErrorDescription)
+		}
+		return nil, fmt.Errorf("token request refused: %s (HTTP %d)", body.Error, status)
+	}
+	if body.IDToken == "" {
+		return nil, errors.New("provider returned no ID token")
+	}
+
+	return p.verifyIDToken(ctx, metadata, body.IDToken, nonce)
+}
+
+// ============================================================================
+// DISCOVERY AND KEYS
+// ============================================================================
+
+// discover returns the provider metadata, fetching it on first use
+// A failed fetch isn't cached, so the next login retries
+func (p *OIDCProvider) discover(ctx context.Context) (*oidcMetadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	endpoint := strings.TrimSuffix(p.config.Issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var metadata oidcMetadata
+	status, err := p.doJSON(req, &metadata)
+	if err != nil {
+		return nil, fmt.Errorf("OIDC discovery failed: %w", err)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("OIDC discovery failed: HTTP %d", status)
+	}
+
+	// The document must be about the configured issuer (OIDC Discovery 4.3)
+	if metadata.Issuer != p.config.Issuer {
+		return nil, fmt.Errorf("OIDC discovery returned issuer %q, expected %q", metadata.Issuer, p.config.Issuer)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, errors.New("OIDC discovery document is missing endpoints")
+	}
+	if len(metadata.CodeChallengeMethods) > 0 && !containsString(metadata.CodeChallengeMethods, "S256") {
+		return nil, errors.New("OIDC provider doesn't support PKCE with S256")
+	}
+
+	p.metadata = &metadata
+	return p.metadata, nil
+}
+
+// signingKey returns the provider key with an ID, refetching the JWKS
+// when the key is unknown (the provider may have rotated)
+// An empty kid is accepted when the provider publishes a single key
+func (p *OIDCProvider) signingKey(ctx context.Context, metadata *oidcMetadata, kid string) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.lookupKeyLocked(kid); ok {
+		return key, nil
+	}
+	if time.Since(p.keysFetched) < jwksRefreshInterval {
+		return nil, errors.New("unknown ID token signing key")
+	}
+	p.keysFetched = time.Now()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadata.JWKSURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	var set JWKSet
+	status, err := p.doJSON(req, &set)
+	if err != nil {
+		return nil, fmt.Errorf("JWKS request failed: %w", err)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("JWKS request failed: HTTP %d", status)
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.PublicKey()
+		if err != nil {
+			continue // Skip key types we can't use
+		}
+		keys[jwk.KeyID] = key
+	}
+	p.keys = keys
+
+	if key, ok := p.lookupKeyLocked(kid); ok {
+		return key, nil
+	}
+	return nil, errors.New("unknown ID token signing key")
+}
+
+// lookupKeyLocked finds a cached key
+// Caller must hold p.mu
+func (p *OIDCProvider) lookupKeyLocked(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+// doJSON sends a request and decodes a JSON response of any status
+// Returns the HTTP status
+func (p *OIDCProvider) doJSON(req *http.Request, out interface{}) (int, error) {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, oidcMaxResponse))
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if err := json.Unmarshal(data, out); err != nil && resp.StatusCode == http.StatusOK {
+		return resp.StatusCode, fmt.Errorf("invalid JSON response: %w", err)
+	}
+	return resp.StatusCode, nil
+}
+
+// ============================================================================
+// ID TOKEN VERIFICATION
+// ============================================================================
+
+// idTokenClaims are the ID token claims used to identify a person
+type idTokenClaims struct {
+	Nonce             string `json:"nonce"`
+	AuthorizedParty   string `json:"azp,omitempty"`
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	Name              string `json:"name"`
+	PreferredUsername string `json:"preferred_username"`
+	jwt.RegisteredClaims
+}
+
+// verifyIDToken checks an ID token per OIDC Core 3.1.3.7 and returns the
+// person it names
+func (p *OIDCProvider) verifyIDToken(ctx context.Context, metadata *oidcMetadata, idToken, nonce string) (*ExternalIdentity, error) {
+	parser := jwt.NewParser(
+		jwt.WithValidMethods(idTokenAlgorithms),
+		jwt.WithIssuer(metadata.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(idTokenLeeway),
+	)
+
+	var claims idTokenClaims
+	_, err := parser.ParseWithClaims(idToken, &claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.signingKey(ctx, metadata, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+
+	if claims.Subject == "" {
+		return nil, errors.New("invalid ID token: no subject")
+	}
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, errors.New("invalid ID token: nonce mismatch")
+	}
+	// A token issued to several clients must name us as the party it's for
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.config.ClientID {
+		return nil, errors.New("invalid ID token: issued for another client")
+	}
+
+	return &ExternalIdentity{
+		Provider:          p.config.Name,
+		Issuer:            metadata.Issuer,
+		Subject:           claims.Subject,
+		Email:             strings.TrimSpace(claims.Email),
+		EmailVerified:     claims.EmailVerified,
+		Name:              claims.Name,
+		PreferredUsername: claims.PreferredUsername,
+	}, nil
+}
+
+// containsString checks if a list contains a value
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
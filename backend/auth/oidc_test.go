@@ -0,0 +1,656 @@
+/*
+================================================================================
+SINGLE SIGN-ON TESTS - P2P Academic Library "The Knowledge Exchange"
+================================================================================
+Runs logins against a fake OpenID Connect provider served by httptest:
+discovery, JWKS and a token endpoint that checks the PKCE verifier and
+signs ID tokens the way a test asks. Covers ID token verification, the
+single-use login state, and how identities map to local accounts.
+================================================================================
+*/
+
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"knowledge-exchange/models"
+	"knowledge-exchange/storage"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ============================================================================
+// FAKE IDENTITY PROVIDER
+// ============================================================================
+
+const (
+	testClientID     = "knowledge-exchange"
+	testClientSecret = "client-secret"
+	testRedirectURL  = "http://localhost:3000/sso/callback"
+	testKeyID        = "idp-key-1"
+)
+
+// testPerson is who signs in at the fake provider
+type testPerson struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+}
+
+// authorizationGrant is a code the fake provider handed out
+type authorizationGrant struct {
+	challenge string
+	nonce     string
+	person    testPerson
+}
+
+// fakeIdP is an OpenID Connect provider on a loopback server
+type fakeIdP struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	// tamper edits each ID token before it is signed; nil leaves it alone
+	tamper func(token *jwt.Token)
+
+	// method and signer sign ID tokens; zero values use RS256 and key
+	method jwt.SigningMethod
+	signer interface{}
+
+	mu            sync.Mutex
+	grants        map[string]authorizationGrant // code -> grant
+	tokenRequests atomic.Int32
+}
+
+// newFakeIdP starts a provider that stops when the test ends
+func newFakeIdP(t *testing.T) *fakeIdP {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	idp := &fakeIdP{
+		key:    key,
+		grants: make(map[string]authorizationGrant),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", idp.discovery)
+	mux.HandleFunc("/jwks", idp.jwks)
+	mux.HandleFunc("/token", idp.token)
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+// issuer is the provider's issuer identifier
+func (idp *fakeIdP) issuer() string {
+	return idp.server.URL
+}
+
+func (idp *fakeIdP) discovery(w http.ResponseWriter, req *http.Request) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"issuer":                           idp.issuer(),
+		"authorization_endpoint":           idp.issuer() + "/authorize",
+		"token_endpoint":                   idp.issuer() + "/token",
+		"jwks_uri":                         idp.issuer() + "/jwks",
+		"code_challenge_methods_supported": []string{"S256"},
+	})
+}
+
+func (idp *fakeIdP) jwks(w http.ResponseWriter, req *http.Request) {
+	encode := base64.RawURLEncoding.EncodeToString
+	json.NewEncoder(w).Encode(JWKSet{Keys: []JWK{{
+		KeyType:   "RSA",
+		Algorithm: AlgRS256,
+		KeyID:     testKeyID,
+		Use:       "sig",
+		N:         encode(idp.key.N.Bytes()),
+		E:         encode(big.NewInt(int64(idp.key.E)).Bytes()),
+	}}})
+}
+
+// token redeems a code once, if the client and PKCE verifier check out
+func (idp *fakeIdP) token(w http.ResponseWriter, req *http.Request) {
+	idp.tokenRequests.Add(1)
+
+	refuse := func(code, description string) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": code, "error_description": description})
+	}
+
+	clientID, secret, ok := req.BasicAuth()
+	if !ok || clientID != testClientID || secret != testClientSecret {
+		refuse("invalid_client", "bad client credentials")
+		return
+	}
+	if req.PostFormValue("grant_type") != "authorization_code" || req.PostFormValue("redirect_uri") != testRedirectURL {
+		refuse("invalid_request", "bad grant type or redirect URI")
+		return
+	}
+
+	idp.mu.Lock()
+	grant, exists := idp.grants[req.PostFormValue("code")]
+	delete(idp.grants, req.PostFormValue("code"))
+	idp.mu.Unlock()
+	if !exists {
+		refuse("invalid_grant", "unknown or used code")
+		return
+	}
+	if pkceChallenge(req.PostFormValue("code_verifier")) != grant.challenge {
+		refuse("invalid_grant", "PKCE verification failed")
+		return
+	}
+
+	now := time.Now()
+	method, signer := idp.method, idp.signer
+	if method == nil {
+		method, signer = jwt.SigningMethodRS256, idp.key
+	}
+	token := jwt.NewWithClaims(method, jwt.MapClaims{
+		"iss":                idp.issuer(),
+		"sub":                grant.person.Subject,
+		"aud":                testClientID,
+		"iat":                now.Unix(),
+		"exp":                now.Add(5 * time.Minute).Unix(),
+		"nonce":              grant.nonce,
+		"email":              grant.person.Email,
+		"email_verified":     grant.person.EmailVerified,
+		"name":               grant.person.Name,
+		"preferred_username": grant.person.PreferredUsername,
+	})
+	token.Header["kid"] = testKeyID
+	if idp.tamper != nil {
+		idp.tamper(token)
+	}
+	idToken, err := token.SignedString(signer)
+	if err != nil {
+		refuse("server_error", err.Error())
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"id_token":     idToken,
+	})
+}
+
+// approve plays the browser at the authorization endpoint: the person
+// signs in and the provider returns a code for the login's parameters
+// Returns the code and the state to come back with
+func (idp *fakeIdP) approve(t *testing.T, authURL string, person testPerson) (string, string) {
+	t.Helper()
+
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("parse authorization URL: %v", err)
+	}
+	if !strings.HasPrefix(authURL, idp.issuer()+"/authorize?") {
+		t.Fatalf("authorization URL %s is not at the provider", authURL)
+	}
+	query := parsed.Query()
+	for param, want := range map[string]string{
+		"response_type":         "code",
+		"client_id":             testClientID,
+		"redirect_uri":          testRedirectURL,
+		"code_challenge_method": "S256",
+	} {
+		if got := query.Get(param); got != want {
+			t.Fatalf("authorization %s = %q, want %q", param, got, want)
+		}
+	}
+	if !strings.Contains(" "+query.Get("scope")+" ", " openid ") {
+		t.Fatalf("scope %q doesn't request openid", query.Get("scope"))
+	}
+	if query.Get("nonce") == "" || query.Get("code_challenge") == "" {
+		t.Fatal("authorization URL has no nonce or code challenge")
+	}
+
+	code, err := randomToken()
+	if err != nil {
+		t.Fatalf("code: %v", err)
+	}
+	idp.mu.Lock()
+	idp.grants[code] = authorizationGrant{
+		challenge: query.Get("code_challenge"),
+		nonce:     query.Get("nonce"),
+		person:    person,
+	}
+	idp.mu.Unlock()
+	return code, query.Get("state")
+}
+
+// pkceChallenge derives the S256 challenge for a verifier
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// ============================================================================
+// HELPERS
+// ============================================================================
+
+// alice is the person most tests sign in as
+var alice = testPerson{
+	Subject:           "alice-subject",
+	Email:             "alice@university.edu",
+	EmailVerified:     true,
+	Name:              "Alice Example",
+	PreferredUsername: "alice",
+}
+
+// newTestProvider creates a client for the fake provider
+func newTestProvider(t *testing.T, idp *fakeIdP) *OIDCProvider {
+	t.Helper()
+
+	provider, err := NewOIDCProvider(OIDCConfig{
+		Name:         "university",
+		Issuer:       idp.issuer(),
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+		RedirectURL:  testRedirectURL,
+		Scopes:       []string{"email", "profile"},
+		HTTPClient:   idp.server.Client(),
+	})
+	if err != nil {
+		t.Fatalf("new provider: %v", err)
+	}
+	return provider
+}
+
+// newTestSSO creates a login flow with the fake provider registered
+func newTestSSO(t *testing.T, idp *fakeIdP) *SingleSignOn {
+	t.Helper()
+
+	sso := NewSingleSignOn()
+	sso.Register(newTestProvider(t, idp))
+	return sso
+}
+
+// signIn runs a whole login as person and returns the verified identity
+func signIn(t *testing.T, sso *SingleSignOn, idp *fakeIdP, person testPerson) (*ExternalIdentity, error) {
+	t.Helper()
+
+	ctx := context.Background()
+	authURL, state, err := sso.Begin(ctx, "university")
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	code, returnedState := idp.approve(t, authURL, person)
+	if returnedState != state {
+		t.Fatalf("provider returned state %q, want %q", returnedState, state)
+	}
+	return sso.Complete(ctx, state, code)
+}
+
+// ============================================================================
+// LOGIN FLOW TESTS
+// ============================================================================
+
+// TestSSOLogin signs in through the fake provider
+func TestSSOLogin(t *testing.T) {
+	idp := newFakeIdP(t)
+	sso := newTestSSO(t, idp)
+
+	identity, err := signIn(t, sso, idp, alice)
+	if err != nil {
+		t.Fatalf("sign in: %v", err)
+	}
+
+	want := ExternalIdentity{
+		Provider:          "university",
+		Issuer:            idp.issuer(),
+		Subject:           alice.Subject,
+		Email:             alice.Email,
+		EmailVerified:     true,
+		Name:              alice.Name,
+		PreferredUsername: alice.PreferredUsername,
+	}
+	if *identity != want {
+		t.Fatalf("identity = %+v, want %+v", *identity, want)
+	}
+}
+
+// TestSSOPKCEVerifier checks the PKCE verifier is sent and checked
+func TestSSOPKCEVerifier(t *testing.T) {
+	idp := newFakeIdP(t)
+	provider := newTestProvider(t, idp)
+	ctx := context.Background()
+
+	verifier, challenge, err := newPKCE()
+	if err != nil {
+		t.Fatalf("pkce: %v", err)
+	}
+	if challenge != pkceChallenge(verifier) {
+		t.Fatal("challenge is not the S256 of the verifier")
+	}
+
+	authURL, err := provider.AuthorizationURL(ctx, "state", "nonce", challenge)
+	if err != nil {
+		t.Fatalf("authorization URL: %v", err)
+	}
+
+	// A stolen code is useless without the verifier
+	code, _ := idp.approve(t, authURL, alice)
+	other, _, _ := newPKCE()
+	if _, err := provider.Exchange(ctx, code, other, "nonce"); err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Fatalf("exchange with the wrong verifier: %v, want invalid_grant", err)
+	}
+
+	code, _ = idp.approve(t, authURL, alice)
+	if _, err := provider.Exchange(ctx, code, verifier, "nonce"); err != nil {
+		t.Fatalf("exchange with the right verifier: %v", err)
+	}
+}
+
+// TestSSORejectsBadIDTokens checks every ID token check fails the login
+func TestSSORejectsBadIDTokens(t *testing.T) {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	claim := func(name string, value interface{}) func(*jwt.Token) {
+		return func(token *jwt.Token) {
+			token.Claims.(jwt.MapClaims)[name] = value
+		}
+	}
+
+	tests := []struct {
+		name   string
+		setup  func(idp *fakeIdP)
+		reason string
+	}{
+		{
+			name:   "nonce mismatch",
+			setup:  func(idp *fakeIdP) { idp.tamper = claim("nonce", "replayed-nonce") },
+			reason: "nonce mismatch",
+		},
+		{
+			name:   "signed by another key",
+			setup:  func(idp *fakeIdP) { idp.signer, idp.method = otherKey, jwt.SigningMethodRS256 },
+			reason: "verification error",
+		},
+		{
+			name: "unknown key ID",
+			setup: func(idp *fakeIdP) {
+				idp.tamper = func(token *jwt.Token) { token.Header["kid"] = "rotated-away" }
+			},
+			reason: "unknown ID token signing key",
+		},
+		{
+			name: "shared-secret algorithm",
+			setup: func(idp *fakeIdP) {
+				idp.signer, idp.method = []byte(testClientSecret), jwt.SigningMethodHS256
+			},
+			reason: "signing method HS256 is invalid",
+		},
+		{
+			name:   "wrong audience",
+			setup:  func(idp *fakeIdP) { idp.tamper = claim("aud", "another-client") },
+			reason: "token has invalid audience",
+		},
+		{
+			name: "several audiences without azp",
+			setup: func(idp *fakeIdP) {
+				idp.tamper = claim("aud", []string{testClientID, "another-client"})
+			},
+			reason: "issued for another client",
+		},
+		{
+			name:   "wrong issuer",
+			setup:  func(idp *fakeIdP) { idp.tamper = claim("iss", "https://evil.example.com") },
+			reason: "token has invalid issuer",
+		},
+		{
+			name: "expired",
+			setup: func(idp *fakeIdP) {
+				idp.tamper = func(token *jwt.Token) {
+					claims := token.Claims.(jwt.MapClaims)
+					claims["iat"] = time.Now().Add(-time.Hour).Unix()
+					claims["exp"] = time.Now().Add(-2 * idTokenLeeway).Unix()
+				}
+			},
+			reason: "token is expired",
+		},
+		{
+			name: "no expiry",
+			setup: func(idp *fakeIdP) {
+				idp.tamper = func(token *jwt.Token) { delete(token.Claims.(jwt.MapClaims), "exp") }
+			},
+			reason: "token is missing required claim",
+		},
+		{
+			name:   "no subject",
+			setup:  func(idp *fakeIdP) { idp.tamper = claim("sub", "") },
+			reason: "no subject",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newFakeIdP(t)
+			tt.setup(idp)
+			sso := newTestSSO(t, idp)
+
+			identity, err := signIn(t, sso, idp, alice)
+			if err == nil {
+				t.Fatalf("login accepted: %+v", identity)
+			}
+			if !strings.Contains(err.Error(), tt.reason) {
+				t.Fatalf("error %q, want it to mention %q", err, tt.reason)
+			}
+		})
+	}
+}
+
+// TestSSOStateIsSingleUse checks a state can't be replayed or used late
+func TestSSOStateIsSingleUse(t *testing.T) {
+	idp := newFakeIdP(t)
+	sso := newTestSSO(t, idp)
+	ctx := context.Background()
+
+	t.Run("reused", func(t *testing.T) {
+		authURL, state, err := sso.Begin(ctx, "university")
+		if err != nil {
+			t.Fatalf("begin: %v", err)
+		}
+		code, _ := idp.approve(t, authURL, alice)
+		if _, err := sso.Complete(ctx, state, code); err != nil {
+			t.Fatalf("first complete: %v", err)
+		}
+
+		code, _ = idp.approve(t, authURL, alice)
+		if _, err := sso.Complete(ctx, state, code); !errors.Is(err, ErrInvalidSSOState) {
+			t.Fatalf("second complete: %v, want ErrInvalidSSOState", err)
+		}
+	})
+
+	t.Run("failed attempt uses it up", func(t *testing.T) {
+		_, state, err := sso.Begin(ctx, "university")
+		if err != nil {
+			t.Fatalf("begin: %v", err)
+		}
+		if _, err := sso.Complete(ctx, state, "not-a-code"); err == nil || errors.Is(err, ErrInvalidSSOState) {
+			t.Fatalf("complete with a bad code: %v, want a provider error", err)
+		}
+		if _, err := sso.Complete(ctx, state, "not-a-code"); !errors.Is(err, ErrInvalidSSOState) {
+			t.Fatalf("retry: %v, want ErrInvalidSSOState", err)
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		if _, err := sso.Complete(ctx, "made-up-state", "code"); !errors.Is(err, ErrInvalidSSOState) {
+			t.Fatalf("complete: %v, want ErrInvalidSSOState", err)
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		authURL, state, err := sso.Begin(ctx, "university")
+		if err != nil {
+			t.Fatalf("begin: %v", err)
+		}
+		code, _ := idp.approve(t, authURL, alice)
+
+		sso.mu.Lock()
+		login := sso.pending[state]
+		login.expiresAt = time.Now().Add(-time.Second)
+		sso.pending[state] = login
+		sso.mu.Unlock()
+
+		before := idp.tokenRequests.Load()
+		if _, err := sso.Complete(ctx, state, code); !errors.Is(err, ErrInvalidSSOState) {
+			t.Fatalf("complete: %v, want ErrInvalidSSOState", err)
+		}
+		if idp.tokenRequests.Load() != before {
+			t.Fatal("an expired login still redeemed its code")
+		}
+	})
+
+	t.Run("unknown provider", func(t *testing.T) {
+		if _, _, err := sso.Begin(ctx, "nowhere"); !errors.Is(err, ErrUnknownProvider) {
+			t.Fatalf("begin: %v, want ErrUnknownProvider", err)
+		}
+	})
+}
+
+// ============================================================================
+// USER MAPPING TESTS
+// ============================================================================
+
+// TestResolveExternalUser maps identities from the fake provider to accounts
+func TestResolveExternalUser(t *testing.T) {
+	idp := newFakeIdP(t)
+	sso := newTestSSO(t, idp)
+
+	// existingUser adds a password account
+	existingUser := func(t *testing.T, users *storage.UserStore, email string) *models.User {
+		t.Helper()
+		user := &models.User{Email: email, Username: "existing", PasswordHash: "hash", Role: models.RoleUser}
+		if err := users.Create(user); err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+		return user
+	}
+	resolve := func(t *testing.T, users *storage.UserStore, person testPerson) (*models.User, bool, error) {
+		t.Helper()
+		identity, err := signIn(t, sso, idp, person)
+		if err != nil {
+			t.Fatalf("sign in: %v", err)
+		}
+		return ResolveExternalUser(users, identity)
+	}
+	linkedTo := func(t *testing.T, users *storage.UserStore, subject string) string {
+		t.Helper()
+		user, err := users.GetByIdentity(idp.issuer(), subject)
+		if err != nil {
+			return ""
+		}
+		return user.ID
+	}
+
+	t.Run("creates an account on first sign-in", func(t *testing.T) {
+		users := storage.NewUserStore()
+
+		user, created, err := resolve(t, users, alice)
+		if err != nil || !created {
+			t.Fatalf("resolve: created=%v err=%v", created, err)
+		}
+		if user.Email != alice.Email || user.Username != alice.PreferredUsername ||
+			user.Role != models.RoleUser || !user.EmailVerified || user.PasswordHash != "" {
+			t.Fatalf("created %+v", user)
+		}
+		if linkedTo(t, users, alice.Subject) != user.ID {
+			t.Fatal("identity not linked to the new account")
+		}
+	})
+
+	t.Run("finds the account linked to the subject", func(t *testing.T) {
+		users := storage.NewUserStore()
+		first, _, err := resolve(t, users, alice)
+		if err != nil {
+			t.Fatalf("first sign-in: %v", err)
+		}
+
+		// The subject identifies the person even after their email changes
+		renamed := alice
+		renamed.Email = "alice.example@university.edu"
+		user, created, err := resolve(t, users, renamed)
+		if err != nil || created || user.ID != first.ID {
+			t.Fatalf("resolve: user=%v created=%v err=%v, want account %s", user, created, err, first.ID)
+		}
+		if users.Count() != 1 {
+			t.Fatalf("%d accounts, want 1", users.Count())
+		}
+	})
+
+	t.Run("links an account by verified email", func(t *testing.T) {
+		users := storage.NewUserStore()
+		existing := existingUser(t, users, alice.Email)
+
+		user, created, err := resolve(t, users, alice)
+		if err != nil || created || user.ID != existing.ID {
+			t.Fatalf("resolve: user=%v created=%v err=%v, want account %s", user, created, err, existing.ID)
+		}
+		if linkedTo(t, users, alice.Subject) != existing.ID {
+			t.Fatal("identity not linked to the existing account")
+		}
+	})
+
+	t.Run("refuses an unverified email", func(t *testing.T) {
+		users := storage.NewUserStore()
+		existingUser(t, users, alice.Email)
+
+		unverified := alice
+		unverified.EmailVerified = false
+		if _, _, err := resolve(t, users, unverified); !errors.Is(err, ErrSSOEmailUnverified) {
+			t.Fatalf("resolve: %v, want ErrSSOEmailUnverified", err)
+		}
+		if linkedTo(t, users, alice.Subject) != "" {
+			t.Fatal("unverified identity was linked")
+		}
+	})
+
+	t.Run("refuses a second identity at the issuer", func(t *testing.T) {
+		users := storage.NewUserStore()
+		existing := existingUser(t, users, alice.Email)
+		if err := users.LinkIdentity(existing.ID, models.LinkedIdentity{Issuer: idp.issuer(), Subject: "old-subject"}); err != nil {
+			t.Fatalf("link: %v", err)
+		}
+
+		if _, _, err := resolve(t, users, alice); !errors.Is(err, ErrSSOIdentityConflicts) {
+			t.Fatalf("resolve: %v, want ErrSSOIdentityConflicts", err)
+		}
+		if linkedTo(t, users, alice.Subject) != "" {
+			t.Fatal("conflicting identity was linked")
+		}
+	})
+
+	t.Run("needs an email", func(t *testing.T) {
+		users := storage.NewUserStore()
+
+		anonymous := alice
+		anonymous.Email = ""
+		if _, _, err := resolve(t, users, anonymous); !errors.Is(err, ErrSSOEmailMissing) {
+			t.Fatalf("resolve: %v, want ErrSSOEmailMissing", err)
+		}
+	})
+}
@@ -0,0 +1,283 @@
+/*
+================================================================================
+SINGLE SIGN-ON - P2P Academic Library "The Knowledge Exchange"
+================================================================================
+This file implements the login flow through external identity providers
+and maps the people they vouch for to local accounts.
+
+A login starts by generating a state, a nonce and a PKCE verifier. They
+are kept here (never sent to the browser except the state) until the
+browser comes back with an authorization code, or until they expire.
+Each state can be completed once.
+
+Mapping an identity to an account, in order:
+1. An account already linked to the issuer and subject
+2. An account with the same email, if the provider verified the email;
+   the identity is linked to it
+3. A new account, created with the default role
+
+Go Concepts Used:
+- Interfaces: Pluggable providers and user storage
+- crypto/rand + SHA-256: State, nonce and PKCE challenge
+- Sync.Mutex: Pending logins
+================================================================================
+*/
+
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"knowledge-exchange/models"
+)
+
+// ============================================================================
+// CONSTANTS
+// ============================================================================
+
+const (
+	// SSOLoginExpiration is how long a user has to finish signing in at
+	// the identity provider
+	SSOLoginExpiration = 10 * time.Minute
+
+	// maxPendingSSOLogins caps unfinished logins held in memory
+	maxPendingSSOLogins = 10000
+)
+
+// Single sign-on errors
+var (
+	ErrUnknownProvider      = errors.New("unknown identity provider")
+	ErrInvalidSSOState      = errors.New("sign-in expired or was already used, please try again")
+	ErrTooManySSOLogins     = errors.New("too many sign-ins in progress, please try again shortly")
+	ErrSSOEmailMissing      = errors.New("the identity provider did not share an email address")
+	ErrSSOEmailUnverified   = errors.New("an account already uses this email, but the identity provider has not verified it")
+	ErrSSOIdentityConflicts = errors.New("this account is already linked to another identity at the provider")
+)
+
+// ============================================================================
+// LOGIN FLOW
+// ============================================================================
+
+// pendingSSOLogin is a login waiting for the browser to come back
+type pendingSSOLogin struct {
+	provider  string
+	verifier  string // PKCE code verifier
+	nonce     string
+	expiresAt time.Time
+}
+
+// SingleSignOn runs logins through registered identity providers
+type SingleSignOn struct {
+	providers map[string]IdentityProvider
+	pending   map[string]pendingSSOLogin // state -> login
+	mu        sync.Mutex
+}
+
+// NewSingleSignOn creates a flow with no providers
+func NewSingleSignOn() *SingleSignOn {
+	return &SingleSignOn{
+		providers: make(map[string]IdentityProvider),
+		pending:   make(map[string]pendingSSOLogin),
+	}
+}
+
+// Register adds a provider, replacing one with the same name
+func (s *SingleSignOn) Register(provider IdentityProvider) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.providers[provider.Name()] = provider
+}
+
+// Providers returns the registered providers by name
+func (s *SingleSignOn) Providers() []IdentityProvider {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make([]IdentityProvider, 0, len(s.providers))
+	for _, provider := range s.providers {
+		list = append(list, provider)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name() < list[j].Name()
+	})
+	return list
+}
+
+// Begin starts a login at a provider
+// Parameters:
+//   - providerName: IdentityProvider.Name() of a registered provider
+//
+// Returns:
+//   - string: The provider URL to send the browser to
+//   - string: The state, which the frontend checks comes back unchanged
+//   - error: If the provider is unknown or unreachable
+func (s *SingleSignOn) Begin(ctx context.Context, providerName string) (string, string, error) {
+	s.mu.Lock()
+	provider, ok := s.providers[providerName]
+	s.mu.Unlock()
+	if !ok {
+		return "", "", ErrUnknownProvider
+	}
+
+	state, err := randomToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, challenge, err := newPKCE()
+	if err != nil {
+		return "", "", err
+	}
+
+	// Discovery may be slow, so build the URL before taking the lock
+	authURL, err := provider.AuthorizationURL(ctx, state, nonce, challenge)
+	if err != nil {
+		return "", "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pruneLocked(time.Now())
+	if len(s.pending) >= maxPendingSSOLogins {
+		return "", "", ErrTooManySSOLogins
+	}
+	s.pending[state] = pendingSSOLogin{
+		provider:  providerName,
+		verifier:  verifier,
+		nonce:     nonce,
+		expiresAt: time.Now().Add(SSOLoginExpiration),
+	}
+	return authURL, state, nil
+}
+
+// Complete finishes a login with the code the provider redirected with
+// The state is used up whether or not the exchange succeeds
+// Returns the verified person
+func (s *SingleSignOn) Complete(ctx context.Context, state, code string) (*ExternalIdentity, error) {
+	s.mu.Lock()
+	login, ok := s.pending[state]
+	delete(s.pending, state)
+	provider := s.providers[login.provider]
+	s.mu.Unlock()
+
+	if !ok || time.Now().After(login.expiresAt) {
+		return nil, ErrInvalidSSOState
+	}
+	if provider == nil {
+		return nil, ErrUnknownProvider
+	}
+
+	return provider.Exchange(ctx, code, login.verifier, login.nonce)
+}
+
+// pruneLocked drops logins that were never completed
+// Caller must hold s.mu
+func (s *SingleSignOn) pruneLocked(now time.Time) {
+	for state, login := range s.pending {
+		if now.After(login.expiresAt) {
+			delete(s.pending, state)
+		}
+	}
+}
+
+// newPKCE returns a code verifier and its S256 challenge (RFC 7636)
+func newPKCE() (string, string, error) {
+	verifier, err := randomToken()
+	if err != nil {
+		return "", "", err
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// ============================================================================
+// USER MAPPING
+// ============================================================================
+
+// UserDirectory finds, creates and links accounts for single sign-on
+// Implemented by storage.UserStore
+type UserDirectory interface {
+	GetByIdentity(issuer, subject string) (*models.User, error)
+	GetByEmail(email string) (*models.User, error)
+	Create(user *models.User) error
+	LinkIdentity(userID string, identity models.LinkedIdentity) error
+}
+
+// ResolveExternalUser maps a person from an identity provider to an account,
+// linking or creating one on their first sign-in
+// The caller still checks that the account is active.
+// Returns:
+//   - *models.User: The account
+//   - bool: Whether the account was created
+//   - error: If the person can't be mapped safely
+func ResolveExternalUser(users UserDirectory, identity *ExternalIdentity) (*models.User, bool, error) {
+	if user, err := users.GetByIdentity(identity.Issuer, identity.Subject); err == nil {
+		return user, false, nil
+	}
+
+	if identity.Email == "" {
+		return nil, false, ErrSSOEmailMissing
+	}
+	link := models.LinkedIdentity{
+		Issuer:   identity.Issuer,
+		Subject:  identity.Subject,
+		LinkedAt: time.Now(),
+	}
+
+	// An existing account is only taken over on the provider's word that
+	// this person owns its email
+	if user, err := users.GetByEmail(identity.Email); err == nil {
+		if !identity.EmailVerified {
+			return nil, false, ErrSSOEmailUnverified
+		}
+		for _, linked := range user.Identities {
+			if linked.Issuer == identity.Issuer {
+				return nil, false, ErrSSOIdentityConflicts
+			}
+		}
+		if err := users.LinkIdentity(user.ID, link); err != nil {
+			return nil, false, err
+		}
+		return user, false, nil
+	}
+
+	// First sign-in: the account has no password until the user sets one
+	// through a password reset
+	user := &models.User{
+		Email:         identity.Email,
+		Username:      externalUsername(identity),
+		Role:          models.RoleUser,
+		EmailVerified: identity.EmailVerified,
+	}
+	if err := users.Create(user); err != nil {
+		return nil, false, err
+	}
+	if err := users.LinkIdentity(user.ID, link); err != nil {
+		return nil, false, err
+	}
+	return user, true, nil
+}
+
+// externalUsername picks a username for a new account from the identity
+func externalUsername(identity *ExternalIdentity) string {
+	localPart, _, _ := strings.Cut(identity.Email, "@")
+	for _, candidate := range []string{identity.PreferredUsername, identity.Name, localPart} {
+		candidate = strings.TrimSpace(candidate)
+		if len(candidate) >= 3 {
+			return candidate
+		}
+	}
+	return identity.Email
+}
@@ -0,0 +1,287 @@
+/*
+================================================================================
+TWO-FACTOR AUTHENTICATION - P2P Academic Library "The Knowledge Exchange"
+================================================================================
+This file implements time-based one-time passwords (TOTP, RFC 6238) and
+recovery codes as a second login factor.
+
+Enrollment is two steps: setup hands out a secret (and an otpauth:// URI for
+authenticator apps) that stays pending until the user proves they saved it
+by entering its first code. Only then does login start asking for a code,
+and a set of single-use recovery codes is issued for a lost device.
+
+Codes are 6 digits over 30-second steps (HMAC-SHA1, the authenticator app
+default). One step of clock drift is allowed either way, and a step that
+was already accepted can't be used again.
+
+Go Concepts Used:
+- crypto/hmac: HOTP code generation (RFC 4226)
+- encoding/base32: Secrets in the format authenticator apps expect
+- crypto/subtle: Constant-time code comparison
+================================================================================
+*/
+
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"knowledge-exchange/models"
+)
+
+// ============================================================================
+// CONSTANTS
+// ============================================================================
+
+const (
+	totpPeriod      = 30 // Seconds per time step
+	totpDigits      = 6
+	totpSkew        = 1  // Steps of clock drift accepted either way
+	totpSecretBytes = 20 // 160 bits, as RFC 4226 recommends
+
+	// RecoveryCodeCount is how many recovery codes are issued at a time
+	RecoveryCodeCount = 10
+
+	// LoginChallengeExpiration is how long a user has to enter their code
+	// after their password was accepted
+	LoginChallengeExpiration = 5 * time.Minute
+)
+
+// Two-factor errors
+var (
+	ErrTwoFactorEnabled    = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorNotPending = errors.New("start two-factor setup first")
+	ErrInvalidTwoFactor    = errors.New("invalid two-factor code")
+)
+
+// totpEncoding is unpadded base32, as used in otpauth:// URIs
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// ============================================================================
+// ENROLLMENT
+// ============================================================================
+
+// StartTOTPEnrollment gives the user a new pending secret
+// Calling it again replaces a pending secret that was never confirmed.
+// The caller saves the user.
+// Parameters:
+//   - user: The user enrolling
+//   - issuer: Name shown in the authenticator app
+//
+// Returns:
+//   - string: The base32 secret, for manual entry
+//   - string: The otpauth:// provisioning URI, for a QR code
+//   - error: If 2FA is already enabled
+func StartTOTPEnrollment(user *models.User, issuer string) (string, string, error) {
+	if user.TwoFactorEnabled() {
+		return "", "", ErrTwoFactorEnabled
+	}
+
+	raw := make([]byte, totpSecretBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	secret := totpEncoding.EncodeToString(raw)
+
+	user.TwoFactor = &models.TwoFactor{PendingSecret: secret}
+	return secret, ProvisioningURI(issuer, user.Email, secret), nil
+}
+
+// ConfirmTOTPEnrollment enables 2FA once the user enters a code from the
+// pending secret, and issues their recovery codes
+// The caller saves the user.
+// Returns:
+//   - []string: The recovery codes in plain text; only their hashes are kept
+//   - error: If there is no pending secret or the code is wrong
+func ConfirmTOTPEnrollment(user *models.User, code string, now time.Time) ([]string, error) {
+	if user.TwoFactorEnabled() {
+		return nil, ErrTwoFactorEnabled
+	}
+	if user.TwoFactor == nil || user.TwoFactor.PendingSecret == "" {
+		return nil, ErrTwoFactorNotPending
+	}
+
+	step, ok := validateTOTP(user.TwoFactor.PendingSecret, code, 0, now)
+	if !ok {
+		return nil, ErrInvalidTwoFactor
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	user.TwoFactor = &models.TwoFactor{
+		Secret:        user.TwoFactor.PendingSecret,
+		RecoveryCodes: hashes,
+		LastStep:      step,
+		EnabledAt:     now,
+	}
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes
+// The caller saves the user.
+func RegenerateRecoveryCodes(user *models.User) ([]string, error) {
+	if !user.TwoFactorEnabled() {
+		return nil, ErrTwoFactorNotEnabled
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	user.TwoFactor.RecoveryCodes = hashes
+	return codes, nil
+}
+
+// ============================================================================
+// VERIFICATION
+// ============================================================================
+
+// VerifySecondFactor checks a TOTP code or, failing that, a recovery code
+// An accepted TOTP step or recovery code is used up, so the caller must
+// save the user on success.
+// Returns:
+//   - bool: Whether a recovery code was used
+//   - error: ErrInvalidTwoFactor if neither matched
+func VerifySecondFactor(user *models.User, code string, now time.Time) (bool, error) {
+	if !user.TwoFactorEnabled() {
+		return false, ErrTwoFactorNotEnabled
+	}
+	if err := VerifyTOTP(user, code, now); err == nil {
+		return false, nil
+	}
+
+	tf := user.TwoFactor
+	hash := hashToken(normalizeRecoveryCode(code))
+	for i, stored := range tf.RecoveryCodes {
+		if subtle.ConstantTimeCompare([]byte(stored), []byte(hash)) == 1 {
+			tf.RecoveryCodes = append(tf.RecoveryCodes[:i:i], tf.RecoveryCodes[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, ErrInvalidTwoFactor
+}
+
+// VerifyTOTP checks a code from the authenticator app only
+// The accepted step is used up, so the caller must save the user on success.
+func VerifyTOTP(user *models.User, code string, now time.Time) error {
+	if !user.TwoFactorEnabled() {
+		return ErrTwoFactorNotEnabled
+	}
+	tf := user.TwoFactor
+
+	step, ok := validateTOTP(tf.Secret, code, tf.LastStep, now)
+	if !ok {
+		return ErrInvalidTwoFactor
+	}
+	tf.LastStep = step
+	return nil
+}
+
+// ProvisioningURI builds the otpauth:// URI authenticator apps scan
+// Format: otpauth://totp/Issuer:account?secret=...&issuer=Issuer
+func ProvisioningURI(issuer, account, secret string) string {
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(totpDigits))
+	params.Set("period", fmt.Sprint(totpPeriod))
+
+	label := url.PathEscape(issuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// validateTOTP checks a code against the steps around now
+// Parameters:
+//   - secret: Base32 TOTP secret
+//   - code: The code the user entered
+//   - lastStep: Steps up to and including this one are refused (replay)
+//   - now: Current time
+//
+// Returns:
+//   - int64: The step the code matched
+//   - bool: Whether it matched
+func validateTOTP(secret, code string, lastStep int64, now time.Time) (int64, bool) {
+	code = strings.ReplaceAll(code, " ", "")
+	if len(code) != totpDigits {
+		return 0, false
+	}
+
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return 0, false
+	}
+
+	current := now.Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= lastStep {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(hotp(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// hotp computes an RFC 4226 code for a counter
+func hotp(key []byte, counter int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, value%mod)
+}
+
+// ============================================================================
+// RECOVERY CODES
+// ============================================================================
+
+// newRecoveryCodes generates recovery codes like "3f9a1-07bc2"
+// Returns the codes and their hashes
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, RecoveryCodeCount)
+	hashes := make([]string, RecoveryCodeCount)
+	for i := range codes {
+		raw := make([]byte, 5)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, err
+		}
+		hexed := hex.EncodeToString(raw)
+		codes[i] = hexed[:5] + "-" + hexed[5:]
+		hashes[i] = hashToken(hexed)
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode drops separators and case so "3F9A1 07BC2" matches
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -18,8 +18,10 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"net"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 
@@ -50,14 +52,27 @@ const (
 
 func main() {
 	// Print banner
-	fmt.Println(banner)
+	fmt.Print(banner)
 	fmt.Printf("Version: %s\n\n", utils.AppVersion)
 
 	// Parse command line flags
 	var (
-		port    = flag.Int("port", utils.DefaultAPIPort, "API server port")
-		name    = flag.String("name", "Anonymous Peer", "Peer display name")
-		dataDir = flag.String("data", utils.DefaultDataDir, "Data storage directory")
+		port      = flag.Int("port", utils.DefaultAPIPort, "API server port")
+		name      = flag.String("name", "Anonymous Peer", "Peer display name")
+		dataDir   = flag.String("data", utils.DefaultDataDir, "Data storage directory")
+		trust     = flag.Bool("trust", false, "Enable EigenTrust reputation gossip")
+		seeds     = flag.String("pretrusted", "", "Comma-separated pre-trusted peer IDs")
+		policy    = flag.String("policy", "", "Reputation policy JSON file")
+		jwtAlg    = flag.String("jwt-alg", "HS256", "JWT signing algorithm for generated keys (HS256, EdDSA, RS256)")
+		devMode   = flag.Bool("dev", false, "Development mode (creates admin@knowledge-exchange.com / admin123)")
+		admin2FA  = flag.Bool("admin-2fa", true, "Require two-factor authentication for admin endpoints")
+		smtp      = flag.String("smtp", "", "SMTP server host:port for outgoing mail (empty writes mail to the data directory)")
+		smtpUser  = flag.String("smtp-user", "", "SMTP username (password from KX_SMTP_PASSWORD)")
+		mailFrom  = flag.String("mail-from", "no-reply@knowledge-exchange.local", "Sender address for outgoing mail")
+		publicURL = flag.String("public-url", "http://localhost:3000", "Frontend URL used in emailed links")
+		oidcURL   = flag.String("oidc-issuer", "", "OpenID Connect issuer URL for single sign-on (empty disables it)")
+		oidcID    = flag.String("oidc-client-id", "", "OpenID Connect client ID (secret from KX_OIDC_CLIENT_SECRET)")
+		oidcName  = flag.String("oidc-name", "University SSO", "Label for the single sign-on button")
 	)
 	flag.Parse()
 
@@ -74,6 +89,35 @@ func main() {
 	config.DataDir = *dataDir
 	config.SharedFilesDir = *dataDir + "/sharedFiles"
 	config.TempDir = *dataDir + "/temp"
+	config.EnableTrustGossip = *trust
+	config.ReputationPolicyFile = *policy
+	config.JWTAlgorithm = *jwtAlg
+	config.DevMode = *devMode
+	config.RequireAdminTwoFactor = *admin2FA
+	config.JWTSecret = os.Getenv("KX_JWT_SECRET")
+	if *smtp != "" {
+		host, portStr, err := net.SplitHostPort(*smtp)
+		if err != nil {
+			log.Fatalf("Invalid -smtp address %q: %v", *smtp, err)
+		}
+		smtpPort, err := strconv.Atoi(portStr)
+		if err != nil {
+			log.Fatalf("Invalid -smtp port %q", portStr)
+		}
+		config.SMTPHost = host
+		config.SMTPPort = smtpPort
+	}
+	config.SMTPUsername = *smtpUser
+	config.SMTPPassword = os.Getenv("KX_SMTP_PASSWORD")
+	config.MailFrom = *mailFrom
+	config.PublicURL = strings.TrimRight(*publicURL, "/")
+	config.OIDCIssuer = *oidcURL
+	config.OIDCClientID = *oidcID
+	config.OIDCClientSecret = os.Getenv("KX_OIDC_CLIENT_SECRET")
+	config.OIDCDisplayName = *oidcName
+	if *seeds != "" {
+		config.PreTrustedPeers = strings.Split(*seeds, ",")
+	}
 
 	// Ensure directories exist
 	if err := utils.EnsureDirectories(); err != nil {
@@ -109,11 +153,13 @@ func main() {
 	fmt.Println("  GET  /api/files/search   - Search files (?q=query)")
 	fmt.Println("  POST /api/files/upload   - Upload file")
 	fmt.Println("  GET  /api/files/download - Download file")
+	fmt.Println("  GET  /api/files/top      - Top rated files (paginated)")
+	fmt.Println("  GET  /api/transfers      - Active transfers and throughput")
 	fmt.Println("  GET  /api/reputation     - Get reputation")
 	fmt.Println("  POST /api/ratings/file   - Rate a file")
 	fmt.Println("  GET  /api/stats          - System statistics")
 	fmt.Println(strings.Repeat("─", 60))
-	fmt.Println("\nPress Ctrl+C to stop the server\n")
+	fmt.Print("\nPress Ctrl+C to stop the server\n\n")
 
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
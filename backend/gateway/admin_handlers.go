@@ -0,0 +1,651 @@
+/*
+================================================================================
+ADMIN HANDLERS - P2P Academic Library "The Knowledge Exchange"
+================================================================================
+This file implements HTTP handlers for the admin API: managing user
+accounts, roles and permissions, unlocking logins, adjusting reputation,
+removing files from the index and kicking or banning peers.
+
+Every route here is registered behind a permission check (see setupRoutes),
+so handlers can assume an authenticated staff member in the request
+context. Bans and file removals are persisted in the moderation store.
+
+Go Concepts Used:
+- HTTP handlers: Request/response handling
+- JSON encoding/decoding
+- Closures: Handlers capturing the router
+- Embedding: Extending the public user view
+================================================================================
+*/
+
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"knowledge-exchange/analytics"
+	"knowledge-exchange/auth"
+	"knowledge-exchange/models"
+)
+
+// ============================================================================
+// REQUEST/RESPONSE TYPES
+// ============================================================================
+
+// AdminUserInfo is the admin view of an account
+type AdminUserInfo struct {
+	models.PublicUser
+	IsActive  bool      `json:"is_active"`
+	LastLogin time.Time `json:"last_login,omitempty"`
+}
+
+// UserActionRequest targets one user account
+type UserActionRequest struct {
+	UserID string `json:"user_id"`
+	Role   string `json:"role,omitempty"` // Only for role changes
+}
+
+// ReputationAdjustRequest changes a peer's reputation by hand
+type ReputationAdjustRequest struct {
+	PeerID string  `json:"peer_id"`
+	Delta  float64 `json:"delta"`
+	Reason string  `json:"reason"`
+}
+
+// UnlockRequest names the account or address to unlock
+type UnlockRequest struct {
+	UserID  string `json:"user_id,omitempty"`
+	Email   string `json:"email,omitempty"`
+	Address string `json:"ip,omitempty"`
+}
+
+// ModerationRequest targets a peer or a file, with an optional reason
+type ModerationRequest struct {
+	PeerID string `json:"peer_id,omitempty"`
+	CID    string `json:"cid,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// ============================================================================
+// USER MANAGEMENT
+// ============================================================================
+
+// listUsersHandler returns a page of user accounts
+// Query: q (matches email or username), include_inactive, page, page_size
+func (r *Router) listUsersHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		query := req.URL.Query()
+
+		page, pageSize := 1, analytics.DefaultPageSize
+		if raw := query.Get("page"); raw != "" {
+			value, err := strconv.Atoi(raw)
+			if err != nil || value < 1 {
+				r.server.sendError(w, http.StatusBadRequest, "invalid page")
+				return
+			}
+			page = value
+		}
+		if raw := query.Get("page_size"); raw != "" {
+			value, err := strconv.Atoi(raw)
+			if err != nil || value < 1 {
+				r.server.sendError(w, http.StatusBadRequest, "invalid page_size")
+				return
+			}
+			pageSize = value
+		}
+
+		includeInactive := query.Get("include_inactive") == "true"
+		users := r.server.userStore.Search(strings.TrimSpace(query.Get("q")), includeInactive)
+
+		start, end, pageSize := analytics.PageBounds(len(users), page, pageSize)
+		items := make([]AdminUserInfo, 0, end-start)
+		for _, user := range users[start:end] {
+			items = append(items, r.server.adminUser(user))
+		}
+
+		r.server.sendJSON(w, http.StatusOK, APIResponse{
+			Success: true,
+			Data: PageInfo{
+				Items:    items,
+				Page:     page,
+				PageSize: pageSize,
+				Total:    len(users),
+			},
+		})
+	}
+}
+
+// setRoleHandler changes a user's role
+// The role is read from the store on every request, so the change applies
+// to the user's existing tokens immediately
+func (r *Router) setRoleHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var body UserActionRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.UserID == "" {
+			r.server.sendError(w, http.StatusBadRequest, "User ID required")
+			return
+		}
+
+		target, err := r.server.userStore.GetByID(body.UserID)
+		if err != nil {
+			r.server.sendError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		if _, exists := r.server.roles.Get(body.Role); !exists {
+			r.server.sendError(w, http.StatusBadRequest, "Unknown role")
+			return
+		}
+		if target.IsAdmin() && body.Role != models.RoleAdmin && r.server.isLastAdmin(target) {
+			r.server.sendError(w, http.StatusConflict, "Cannot demote the last active admin")
+			return
+		}
+
+		if err := r.server.userStore.UpdateRole(body.UserID, body.Role); err != nil {
+			r.server.sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		admin, _ := auth.UserFromContext(req.Context())
+		log.Printf("Admin %s changed role of %s to %s", admin.Username, target.Username, body.Role)
+
+		r.server.sendJSON(w, http.StatusOK, APIResponse{
+			Success: true,
+			Message: "Role updated",
+			Data:    r.server.adminUser(target),
+		})
+	}
+}
+
+// setActiveHandler deactivates or reactivates an account
+// Deactivation also revokes the user's sessions
+func (r *Router) setActiveHandler(active bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var body UserActionRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.UserID == "" {
+			r.server.sendError(w, http.StatusBadRequest, "User ID required")
+			return
+		}
+
+		target, err := r.server.userStore.GetByID(body.UserID)
+		if err != nil {
+			r.server.sendError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		admin, _ := auth.UserFromContext(req.Context())
+		if !active {
+			if target.ID == admin.ID {
+				r.server.sendError(w, http.StatusConflict, "Cannot deactivate your own account")
+				return
+			}
+			if r.server.isLastAdmin(target) {
+				r.server.sendError(w, http.StatusConflict, "Cannot deactivate the last active admin")
+				return
+			}
+		}
+
+		if err := r.server.userStore.SetActive(target.ID, active); err != nil {
+			r.server.sendError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		message := "Account reactivated"
+		if !active {
+			message = "Account deactivated"
+			if err := r.server.authService.RevokeUser(target.ID); err != nil {
+				log.Printf("Warning: failed to revoke sessions for %s: %v", target.ID, err)
+			}
+		}
+		log.Printf("Admin %s: %s (%s)", admin.Username, message, target.Username)
+
+		r.server.sendJSON(w, http.StatusOK, APIResponse{
+			Success: true,
+			Message: message,
+			Data:    r.server.adminUser(target),
+		})
+	}
+}
+
+// resetTwoFactorHandler turns off a user's 2FA when they lost their device
+// and every recovery code; their sessions are revoked
+func (r *Router) resetTwoFactorHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var body UserActionRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.UserID == "" {
+			r.server.sendError(w, http.StatusBadRequest, "User ID required")
+			return
+		}
+
+		target, err := r.server.userStore.GetByID(body.UserID)
+		if err != nil {
+			r.server.sendError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		admin, _ := auth.UserFromContext(req.Context())
+		if target.ID == admin.ID {
+			r.server.sendError(w, http.StatusConflict, "Cannot reset your own two-factor authentication")
+			return
+		}
+		if target.TwoFactor == nil {
+			r.server.sendError(w, http.StatusConflict, auth.ErrTwoFactorNotEnabled.Error())
+			return
+		}
+
+		target, err = r.server.userStore.Modify(target.ID, func(u *models.User) error {
+			u.TwoFactor = nil
+			return nil
+		})
+		if err != nil {
+			r.server.sendError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if err := r.server.authService.RevokeUser(target.ID); err != nil {
+			log.Printf("Warning: failed to revoke sessions for %s: %v", target.ID, err)
+		}
+		log.Printf("Admin %s: two-factor authentication reset (%s)", admin.Username, target.Username)
+
+		r.server.sendJSON(w, http.StatusOK, APIResponse{
+			Success: true,
+			Message: "Two-factor authentication reset",
+			Data:    r.server.adminUser(target),
+		})
+	}
+}
+
+// ============================================================================
+// ROLES AND PERMISSIONS
+// ============================================================================
+
+// RoleInfo is a role with the number of accounts that have it
+type RoleInfo struct {
+	models.Role
+	Users int `json:"users"`
+}
+
+// DeleteRoleRequest names the role to delete
+type DeleteRoleRequest struct {
+	Name string `json:"name"`
+}
+
+// listRolesHandler returns every role and every known permission
+func (r *Router) listRolesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		roles := r.server.roles.List()
+		infos := make([]RoleInfo, len(roles))
+		for i, role := range roles {
+			infos[i] = RoleInfo{
+				Role:  role,
+				Users: r.server.userStore.CountWithRole(role.Name),
+			}
+		}
+
+		r.server.sendJSON(w, http.StatusOK, APIResponse{
+			Success: true,
+			Data: map[string]interface{}{
+				"roles":       infos,
+				"permissions": models.AllPermissions(),
+			},
+		})
+	}
+}
+
+// saveRoleHandler creates a role or replaces its description and
+// permissions; users with the role are affected immediately
+func (r *Router) saveRoleHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var body models.Role
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			r.server.sendError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		if err := r.server.roles.Save(body); err != nil {
+			r.server.sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		role, _ := r.server.roles.Get(body.Name)
+		admin, _ := auth.UserFromContext(req.Context())
+		log.Printf("Admin %s saved role %s: %v", admin.Username, role.Name, role.Permissions)
+
+		r.server.sendJSON(w, http.StatusOK, APIResponse{
+			Success: true,
+			Message: "Role saved",
+			Data: RoleInfo{
+				Role:  role,
+				Users: r.server.userStore.CountWithRole(role.Name),
+			},
+		})
+	}
+}
+
+// deleteRoleHandler deletes a role no account has
+func (r *Router) deleteRoleHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var body DeleteRoleRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.Name == "" {
+			r.server.sendError(w, http.StatusBadRequest, "Role name required")
+			return
+		}
+
+		if count := r.server.userStore.CountWithRole(body.Name); count > 0 {
+			r.server.sendError(w, http.StatusConflict,
+				fmt.Sprintf("%d account(s) still have this role; assign them another first", count))
+			return
+		}
+
+		if err := r.server.roles.Delete(body.Name); err != nil {
+			r.server.sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		admin, _ := auth.UserFromContext(req.Context())
+		log.Printf("Admin %s deleted role %s", admin.Username, body.Name)
+
+		r.server.sendJSON(w, http.StatusOK, APIResponse{
+			Success: true,
+			Message: "Role deleted",
+		})
+	}
+}
+
+// ============================================================================
+// LOGIN LOCKOUTS
+// ============================================================================
+
+// lockoutsHandler lists locked accounts and addresses with recent
+// security events
+// Query: limit (number of events, default 100)
+func (r *Router) lockoutsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		limit := 100
+		if raw := req.URL.Query().Get("limit"); raw != "" {
+			value, err := strconv.Atoi(raw)
+			if err != nil || value < 1 {
+				r.server.sendError(w, http.StatusBadRequest, "invalid limit")
+				return
+			}
+			limit = value
+		}
+
+		guard := r.server.loginGuard
+		r.server.sendJSON(w, http.StatusOK, APIResponse{
+			Success: true,
+			Data: map[string]interface{}{
+				"locked": guard.Locked(),
+				"events": guard.Audit(limit),
+			},
+		})
+	}
+}
+
+// unlockHandler clears the failed logins of an account or an address
+func (r *Router) unlockHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var body UnlockRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			r.server.sendError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		var key string
+		switch {
+		case body.UserID != "":
+			user, err := r.server.userStore.GetByID(body.UserID)
+			if err != nil {
+				r.server.sendError(w, http.StatusNotFound, err.Error())
+				return
+			}
+			key = auth.AccountKey(user.Email)
+		case body.Email != "":
+			key = auth.AccountKey(body.Email)
+		case body.Address != "":
+			key = auth.AddressKey(body.Address)
+		default:
+			r.server.sendError(w, http.StatusBadRequest, "user_id, email or ip required")
+			return
+		}
+
+		admin, _ := auth.UserFromContext(req.Context())
+		if err := r.server.loginGuard.Unlock(key, admin.Username); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, auth.ErrUnknownLockKey) {
+				status = http.StatusNotFound
+			}
+			r.server.sendError(w, status, err.Error())
+			return
+		}
+
+		r.server.sendJSON(w, http.StatusOK, APIResponse{
+			Success: true,
+			Message: "Unlocked " + key,
+		})
+	}
+}
+
+// ============================================================================
+// REPUTATION
+// ============================================================================
+
+// adjustReputationHandler changes a peer's reputation by a fixed amount
+// The change is an ADJUSTMENT event in the reputation log, so it shows up
+// in the peer's audit trail and survives replay
+func (r *Router) adjustReputationHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var body ReputationAdjustRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			r.server.sendError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		body.Reason = strings.TrimSpace(body.Reason)
+		if body.PeerID == "" || body.Reason == "" {
+			r.server.sendError(w, http.StatusBadRequest, "peer_id and reason are required")
+			return
+		}
+		if body.Delta == 0 {
+			r.server.sendError(w, http.StatusBadRequest, "delta must be non-zero")
+			return
+		}
+
+		admin, _ := auth.UserFromContext(req.Context())
+		reason := fmt.Sprintf("Admin adjustment by %s: %s", admin.Username, body.Reason)
+		score := r.server.reputationService.AdjustReputation(body.PeerID, body.Delta, reason)
+
+		log.Printf("Admin %s adjusted reputation of %s by %+.2f: %s", admin.Username, body.PeerID, body.Delta, body.Reason)
+
+		r.server.sendJSON(w, http.StatusOK, APIResponse{
+			Success: true,
+			Message: "Reputation adjusted",
+			Data: map[string]interface{}{
+				"peer_id":    body.PeerID,
+				"delta":      body.Delta,
+				"reputation": score,
+			},
+		})
+	}
+}
+
+// ============================================================================
+// FILE MODERATION
+// ============================================================================
+
+// removeFileHandler removes a file from the index
+// The CID is remembered so the file isn't indexed or uploaded again
+func (r *Router) removeFileHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var body ModerationRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.CID == "" {
+			r.server.sendError(w, http.StatusBadRequest, "File CID required")
+			return
+		}
+
+		if !r.server.fileExists(body.CID) {
+			r.server.sendError(w, http.StatusNotFound, "File not found")
+			return
+		}
+
+		staff, _ := auth.UserFromContext(req.Context())
+		if err := r.server.removeFile(body.CID, body.Reason, staff); err != nil {
+			r.server.sendError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		r.server.sendJSON(w, http.StatusOK, APIResponse{
+			Success: true,
+			Message: "File removed from the index",
+		})
+	}
+}
+
+// removeFile removes a file from the index and remembers its CID
+func (s *Server) removeFile(cid, reason string, by *models.User) error {
+	if err := s.moderation.RemoveFile(cid, reason, by.ID); err != nil {
+		return err
+	}
+	s.fileIndex.Remove(cid)
+	s.indexer.BlockFile(cid)
+
+	log.Printf("%s %s removed file %s: %s", by.Role, by.Username, cid, reason)
+	return nil
+}
+
+// fileExists checks if a file is in the network index or shared locally
+func (s *Server) fileExists(cid string) bool {
+	_, inIndex := s.fileIndex.Get(cid)
+	_, inIndexer := s.indexer.GetFile(cid)
+	return inIndex || inIndexer
+}
+
+// removedFilesHandler lists removed files
+func (r *Router) removedFilesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		r.server.sendJSON(w, http.StatusOK, APIResponse{
+			Success: true,
+			Data:    r.server.moderation.ListRemovedFiles(),
+		})
+	}
+}
+
+// ============================================================================
+// PEER MODERATION
+// ============================================================================
+
+// kickPeerHandler disconnects a peer; it may register again
+func (r *Router) kickPeerHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var body ModerationRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.PeerID == "" {
+			r.server.sendError(w, http.StatusBadRequest, "Peer ID required")
+			return
+		}
+
+		if !r.server.discovery.RemovePeer(body.PeerID) {
+			r.server.sendError(w, http.StatusNotFound, "Peer not found")
+			return
+		}
+
+		staff, _ := auth.UserFromContext(req.Context())
+		log.Printf("%s %s kicked peer %s: %s", staff.Role, staff.Username, body.PeerID, body.Reason)
+
+		r.server.sendJSON(w, http.StatusOK, APIResponse{
+			Success: true,
+			Message: "Peer kicked",
+		})
+	}
+}
+
+// banPeerHandler disconnects a peer and keeps it from registering, being
+// discovered or acting on anyone's behalf
+func (r *Router) banPeerHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var body ModerationRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.PeerID == "" {
+			r.server.sendError(w, http.StatusBadRequest, "Peer ID required")
+			return
+		}
+
+		staff, _ := auth.UserFromContext(req.Context())
+		if err := r.server.banPeer(body.PeerID, body.Reason, staff); err != nil {
+			r.server.sendError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		r.server.sendJSON(w, http.StatusOK, APIResponse{
+			Success: true,
+			Message: "Peer banned",
+		})
+	}
+}
+
+// banPeer bans a peer and disconnects it
+func (s *Server) banPeer(peerID, reason string, by *models.User) error {
+	if err := s.moderation.BanPeer(peerID, reason, by.ID); err != nil {
+		return err
+	}
+	s.discovery.RemovePeer(peerID)
+
+	log.Printf("%s %s banned peer %s: %s", by.Role, by.Username, peerID, reason)
+	return nil
+}
+
+// unbanPeerHandler lifts a peer's ban
+func (r *Router) unbanPeerHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var body ModerationRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.PeerID == "" {
+			r.server.sendError(w, http.StatusBadRequest, "Peer ID required")
+			return
+		}
+
+		if err := r.server.moderation.UnbanPeer(body.PeerID); err != nil {
+			r.server.sendError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		staff, _ := auth.UserFromContext(req.Context())
+		log.Printf("%s %s unbanned peer %s", staff.Role, staff.Username, body.PeerID)
+
+		r.server.sendJSON(w, http.StatusOK, APIResponse{
+			Success: true,
+			Message: "Peer unbanned",
+		})
+	}
+}
+
+// bansHandler lists banned peers
+func (r *Router) bansHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		r.server.sendJSON(w, http.StatusOK, APIResponse{
+			Success: true,
+			Data:    r.server.moderation.ListBans(),
+		})
+	}
+}
+
+// ============================================================================
+// HELPERS
+// ============================================================================
+
+// adminUser converts a user for the admin view
+func (s *Server) adminUser(user *models.User) AdminUserInfo {
+	return AdminUserInfo{
+		PublicUser: s.publicUser(user),
+		IsActive:   user.IsActive,
+		LastLogin:  user.LastLogin,
+	}
+}
+
+// isLastAdmin checks if a user is the only active admin, whom demoting or
+// deactivating would lock everyone out of the admin API
+func (s *Server) isLastAdmin(user *models.User) bool {
+	return user.IsActive && user.IsAdmin() && s.userStore.CountAdmins() <= 1
+}
@@ -0,0 +1,177 @@
+/*
+================================================================================
+API KEY HANDLERS - P2P Academic Library "The Knowledge Exchange"
+================================================================================
+This file implements the endpoints users manage their API keys with.
+
+Keys are created, listed and revoked from a signed-in session only; an API
+key can't be used to mint or revoke keys. The key itself is returned once,
+on creation.
+
+Go Concepts Used:
+- HTTP handlers: Request/response handling
+- JSON encoding/decoding
+- Error handling
+================================================================================
+*/
+
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"knowledge-exchange/auth"
+	"knowledge-exchange/models"
+)
+
+// ============================================================================
+// REQUEST/RESPONSE TYPES
+// ============================================================================
+
+// CreateAPIKeyRequest names a new key and what it may do
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days,omitempty"` // 0 for the default
+}
+
+// RevokeAPIKeyRequest names the key to revoke
+type RevokeAPIKeyRequest struct {
+	ID string `json:"id"`
+}
+
+// APIKeyInfo describes a key without its hash
+type APIKeyInfo struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Prefix    string    `json:"prefix"`
+	Scopes    []string  `json:"scopes"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	LastUsed  time.Time `json:"last_used,omitempty"`
+	Revoked   bool      `json:"revoked"`
+	Expired   bool      `json:"expired"`
+}
+
+// CreatedAPIKey is the reply to creating a key
+type CreatedAPIKey struct {
+	Key  string     `json:"key"` // Shown once; only a hash is stored
+	Info APIKeyInfo `json:"info"`
+}
+
+// ============================================================================
+// API KEY HANDLERS
+// ============================================================================
+
+// listAPIKeysHandler returns the caller's keys, newest first
+func (r *Router) listAPIKeysHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		user, _ := auth.UserFromContext(req.Context())
+
+		keys := r.server.authService.ListAPIKeys(user.ID)
+		infos := make([]APIKeyInfo, len(keys))
+		for i := range keys {
+			infos[i] = apiKeyInfo(keys[i])
+		}
+
+		r.server.sendJSON(w, http.StatusOK, APIResponse{
+			Success: true,
+			Data:    infos,
+		})
+	}
+}
+
+// createAPIKeyHandler issues a key for the caller
+func (r *Router) createAPIKeyHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		user, _ := auth.UserFromContext(req.Context())
+
+		var body CreateAPIKeyRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			r.server.sendError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if body.ExpiresInDays < 0 {
+			r.server.sendError(w, http.StatusBadRequest, "expires_in_days must not be negative")
+			return
+		}
+
+		ttl := time.Duration(body.ExpiresInDays) * 24 * time.Hour
+		key, record, err := r.server.authService.CreateAPIKey(user, body.Name, body.Scopes, ttl)
+		if err != nil {
+			status := http.StatusBadRequest
+			switch {
+			case errors.Is(err, auth.ErrScopeNotAllowed):
+				status = http.StatusForbidden
+			case errors.Is(err, auth.ErrNoAPIKeyStore):
+				status = http.StatusServiceUnavailable
+			}
+			r.server.sendError(w, status, err.Error())
+			return
+		}
+
+		log.Printf("API key %q (%s) created for %s with scopes %v",
+			record.Name, record.Prefix, user.Username, record.Scopes)
+
+		r.server.sendJSON(w, http.StatusCreated, APIResponse{
+			Success: true,
+			Message: "API key created. Copy it now, it won't be shown again",
+			Data: CreatedAPIKey{
+				Key:  key,
+				Info: apiKeyInfo(record),
+			},
+		})
+	}
+}
+
+// revokeAPIKeyHandler revokes one of the caller's keys
+func (r *Router) revokeAPIKeyHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		user, _ := auth.UserFromContext(req.Context())
+
+		var body RevokeAPIKeyRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.ID == "" {
+			r.server.sendError(w, http.StatusBadRequest, "Key ID required")
+			return
+		}
+
+		if err := r.server.authService.RevokeAPIKey(user.ID, body.ID); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, auth.ErrAPIKeyNotFound) {
+				status = http.StatusNotFound
+			}
+			r.server.sendError(w, status, err.Error())
+			return
+		}
+
+		log.Printf("API key %s revoked by %s", body.ID, user.Username)
+
+		r.server.sendJSON(w, http.StatusOK, APIResponse{
+			Success: true,
+			Message: "API key revoked",
+		})
+	}
+}
+
+// ============================================================================
+// HELPERS
+// ============================================================================
+
+// apiKeyInfo converts a stored key for display
+func apiKeyInfo(key models.APIKey) APIKeyInfo {
+	return APIKeyInfo{
+		ID:        key.ID,
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		Scopes:    key.Scopes,
+		CreatedAt: key.CreatedAt,
+		ExpiresAt: key.ExpiresAt,
+		LastUsed:  key.LastUsed,
+		Revoked:   key.IsRevoked(),
+		Expired:   key.IsExpired(),
+	}
+}
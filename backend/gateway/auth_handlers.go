@@ -15,13 +15,26 @@ Go Concepts Used:
 package gateway
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"math"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"knowledge-exchange/auth"
+	"knowledge-exchange/mail"
 	"knowledge-exchange/models"
+	"knowledge-exchange/utils"
 )
 
 // ============================================================================
@@ -49,10 +62,23 @@ type AuthResponse struct {
 	Error   string    `json:"error,omitempty"`
 }
 
-// AuthData contains user data and token
+// AuthData contains user data and tokens
 type AuthData struct {
-	User  models.PublicUser `json:"user"`
-	Token string            `json:"token"`
+	User         models.PublicUser `json:"user"`
+	Token        string            `json:"token"`
+	RefreshToken string            `json:"refresh_token,omitempty"`
+	ExpiresIn    int64             `json:"expires_in,omitempty"`
+}
+
+// RefreshRequest carries a refresh token (refresh and logout)
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// ChangePasswordRequest represents a password change request
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
 }
 
 // ============================================================================
@@ -110,23 +136,62 @@ func (r *Router) registerHandler() http.HandlerFunc {
 
 		log.Printf("New user registered: %s (%s)", user.Username, user.Email)
 
+		go func() {
+			if err := r.server.sendVerification(user); err != nil {
+				log.Printf("Failed to send verification to %s: %v", user.Email, err)
+			}
+		}()
+
 		sendJSON(w, http.StatusCreated, AuthResponse{
 			Success: true,
-			Message: "Registration successful. Please login.",
+			Message: "Registration successful. Check your email to verify your address, then login.",
 		})
 	}
 }
 
 // ============================================================================
-// LOGIN HANDLER
+// FIRST-RUN SETUP
 // ============================================================================
 
-// loginHandler handles user login
-func (r *Router) loginHandler() http.HandlerFunc {
+// SetupRequest creates the first admin account
+type SetupRequest struct {
+	SetupToken string `json:"setup_token"`
+	Email      string `json:"email"`
+	Username   string `json:"username"`
+	Password   string `json:"password"`
+}
+
+// newSetupToken generates the one-time token that authorizes creating the
+// first admin; it only lives in memory and is replaced on every start
+func (s *Server) newSetupToken() (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+
+	s.setupMutex.Lock()
+	defer s.setupMutex.Unlock()
+	s.setupToken = hex.EncodeToString(raw)
+	return s.setupToken, nil
+}
+
+// setupStatusHandler reports whether the first admin still has to be created
+func (r *Router) setupStatusHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
-		var loginReq LoginRequest
+		r.server.sendJSON(w, http.StatusOK, APIResponse{
+			Success: true,
+			Data: map[string]interface{}{
+				"required": !r.server.userStore.HasAdmin(),
+			},
+		})
+	}
+}
 
-		if err := json.NewDecoder(req.Body).Decode(&loginReq); err != nil {
+// setupHandler creates the first admin account using the setup token
+func (r *Router) setupHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var body SetupRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
 			sendJSON(w, http.StatusBadRequest, AuthResponse{
 				Success: false,
 				Error:   "Invalid request body",
@@ -134,113 +199,212 @@ func (r *Router) loginHandler() http.HandlerFunc {
 			return
 		}
 
-		// Find user by email
-		user, err := r.server.userStore.GetByEmail(loginReq.Email)
-		if err != nil {
-			sendJSON(w, http.StatusUnauthorized, AuthResponse{
+		// Hold the lock throughout so the token can only be used once
+		r.server.setupMutex.Lock()
+		defer r.server.setupMutex.Unlock()
+
+		if r.server.setupToken == "" || r.server.userStore.HasAdmin() {
+			sendJSON(w, http.StatusConflict, AuthResponse{
 				Success: false,
-				Error:   "Invalid email or password",
+				Error:   "Setup has already been completed",
 			})
 			return
 		}
 
-		// Check if user is active
-		if !user.IsActive {
-			sendJSON(w, http.StatusUnauthorized, AuthResponse{
+		if subtle.ConstantTimeCompare([]byte(body.SetupToken), []byte(r.server.setupToken)) != 1 {
+			sendJSON(w, http.StatusForbidden, AuthResponse{
 				Success: false,
-				Error:   "Account is deactivated",
+				Error:   "Invalid setup token",
 			})
 			return
 		}
 
-		// Verify password
-		if err := r.server.authService.VerifyPassword(user.PasswordHash, loginReq.Password); err != nil {
-			sendJSON(w, http.StatusUnauthorized, AuthResponse{
+		if err := auth.ValidatePasswordStrength(body.Password); err != nil {
+			sendJSON(w, http.StatusBadRequest, AuthResponse{
 				Success: false,
-				Error:   "Invalid email or password",
+				Error:   err.Error(),
 			})
 			return
 		}
 
-		// Generate JWT token
-		token, err := r.server.authService.GenerateToken(user)
+		passwordHash, err := r.server.authService.HashPassword(body.Password)
 		if err != nil {
 			sendJSON(w, http.StatusInternalServerError, AuthResponse{
 				Success: false,
-				Error:   "Failed to generate token",
+				Error:   "Failed to process password",
 			})
 			return
 		}
 
-		// Update last login
-		user.UpdateLastLogin()
-		r.server.userStore.Update(user)
+		admin := &models.User{
+			Email:        body.Email,
+			Username:     body.Username,
+			PasswordHash: passwordHash,
+			Role:         models.RoleAdmin,
+		}
+		if err := r.server.userStore.Create(admin); err != nil {
+			sendJSON(w, http.StatusBadRequest, AuthResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
 
-		log.Printf("User logged in: %s (%s)", user.Username, user.Email)
+		r.server.setupToken = ""
+		log.Printf("First admin created: %s (%s)", admin.Username, admin.Email)
 
-		sendJSON(w, http.StatusOK, AuthResponse{
+		sendJSON(w, http.StatusCreated, AuthResponse{
 			Success: true,
-			Message: "Login successful",
-			Data: &AuthData{
-				User:  user.ToPublic(),
-				Token: token,
-			},
+			Message: "Admin account created. Please login.",
 		})
 	}
 }
 
 // ============================================================================
-// GET CURRENT USER HANDLER
+// LOGIN HANDLER
 // ============================================================================
 
-// meHandler returns the current user from the token
-func (r *Router) meHandler() http.HandlerFunc {
+// loginHandler handles user login
+func (r *Router) loginHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
-		// Extract token from header
-		authHeader := req.Header.Get("Authorization")
-		if authHeader == "" {
-			sendJSON(w, http.StatusUnauthorized, AuthResponse{
+		var loginReq LoginRequest
+
+		if err := json.NewDecoder(req.Body).Decode(&loginReq); err != nil {
+			sendJSON(w, http.StatusBadRequest, AuthResponse{
 				Success: false,
-				Error:   "Authorization header required",
+				Error:   "Invalid request body",
 			})
 			return
 		}
 
-		// Extract token from "Bearer <token>"
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			sendJSON(w, http.StatusUnauthorized, AuthResponse{
-				Success: false,
-				Error:   "Invalid authorization header format",
-			})
+		// Refuse while the account or address is locked out
+		address := clientAddress(req)
+		guard := r.server.loginGuard
+		if wait, ok := guard.Begin(loginReq.Email, address); !ok {
+			sendLockedOut(w, wait)
 			return
 		}
+		defer guard.Finish(loginReq.Email, address)
 
-		token := parts[1]
-
-		// Validate token and get claims
-		claims, err := r.server.authService.ValidateToken(token)
+		// Find user by email
+		user, err := r.server.userStore.GetByEmail(loginReq.Email)
 		if err != nil {
+			r.loginFailed(w, loginReq.Email, address)
+			return
+		}
+
+		// Verify password
+		if err := r.server.authService.VerifyPassword(user.PasswordHash, loginReq.Password); err != nil {
+			r.loginFailed(w, loginReq.Email, address)
+			return
+		}
+
+		// Only say the account is deactivated once the password is proven,
+		// so the status can't be probed and guesses still count as failures
+		if !user.IsActive {
 			sendJSON(w, http.StatusUnauthorized, AuthResponse{
 				Success: false,
-				Error:   "Invalid or expired token",
+				Error:   "Account is deactivated",
 			})
 			return
 		}
 
-		// Get user from store
-		user, err := r.server.userStore.GetByID(claims.UserID)
-		if err != nil {
-			sendJSON(w, http.StatusNotFound, AuthResponse{
+		// With 2FA on, the password only earns a challenge for the code;
+		// the failure counter is cleared once the code is accepted
+		if user.TwoFactorEnabled() {
+			r.sendTwoFactorChallenge(w, user)
+			return
+		}
+		guard.RecordSuccess(loginReq.Email)
+
+		r.completeLogin(w, user, "Login successful")
+	}
+}
+
+// completeLogin starts a session for a user whose credentials were accepted
+func (r *Router) completeLogin(w http.ResponseWriter, user *models.User, message string) {
+	// Start a session: access token plus refresh token
+	tokens, err := r.server.authService.IssueTokens(user)
+	if err != nil {
+		sendJSON(w, http.StatusInternalServerError, AuthResponse{
+			Success: false,
+			Error:   "Failed to generate token",
+		})
+		return
+	}
+
+	// Update last login
+	updated, err := r.server.userStore.Modify(user.ID, func(u *models.User) error {
+		u.UpdateLastLogin()
+		return nil
+	})
+	if err != nil {
+		log.Printf("Warning: failed to record login for %s: %v", user.Username, err)
+	} else {
+		user = updated
+	}
+
+	log.Printf("User logged in: %s (%s)", user.Username, user.Email)
+
+	sendJSON(w, http.StatusOK, AuthResponse{
+		Success: true,
+		Message: message,
+		Data:    r.server.authData(user, tokens),
+	})
+}
+
+// loginFailed counts a failed login and answers it
+// The reply is the same whether or not the email exists
+func (r *Router) loginFailed(w http.ResponseWriter, email, address string) {
+	if wait := r.server.loginGuard.RecordFailure(email, address); wait > 0 {
+		sendLockedOut(w, wait)
+		return
+	}
+	sendJSON(w, http.StatusUnauthorized, AuthResponse{
+		Success: false,
+		Error:   "Invalid email or password",
+	})
+}
+
+// sendLockedOut refuses a login attempt until a lockout ends
+func sendLockedOut(w http.ResponseWriter, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	sendJSON(w, http.StatusTooManyRequests, AuthResponse{
+		Success: false,
+		Error:   fmt.Sprintf("Too many failed login attempts. Try again in %d seconds", seconds),
+	})
+}
+
+// clientAddress returns the client's IP address
+// Forwarding headers are ignored since any client can set them
+func clientAddress(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+// ============================================================================
+// GET CURRENT USER HANDLER
+// ============================================================================
+
+// meHandler returns the current user from the request context
+func (r *Router) meHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		user, ok := auth.UserFromContext(req.Context())
+		if !ok {
+			sendJSON(w, http.StatusUnauthorized, AuthResponse{
 				Success: false,
-				Error:   "User not found",
+				Error:   "Authentication required",
 			})
 			return
 		}
 
 		sendJSON(w, http.StatusOK, map[string]interface{}{
 			"success": true,
-			"data":    user.ToPublic(),
+			"data":    r.server.publicUser(user),
 		})
 	}
 }
@@ -249,12 +413,23 @@ func (r *Router) meHandler() http.HandlerFunc {
 // LOGOUT HANDLER
 // ============================================================================
 
-// logoutHandler handles user logout (client-side token deletion)
+// logoutHandler ends the caller's session server-side
+// The access token in the header (if any) is revoked until it expires, and
+// the refresh token in the body (if any) is revoked with its family
 func (r *Router) logoutHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
-		// In JWT-based auth, logout is primarily client-side
-		// The client should delete the token from storage
-		// We could implement a token blacklist here if needed
+		if claims, ok := auth.ClaimsFromContext(req.Context()); ok {
+			if err := r.server.authService.RevokeAccessToken(claims); err != nil {
+				log.Printf("Warning: failed to revoke access token: %v", err)
+			}
+		}
+
+		var body RefreshRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err == nil && body.RefreshToken != "" {
+			if err := r.server.authService.RevokeRefreshToken(body.RefreshToken); err != nil && err != auth.ErrInvalidRefreshToken {
+				log.Printf("Warning: failed to revoke refresh token: %v", err)
+			}
+		}
 
 		sendJSON(w, http.StatusOK, AuthResponse{
 			Success: true,
@@ -264,109 +439,1232 @@ func (r *Router) logoutHandler() http.HandlerFunc {
 }
 
 // ============================================================================
-// MIDDLEWARE
+// SESSION HANDLERS
 // ============================================================================
 
-// authMiddleware validates JWT token and adds user info to request context
-func (r *Router) authMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		// Extract token from header
-		authHeader := req.Header.Get("Authorization")
-		if authHeader == "" {
-			sendJSON(w, http.StatusUnauthorized, map[string]interface{}{
-				"success": false,
-				"error":   "Authorization header required",
+// refreshHandler exchanges a refresh token for a new token pair
+func (r *Router) refreshHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var body RefreshRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.RefreshToken == "" {
+			sendJSON(w, http.StatusBadRequest, AuthResponse{
+				Success: false,
+				Error:   "Refresh token required",
 			})
 			return
 		}
 
-		//Extract token from "Bearer <token>"
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			sendJSON(w, http.StatusUnauthorized, map[string]interface{}{
-				"success": false,
-				"error":   "Invalid authorization header format",
+		var user *models.User
+		tokens, err := r.server.authService.Refresh(body.RefreshToken, func(userID string) (*models.User, error) {
+			found, err := r.server.userStore.GetByID(userID)
+			if err != nil {
+				return nil, err
+			}
+			if !found.IsActive {
+				return nil, errors.New("Account is deactivated")
+			}
+			user = found
+			return found, nil
+		})
+		if err != nil {
+			if err == auth.ErrRefreshTokenReused {
+				log.Printf("Refresh token reuse detected; session family revoked")
+			}
+			sendJSON(w, http.StatusUnauthorized, AuthResponse{
+				Success: false,
+				Error:   err.Error(),
 			})
 			return
 		}
 
-		token := parts[1]
+		sendJSON(w, http.StatusOK, AuthResponse{
+			Success: true,
+			Message: "Token refreshed",
+			Data:    r.server.authData(user, tokens),
+		})
+	}
+}
 
-		// Validate token
-		claims, err := r.server.authService.ValidateToken(token)
-		if err != nil {
-			sendJSON(w, http.StatusUnauthorized, map[string]interface{}{
-				"success": false,
-				"error":   "Invalid or expired token",
+// changePasswordHandler changes the caller's password and signs out every
+// other session
+func (r *Router) changePasswordHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		user, ok := auth.UserFromContext(req.Context())
+		if !ok {
+			sendJSON(w, http.StatusUnauthorized, AuthResponse{
+				Success: false,
+				Error:   "Authentication required",
 			})
 			return
 		}
 
-		// Store user ID in request context for handlers to use
-		// For now, we'll validate the user exists
-		_, err = r.server.userStore.GetByID(claims.UserID)
-		if err != nil {
-			sendJSON(w, http.StatusUnauthorized, map[string]interface{}{
-				"success": false,
-				"error":   "User not found",
+		var body ChangePasswordRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			sendJSON(w, http.StatusBadRequest, AuthResponse{
+				Success: false,
+				Error:   "Invalid request body",
 			})
 			return
 		}
 
-		// Continue to next handler
-		next.ServeHTTP(w, req)
-	})
-}
+		if err := r.server.authService.VerifyPassword(user.PasswordHash, body.CurrentPassword); err != nil {
+			sendJSON(w, http.StatusUnauthorized, AuthResponse{
+				Success: false,
+				Error:   "Current password is incorrect",
+			})
+			return
+		}
 
-// adminMiddleware checks if user has admin role
-func (r *Router) adminMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		// Extract and validate token (similar to authMiddleware)
-		authHeader := req.Header.Get("Authorization")
-		if authHeader == "" {
-			sendJSON(w, http.StatusUnauthorized, map[string]interface{}{
-				"success": false,
-				"error":   "Authorization header required",
+		if err := auth.ValidatePasswordStrength(body.NewPassword); err != nil {
+			sendJSON(w, http.StatusBadRequest, AuthResponse{
+				Success: false,
+				Error:   err.Error(),
 			})
 			return
 		}
 
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			sendJSON(w, http.StatusUnauthorized, map[string]interface{}{
-				"success": false,
-				"error":   "Invalid authorization header format",
+		passwordHash, err := r.server.authService.HashPassword(body.NewPassword)
+		if err != nil {
+			sendJSON(w, http.StatusInternalServerError, AuthResponse{
+				Success: false,
+				Error:   "Failed to process password",
 			})
 			return
 		}
 
-		token := parts[1]
-		claims, err := r.server.authService.ValidateToken(token)
+		user, err = r.server.userStore.Modify(user.ID, func(u *models.User) error {
+			u.PasswordHash = passwordHash
+			return nil
+		})
 		if err != nil {
-			sendJSON(w, http.StatusUnauthorized, map[string]interface{}{
-				"success": false,
-				"error":   "Invalid or expired token",
+			sendJSON(w, http.StatusInternalServerError, AuthResponse{
+				Success: false,
+				Error:   err.Error(),
 			})
 			return
 		}
 
-		// Check if user is admin
-		if !auth.IsAdmin(claims) {
-			sendJSON(w, http.StatusForbidden, map[string]interface{}{
-				"success": false,
-				"error":   "Admin access required",
+		// Revoke all existing sessions, then start a fresh one for the caller
+		if err := r.server.authService.RevokeUser(user.ID); err != nil {
+			log.Printf("Warning: failed to revoke sessions for %s: %v", user.Username, err)
+		}
+		tokens, err := r.server.authService.IssueTokens(user)
+		if err != nil {
+			sendJSON(w, http.StatusInternalServerError, AuthResponse{
+				Success: false,
+				Error:   "Failed to generate token",
 			})
 			return
 		}
 
-		next.ServeHTTP(w, req)
-	})
+		log.Printf("Password changed for %s; other sessions revoked", user.Username)
+
+		sendJSON(w, http.StatusOK, AuthResponse{
+			Success: true,
+			Message: "Password changed",
+			Data:    r.server.authData(user, tokens),
+		})
+	}
+}
+
+// revokeSessionsHandler lets an admin sign a user out everywhere
+func (r *Router) revokeSessionsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var body struct {
+			UserID string `json:"user_id"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.UserID == "" {
+			sendJSON(w, http.StatusBadRequest, AuthResponse{
+				Success: false,
+				Error:   "User ID required",
+			})
+			return
+		}
+
+		if _, err := r.server.userStore.GetByID(body.UserID); err != nil {
+			sendJSON(w, http.StatusNotFound, AuthResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		if err := r.server.authService.RevokeUser(body.UserID); err != nil {
+			sendJSON(w, http.StatusInternalServerError, AuthResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		log.Printf("All sessions revoked for user %s", body.UserID)
+
+		sendJSON(w, http.StatusOK, AuthResponse{
+			Success: true,
+			Message: "Sessions revoked",
+		})
+	}
 }
 
 // ============================================================================
-// HELPER FUNCTIONS
+// PASSWORD RESET AND EMAIL VERIFICATION
 // ============================================================================
 
+// ForgotPasswordRequest asks for a password reset link
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// ResetPasswordRequest sets a new password using an emailed token
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+// VerifyEmailRequest confirms an email address using an emailed token
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// forgotPasswordHandler emails a password reset link
+// The reply is the same whether or not the email is registered, and the
+// mail is sent in the background so timing doesn't tell either
+func (r *Router) forgotPasswordHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var body ForgotPasswordRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.Email == "" {
+			sendJSON(w, http.StatusBadRequest, AuthResponse{
+				Success: false,
+				Error:   "Email required",
+			})
+			return
+		}
+
+		if user, err := r.server.userStore.GetByEmail(body.Email); err == nil && user.IsActive {
+			go func() {
+				if err := r.server.sendPasswordReset(user); err != nil {
+					log.Printf("Failed to send password reset to %s: %v", user.Email, err)
+				}
+			}()
+		}
+
+		sendJSON(w, http.StatusOK, AuthResponse{
+			Success: true,
+			Message: "If that email is registered, a reset link has been sent",
+		})
+	}
+}
+
+// resetPasswordHandler sets a new password from a reset token
+// All sessions are revoked and any login lockout on the account is cleared
+func (r *Router) resetPasswordHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var body ResetPasswordRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.Token == "" {
+			sendJSON(w, http.StatusBadRequest, AuthResponse{
+				Success: false,
+				Error:   "Reset token required",
+			})
+			return
+		}
+
+		// Check the password first so a weak one doesn't use up the token
+		if err := auth.ValidatePasswordStrength(body.NewPassword); err != nil {
+			sendJSON(w, http.StatusBadRequest, AuthResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		user, err := r.server.redeemActionToken(body.Token, models.PurposePasswordReset)
+		if err != nil {
+			sendJSON(w, http.StatusBadRequest, AuthResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		passwordHash, err := r.server.authService.HashPassword(body.NewPassword)
+		if err != nil {
+			sendJSON(w, http.StatusInternalServerError, AuthResponse{
+				Success: false,
+				Error:   "Failed to process password",
+			})
+			return
+		}
+
+		// Receiving the link also proves the user owns the address
+		user, err = r.server.userStore.Modify(user.ID, func(u *models.User) error {
+			u.PasswordHash = passwordHash
+			u.EmailVerified = true
+			return nil
+		})
+		if err != nil {
+			sendJSON(w, http.StatusInternalServerError, AuthResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		if err := r.server.authService.RevokeUser(user.ID); err != nil {
+			log.Printf("Warning: failed to revoke sessions for %s: %v", user.Username, err)
+		}
+		r.server.loginGuard.RecordSuccess(user.Email)
+
+		log.Printf("Password reset for %s; all sessions revoked", user.Username)
+
+		sendJSON(w, http.StatusOK, AuthResponse{
+			Success: true,
+			Message: "Password reset. Please login.",
+		})
+	}
+}
+
+// requestVerificationHandler emails the caller a verification link
+func (r *Router) requestVerificationHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		user, ok := auth.UserFromContext(req.Context())
+		if !ok {
+			sendJSON(w, http.StatusUnauthorized, AuthResponse{
+				Success: false,
+				Error:   "Authentication required",
+			})
+			return
+		}
+
+		if user.EmailVerified {
+			sendJSON(w, http.StatusConflict, AuthResponse{
+				Success: false,
+				Error:   "Email is already verified",
+			})
+			return
+		}
+
+		if err := r.server.sendVerification(user); err != nil {
+			log.Printf("Failed to send verification to %s: %v", user.Email, err)
+			sendJSON(w, http.StatusInternalServerError, AuthResponse{
+				Success: false,
+				Error:   "Failed to send verification email",
+			})
+			return
+		}
+
+		sendJSON(w, http.StatusOK, AuthResponse{
+			Success: true,
+			Message: "Verification email sent to " + user.Email,
+		})
+	}
+}
+
+// confirmVerificationHandler marks an email verified from a token
+func (r *Router) confirmVerificationHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var body VerifyEmailRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.Token == "" {
+			sendJSON(w, http.StatusBadRequest, AuthResponse{
+				Success: false,
+				Error:   "Verification token required",
+			})
+			return
+		}
+
+		user, err := r.server.redeemActionToken(body.Token, models.PurposeVerifyEmail)
+		if err != nil {
+			sendJSON(w, http.StatusBadRequest, AuthResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		user, err = r.server.userStore.Modify(user.ID, func(u *models.User) error {
+			u.EmailVerified = true
+			return nil
+		})
+		if err != nil {
+			sendJSON(w, http.StatusInternalServerError, AuthResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		log.Printf("Email verified for %s (%s)", user.Username, user.Email)
+
+		sendJSON(w, http.StatusOK, AuthResponse{
+			Success: true,
+			Message: "Email verified",
+		})
+	}
+}
+
+// sendPasswordReset emails a user a password reset link
+func (s *Server) sendPasswordReset(user *models.User) error {
+	token, err := s.authService.IssueActionToken(user, models.PurposePasswordReset, auth.PasswordResetExpiration)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(mail.Message{
+		To:      user.Email,
+		Subject: utils.AppName + ": reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"Someone asked to reset the password for your account. To choose a\n"+
+			"new password, open this link within %s:\n\n%s\n\n"+
+			"If you didn't ask for this, you can ignore this email.\n",
+			user.Username, hours(auth.PasswordResetExpiration), s.publicLink("/reset-password", token)),
+	})
+}
+
+// sendVerification emails a user a link that verifies their address
+func (s *Server) sendVerification(user *models.User) error {
+	token, err := s.authService.IssueActionToken(user, models.PurposeVerifyEmail, auth.VerifyEmailExpiration)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(mail.Message{
+		To:      user.Email,
+		Subject: utils.AppName + ": verify your email",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"Please confirm this is your email address by opening this link\n"+
+			"within %s:\n\n%s\n",
+			user.Username, hours(auth.VerifyEmailExpiration), s.publicLink("/verify-email", token)),
+	})
+}
+
+// redeemActionToken consumes an emailed token and returns its user
+// The token is refused if the account's email changed since it was sent
+func (s *Server) redeemActionToken(token, purpose string) (*models.User, error) {
+	record, err := s.authService.ConsumeActionToken(token, purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userStore.GetByID(record.UserID)
+	if err != nil || !strings.EqualFold(user.Email, record.Email) {
+		return nil, auth.ErrInvalidActionToken
+	}
+	if !user.IsActive {
+		return nil, errors.New("Account is deactivated")
+	}
+	return user, nil
+}
+
+// hours formats a token lifetime for an email ("1 hour", "48 hours")
+func hours(d time.Duration) string {
+	n := int(d.Hours())
+	if n == 1 {
+		return "1 hour"
+	}
+	return fmt.Sprintf("%d hours", n)
+}
+
+// publicLink builds a frontend URL carrying a token
+func (s *Server) publicLink(path, token string) string {
+	return s.config.PublicURL + path + "?token=" + url.QueryEscape(token)
+}
+
+// ============================================================================
+// TWO-FACTOR AUTHENTICATION
+// ============================================================================
+
+// TwoFactorChallenge is the login reply when a second factor is needed
+type TwoFactorChallenge struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+	ExpiresIn         int64  `json:"expires_in"`
+}
+
+// TwoFactorLoginRequest completes a login with a TOTP or recovery code
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+}
+
+// TwoFactorRequest carries the credentials 2FA changes ask for
+// Setup needs the password, enable and new recovery codes need a code,
+// and disabling needs both
+type TwoFactorRequest struct {
+	Password string `json:"password,omitempty"`
+	Code     string `json:"code,omitempty"`
+}
+
+// TwoFactorStatus describes the caller's 2FA enrollment
+type TwoFactorStatus struct {
+	Enabled           bool `json:"enabled"`
+	Pending           bool `json:"pending"` // Setup started but not confirmed
+	Required          bool `json:"required"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+// TwoFactorSetup is the secret to load into an authenticator app
+type TwoFactorSetup struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// TwoFactorEnabled is the reply to enabling 2FA: the recovery codes, shown
+// once, and a new session since every other session was signed out
+type TwoFactorEnabled struct {
+	RecoveryCodes []string  `json:"recovery_codes"`
+	Session       *AuthData `json:"session"`
+}
+
+// sendTwoFactorChallenge answers a correct password for a 2FA account
+func (r *Router) sendTwoFactorChallenge(w http.ResponseWriter, user *models.User) {
+	token, err := r.server.authService.IssueActionToken(user, models.PurposeLoginTOTP, auth.LoginChallengeExpiration)
+	if err != nil {
+		sendJSON(w, http.StatusInternalServerError, AuthResponse{
+			Success: false,
+			Error:   "Failed to start two-factor login",
+		})
+		return
+	}
+
+	sendJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Message: "Enter the code from your authenticator app",
+		Data: TwoFactorChallenge{
+			TwoFactorRequired: true,
+			ChallengeToken:    token,
+			ExpiresIn:         int64(auth.LoginChallengeExpiration.Seconds()),
+		},
+	})
+}
+
+// loginTwoFactorHandler completes a login with the second factor
+// A challenge is single-use: after a wrong code the user signs in again,
+// and the failure counts toward the account's lockout
+func (r *Router) loginTwoFactorHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var body TwoFactorLoginRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.ChallengeToken == "" || body.Code == "" {
+			sendJSON(w, http.StatusBadRequest, AuthResponse{
+				Success: false,
+				Error:   "Challenge token and code required",
+			})
+			return
+		}
+
+		user, err := r.server.redeemActionToken(body.ChallengeToken, models.PurposeLoginTOTP)
+		if err != nil {
+			sendJSON(w, http.StatusUnauthorized, AuthResponse{
+				Success: false,
+				Error:   "Login expired, please sign in again",
+			})
+			return
+		}
+
+		address := clientAddress(req)
+		guard := r.server.loginGuard
+		if wait, ok := guard.Begin(user.Email, address); !ok {
+			sendLockedOut(w, wait)
+			return
+		}
+		defer guard.Finish(user.Email, address)
+
+		verified, usedRecovery, err := r.server.checkSecondFactor(user.ID, body.Code, true, nil)
+		if isTwoFactorRejection(err) {
+			if wait := guard.RecordFailure(user.Email, address); wait > 0 {
+				sendLockedOut(w, wait)
+				return
+			}
+			sendJSON(w, http.StatusUnauthorized, AuthResponse{
+				Success: false,
+				Error:   "Invalid two-factor code, please sign in again",
+			})
+			return
+		}
+		if err != nil {
+			sendJSON(w, http.StatusInternalServerError, AuthResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+		guard.RecordSuccess(user.Email)
+
+		message := "Login successful"
+		if usedRecovery {
+			left := len(verified.TwoFactor.RecoveryCodes)
+			log.Printf("Recovery code used by %s (%d left)", verified.Username, left)
+			message = fmt.Sprintf("Login successful. Recovery code used, %d left", left)
+		}
+
+		r.completeLogin(w, verified, message)
+	}
+}
+
+// twoFactorStatusHandler reports the caller's 2FA enrollment
+func (r *Router) twoFactorStatusHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		user, _ := auth.UserFromContext(req.Context())
+
+		status := TwoFactorStatus{
+			Enabled:  user.TwoFactorEnabled(),
+			Required: r.server.twoFactorRequired(user),
+		}
+		if tf := user.TwoFactor; tf != nil {
+			status.Pending = tf.PendingSecret != ""
+			status.RecoveryCodesLeft = len(tf.RecoveryCodes)
+		}
+
+		sendJSON(w, http.StatusOK, APIResponse{
+			Success: true,
+			Data:    status,
+		})
+	}
+}
+
+// twoFactorSetupHandler starts enrollment with a new secret
+// The password is asked again so a stolen session can't enroll its own device
+func (r *Router) twoFactorSetupHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		user, _ := auth.UserFromContext(req.Context())
+
+		var body TwoFactorRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			sendJSON(w, http.StatusBadRequest, AuthResponse{
+				Success: false,
+				Error:   "Invalid request body",
+			})
+			return
+		}
+		if err := r.server.authService.VerifyPassword(user.PasswordHash, body.Password); err != nil {
+			sendJSON(w, http.StatusUnauthorized, AuthResponse{
+				Success: false,
+				Error:   "Password is incorrect",
+			})
+			return
+		}
+
+		var secret, uri string
+		_, err := r.server.userStore.Modify(user.ID, func(u *models.User) error {
+			var err error
+			secret, uri, err = auth.StartTOTPEnrollment(u, utils.AppName)
+			return err
+		})
+		if errors.Is(err, auth.ErrTwoFactorEnabled) {
+			sendJSON(w, http.StatusConflict, AuthResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+		if err != nil {
+			sendJSON(w, http.StatusInternalServerError, AuthResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		sendJSON(w, http.StatusOK, APIResponse{
+			Success: true,
+			Message: "Scan the code, then confirm with the first code it shows",
+			Data: TwoFactorSetup{
+				Secret:          secret,
+				ProvisioningURI: uri,
+			},
+		})
+	}
+}
+
+// twoFactorEnableHandler confirms enrollment with a first code
+// Every other session is signed out, since they never passed a second factor
+func (r *Router) twoFactorEnableHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		user, _ := auth.UserFromContext(req.Context())
+
+		var body TwoFactorRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.Code == "" {
+			sendJSON(w, http.StatusBadRequest, AuthResponse{
+				Success: false,
+				Error:   "Code required",
+			})
+			return
+		}
+
+		var codes []string
+		user, err := r.server.userStore.Modify(user.ID, func(u *models.User) error {
+			var err error
+			codes, err = auth.ConfirmTOTPEnrollment(u, body.Code, time.Now())
+			return err
+		})
+		if err != nil {
+			status := http.StatusInternalServerError
+			switch {
+			case errors.Is(err, auth.ErrTwoFactorEnabled):
+				status = http.StatusConflict
+			case errors.Is(err, auth.ErrTwoFactorNotPending), errors.Is(err, auth.ErrInvalidTwoFactor):
+				status = http.StatusBadRequest
+			}
+			sendJSON(w, status, AuthResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		if err := r.server.authService.RevokeUser(user.ID); err != nil {
+			log.Printf("Warning: failed to revoke sessions for %s: %v", user.Username, err)
+		}
+		tokens, err := r.server.authService.IssueTokens(user)
+		if err != nil {
+			sendJSON(w, http.StatusInternalServerError, AuthResponse{
+				Success: false,
+				Error:   "Failed to generate token",
+			})
+			return
+		}
+
+		log.Printf("Two-factor authentication enabled for %s", user.Username)
+
+		sendJSON(w, http.StatusOK, APIResponse{
+			Success: true,
+			Message: "Two-factor authentication enabled. Store your recovery codes somewhere safe",
+			Data: TwoFactorEnabled{
+				RecoveryCodes: codes,
+				Session:       r.server.authData(user, tokens),
+			},
+		})
+	}
+}
+
+// twoFactorDisableHandler turns 2FA off after checking password and code
+// Admins can't turn it off while it is required for their role. Wrong
+// passwords and codes count toward the login lockout.
+func (r *Router) twoFactorDisableHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		user, _ := auth.UserFromContext(req.Context())
+
+		var body TwoFactorRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			sendJSON(w, http.StatusBadRequest, AuthResponse{
+				Success: false,
+				Error:   "Invalid request body",
+			})
+			return
+		}
+
+		if r.server.twoFactorRequired(user) {
+			sendJSON(w, http.StatusForbidden, AuthResponse{
+				Success: false,
+				Error:   "Two-factor authentication is required for admin accounts",
+			})
+			return
+		}
+		if !user.TwoFactorEnabled() {
+			sendJSON(w, http.StatusConflict, AuthResponse{
+				Success: false,
+				Error:   auth.ErrTwoFactorNotEnabled.Error(),
+			})
+			return
+		}
+
+		address := clientAddress(req)
+		guard := r.server.loginGuard
+		if wait, ok := guard.Begin(user.Email, address); !ok {
+			sendLockedOut(w, wait)
+			return
+		}
+		defer guard.Finish(user.Email, address)
+
+		if err := r.server.authService.VerifyPassword(user.PasswordHash, body.Password); err != nil {
+			r.twoFactorFailed(w, user.Email, address, "Password is incorrect")
+			return
+		}
+		_, _, err := r.server.checkSecondFactor(user.ID, body.Code, true, func(u *models.User) error {
+			u.TwoFactor = nil
+			return nil
+		})
+		if isTwoFactorRejection(err) {
+			r.twoFactorFailed(w, user.Email, address, err.Error())
+			return
+		}
+		if err != nil {
+			sendJSON(w, http.StatusInternalServerError, AuthResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+		guard.RecordSuccess(user.Email)
+
+		log.Printf("Two-factor authentication disabled for %s", user.Username)
+
+		sendJSON(w, http.StatusOK, AuthResponse{
+			Success: true,
+			Message: "Two-factor authentication disabled",
+		})
+	}
+}
+
+// recoveryCodesHandler replaces the caller's recovery codes
+// Needs a current code, so a recovery code can't be used to mint more;
+// wrong codes count toward the login lockout
+func (r *Router) recoveryCodesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		user, _ := auth.UserFromContext(req.Context())
+
+		var body TwoFactorRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.Code == "" {
+			sendJSON(w, http.StatusBadRequest, AuthResponse{
+				Success: false,
+				Error:   "Code required",
+			})
+			return
+		}
+
+		address := clientAddress(req)
+		guard := r.server.loginGuard
+		if wait, ok := guard.Begin(user.Email, address); !ok {
+			sendLockedOut(w, wait)
+			return
+		}
+		defer guard.Finish(user.Email, address)
+
+		var codes []string
+		_, _, err := r.server.checkSecondFactor(user.ID, body.Code, false, func(u *models.User) error {
+			var err error
+			codes, err = auth.RegenerateRecoveryCodes(u)
+			return err
+		})
+		if isTwoFactorRejection(err) {
+			r.twoFactorFailed(w, user.Email, address, err.Error())
+			return
+		}
+		if err != nil {
+			sendJSON(w, http.StatusInternalServerError, AuthResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+		guard.RecordSuccess(user.Email)
+
+		sendJSON(w, http.StatusOK, APIResponse{
+			Success: true,
+			Message: "New recovery codes issued; the old ones no longer work",
+			Data: map[string]interface{}{
+				"recovery_codes": codes,
+			},
+		})
+	}
+}
+
+// twoFactorFailed counts a wrong password or code on a 2FA change toward
+// the login lockout, so a stolen session can't guess the code, and
+// answers it
+func (r *Router) twoFactorFailed(w http.ResponseWriter, email, address, message string) {
+	if wait := r.server.loginGuard.RecordFailure(email, address); wait > 0 {
+		sendLockedOut(w, wait)
+		return
+	}
+	sendJSON(w, http.StatusUnauthorized, AuthResponse{
+		Success: false,
+		Error:   message,
+	})
+}
+
+// checkSecondFactor verifies a code against the stored user and saves the
+// used-up TOTP step or recovery code in the same locked change, so two
+// requests can't both accept one code
+// Parameters:
+//   - userID: The user entering the code
+//   - code: TOTP code, or a recovery code if allowRecovery is set
+//   - allowRecovery: Whether recovery codes are accepted
+//   - then: Further changes saved with the accepted code; may be nil
+//
+// Returns:
+//   - *models.User: The user after the change
+//   - bool: Whether a recovery code was used
+//   - error: Rejections satisfy isTwoFactorRejection
+func (s *Server) checkSecondFactor(userID, code string, allowRecovery bool, then func(*models.User) error) (*models.User, bool, error) {
+	var usedRecovery bool
+	user, err := s.userStore.Modify(userID, func(u *models.User) error {
+		var err error
+		if allowRecovery {
+			usedRecovery, err = auth.VerifySecondFactor(u, code, time.Now())
+		} else {
+			err = auth.VerifyTOTP(u, code, time.Now())
+		}
+		if err != nil || then == nil {
+			return err
+		}
+		return then(u)
+	})
+	return user, usedRecovery, err
+}
+
+// isTwoFactorRejection checks if an error means the code was refused
+// rather than that the change couldn't be saved
+func isTwoFactorRejection(err error) bool {
+	return errors.Is(err, auth.ErrInvalidTwoFactor) || errors.Is(err, auth.ErrTwoFactorNotEnabled)
+}
+
+// twoFactorRequired checks if a user's role requires 2FA
+func (s *Server) twoFactorRequired(user *models.User) bool {
+	return s.config.RequireAdminTwoFactor && user.IsAdmin()
+}
+
+// ============================================================================
+// PEER BINDING HANDLERS
+// ============================================================================
+
+// unbindPeerHandler removes one of the caller's peers from their account
+func (r *Router) unbindPeerHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		user, ok := auth.UserFromContext(req.Context())
+		if !ok {
+			sendJSON(w, http.StatusUnauthorized, AuthResponse{
+				Success: false,
+				Error:   "Authentication required",
+			})
+			return
+		}
+
+		var body struct {
+			PeerID string `json:"peer_id"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.PeerID == "" {
+			sendJSON(w, http.StatusBadRequest, AuthResponse{
+				Success: false,
+				Error:   "Peer ID required",
+			})
+			return
+		}
+
+		if err := r.server.userStore.UnbindPeer(user.ID, body.PeerID); err != nil {
+			sendJSON(w, http.StatusBadRequest, AuthResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		log.Printf("Peer %s unbound from user %s", body.PeerID, user.Username)
+
+		sendJSON(w, http.StatusOK, AuthResponse{
+			Success: true,
+			Message: "Peer unbound",
+		})
+	}
+}
+
+// ============================================================================
+// SIGNING KEY HANDLERS
+// ============================================================================
+
+// jwksHandler publishes the public signing keys so other nodes can verify
+// tokens issued here
+func (r *Router) jwksHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		sendJSON(w, http.StatusOK, r.server.authService.Keys().JWKS())
+	}
+}
+
+// listKeysHandler lists signing keys without their key material
+func (r *Router) listKeysHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		r.server.sendJSON(w, http.StatusOK, APIResponse{
+			Success: true,
+			Data:    r.server.authService.Keys().List(),
+		})
+	}
+}
+
+// rotateKeyHandler makes a new signing key active
+// Tokens signed by the previous key stay valid until they expire
+func (r *Router) rotateKeyHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var body struct {
+			Algorithm string `json:"algorithm"`
+		}
+		// An empty body keeps the configured algorithm
+		json.NewDecoder(req.Body).Decode(&body)
+		if body.Algorithm == "" {
+			body.Algorithm = r.server.config.JWTAlgorithm
+		}
+
+		key, err := r.server.authService.Keys().Rotate(body.Algorithm)
+		if err != nil {
+			r.server.sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		log.Printf("JWT signing key rotated: %s (%s)", key.ID, key.Algorithm)
+
+		r.server.sendJSON(w, http.StatusOK, APIResponse{
+			Success: true,
+			Message: "Signing key rotated",
+			Data: map[string]interface{}{
+				"kid": key.ID,
+				"alg": key.Algorithm,
+			},
+		})
+	}
+}
+
+// ============================================================================
+// MIDDLEWARE
+// ============================================================================
+
+// APIKeyHeader carries an API key instead of a bearer token
+const APIKeyHeader = "X-API-Key"
+
+// authenticate validates the request's bearer token and loads its user
+// Returns:
+//   - *models.User: The user the token belongs to
+//   - *auth.Claims: The validated claims
+//   - error: If the header is missing, malformed, the token is invalid or
+//     the user no longer exists
+func (s *Server) authenticate(req *http.Request) (*models.User, *auth.Claims, error) {
+	// Extract token from header
+	authHeader := req.Header.Get("Authorization")
+	if authHeader == "" {
+		return nil, nil, errors.New("Authorization header required")
+	}
+
+	// Extract token from "Bearer <token>"
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return nil, nil, errors.New("Invalid authorization header format")
+	}
+
+	claims, err := s.authService.ValidateToken(parts[1])
+	if err != nil {
+		return nil, nil, errors.New("Invalid or expired token")
+	}
+
+	user, err := s.userStore.GetByID(claims.UserID)
+	if err != nil {
+		return nil, nil, errors.New("User not found")
+	}
+	if !user.IsActive {
+		return nil, nil, errors.New("Account is deactivated")
+	}
+
+	return user, claims, nil
+}
+
+// authenticateKey validates the request's API key and loads its owner
+// Returns:
+//   - *models.User: The key's owner
+//   - *models.APIKey: The validated key
+//   - error: If the key is unknown, revoked or expired, or the owner no
+//     longer exists or is deactivated
+func (s *Server) authenticateKey(req *http.Request) (*models.User, *models.APIKey, error) {
+	key, err := s.authService.AuthenticateAPIKey(req.Header.Get(APIKeyHeader))
+	if err != nil {
+		return nil, nil, errors.New("Invalid, revoked or expired API key")
+	}
+
+	user, err := s.userStore.GetByID(key.UserID)
+	if err != nil {
+		return nil, nil, errors.New("User not found")
+	}
+	if !user.IsActive {
+		return nil, nil, errors.New("Account is deactivated")
+	}
+
+	return user, &key, nil
+}
+
+// identify authenticates a request by API key or bearer token
+// Returns the request's context with the identity attached
+func (s *Server) identify(req *http.Request) (context.Context, error) {
+	if req.Header.Get(APIKeyHeader) != "" {
+		user, key, err := s.authenticateKey(req)
+		if err != nil {
+			return nil, err
+		}
+		return auth.WithAPIKey(req.Context(), user, key), nil
+	}
+
+	user, claims, err := s.authenticate(req)
+	if err != nil {
+		return nil, err
+	}
+	return auth.WithIdentity(req.Context(), user, claims), nil
+}
+
+// identifyMiddleware attaches the caller's identity when a valid token or
+// API key is sent, and lets anonymous requests through unchanged (public
+// routes)
+func (r *Router) identifyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if ctx, err := r.server.identify(req); err == nil {
+			req = req.WithContext(ctx)
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// scopeMiddleware accepts a session token or an API key holding scope
+// Sessions may do everything their user may; keys only what they were
+// granted
+func (r *Router) scopeMiddleware(scope string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			ctx, err := r.server.identify(req)
+			if err != nil {
+				sendJSON(w, http.StatusUnauthorized, map[string]interface{}{
+					"success": false,
+					"error":   err.Error(),
+				})
+				return
+			}
+
+			if key, ok := auth.APIKeyFromContext(ctx); ok && !key.HasScope(scope) {
+				sendJSON(w, http.StatusForbidden, map[string]interface{}{
+					"success": false,
+					"error":   fmt.Sprintf("API key lacks the %s scope", scope),
+				})
+				return
+			}
+
+			next.ServeHTTP(w, req.WithContext(ctx))
+		})
+	}
+}
+
+// authMiddleware validates JWT token and adds user info to request context
+// API keys are refused: these routes manage the account itself
+func (r *Router) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get(APIKeyHeader) != "" {
+			sendJSON(w, http.StatusForbidden, map[string]interface{}{
+				"success": false,
+				"error":   "API keys can't be used for this endpoint; sign in instead",
+			})
+			return
+		}
+
+		user, claims, err := r.server.authenticate(req)
+		if err != nil {
+			sendJSON(w, http.StatusUnauthorized, map[string]interface{}{
+				"success": false,
+				"error":   err.Error(),
+			})
+			return
+		}
+
+		// Continue to next handler with the identity in context
+		next.ServeHTTP(w, req.WithContext(auth.WithIdentity(req.Context(), user, claims)))
+	})
+}
+
+// permissionMiddleware checks that the caller's role grants a permission
+// Must run after authMiddleware or scopeMiddleware; the role is read from
+// the stored user so role changes apply without waiting for the token to
+// expire
+func (r *Router) permissionMiddleware(permission string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			user, ok := auth.UserFromContext(req.Context())
+			if !ok {
+				sendJSON(w, http.StatusUnauthorized, map[string]interface{}{
+					"success": false,
+					"error":   "Authentication required",
+				})
+				return
+			}
+
+			if !r.server.can(user, permission) {
+				sendJSON(w, http.StatusForbidden, map[string]interface{}{
+					"success": false,
+					"error":   fmt.Sprintf("Your role (%s) lacks the %s permission", user.Role, permission),
+				})
+				return
+			}
+
+			// Admin sessions must have passed a second factor for staff actions
+			if models.IsPrivilegedPermission(permission) &&
+				r.server.twoFactorRequired(user) && !user.TwoFactorEnabled() {
+				sendJSON(w, http.StatusForbidden, map[string]interface{}{
+					"success": false,
+					"error":   "Admin accounts must enable two-factor authentication (/api/auth/2fa/setup)",
+				})
+				return
+			}
+
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+// can checks if a user's role grants a permission
+func (s *Server) can(user *models.User, permission string) bool {
+	return user.IsAdmin() || s.roles.HasPermission(user.Role, permission)
+}
+
+// ============================================================================
+// HELPER FUNCTIONS
+// ============================================================================
+
+// resolveActor returns the peer an authenticated request acts as
+// Users act as one of their bound peers: requestedID picks which one, and
+// an empty requestedID means the first bound peer. Admins may act as any
+// peer. Banned peers can't act at all. Must be called behind authMiddleware.
+// Parameters:
+//   - req: The request carrying the caller's claims
+//   - requestedID: Client-supplied peer ID, or "" for the default
+//
+// Returns:
+//   - string: The peer ID to act as
+//   - error: If the caller may not act as the requested peer
+func (s *Server) resolveActor(req *http.Request, requestedID string) (string, error) {
+	user, ok := auth.UserFromContext(req.Context())
+	if !ok {
+		return "", errors.New("Authentication required")
+	}
+
+	if requestedID == "" {
+		if len(user.PeerIDs) == 0 {
+			return "", errors.New("No peer bound to this account; register a peer first")
+		}
+		requestedID = user.PeerIDs[0]
+	} else if !user.IsAdmin() && !user.HasPeer(requestedID) {
+		return "", errors.New("Cannot act as a peer not bound to this account")
+	}
+
+	if s.moderation.IsPeerBanned(requestedID) {
+		return "", errors.New("This peer has been banned")
+	}
+	return requestedID, nil
+}
+
+// authData builds the response body for a login or refresh
+func (s *Server) authData(user *models.User, tokens *auth.TokenPair) *AuthData {
+	return &AuthData{
+		User:         s.publicUser(user),
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+	}
+}
+
+// publicUser converts a user for display, reading reputation and counts
+// from the user's bound peers and permissions from their role
+func (s *Server) publicUser(user *models.User) models.PublicUser {
+	public := user.ToPublic()
+	public.Permissions = []string{}
+	if role, ok := s.roles.Get(user.Role); ok {
+		public.Permissions = role.Permissions
+	}
+	standing := s.reputationService.GetStanding(public.PeerIDs...)
+	public.Reputation = standing.Reputation
+	public.TotalUploads = standing.TotalUploads
+	public.TotalDownloads = standing.TotalDownloads
+	return public
+}
+
 // sendJSON sends a JSON response
 func sendJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
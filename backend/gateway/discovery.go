@@ -16,9 +16,9 @@ package gateway
 
 import (
 	"encoding/json"
-	"fmt"
 	"log"
 	"net"
+	"strconv"
 	"sync"
 	"time"
 
@@ -81,6 +81,12 @@ type Discovery struct {
 	// State
 	isRunning bool
 	localPeer *models.Student
+
+	// isBanned rejects banned peers (nil allows everyone)
+	isBanned func(peerID string) bool
+
+	// syncPeer sets a newly discovered peer's reputation (nil leaves it unset)
+	syncPeer func(peer *models.Student)
 }
 
 // DiscoveryEvent represents a discovery event
@@ -97,6 +103,7 @@ const (
 	EventPeerLeft    = "PEER_LEFT"
 	EventPeerTimeout = "PEER_TIMEOUT"
 	EventPeerUpdated = "PEER_UPDATED"
+	EventPeerKicked  = "PEER_KICKED"
 )
 
 // ============================================================================
@@ -164,6 +171,21 @@ func (d *Discovery) SetLocalPeer(peer *models.Student) {
 	d.localPeer = peer
 }
 
+// SetBanCheck sets the function used to reject banned peers
+func (d *Discovery) SetBanCheck(isBanned func(peerID string) bool) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.isBanned = isBanned
+}
+
+// SetPeerSync sets the function that gives a newly discovered peer the
+// reputation it has earned so far
+func (d *Discovery) SetPeerSync(syncPeer func(peer *models.Student)) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.syncPeer = syncPeer
+}
+
 // ============================================================================
 // PEER DISCOVERY
 // ============================================================================
@@ -187,6 +209,10 @@ func (d *Discovery) RegisterPeer(msg *DiscoveryMessage) {
 	d.mutex.Lock()
 	defer d.mutex.Unlock()
 
+	if d.isBanned != nil && d.isBanned(msg.PeerID) {
+		return
+	}
+
 	// Check if already known
 	_, exists := d.knownPeers[msg.PeerID]
 
@@ -200,6 +226,9 @@ func (d *Discovery) RegisterPeer(msg *DiscoveryMessage) {
 		// Create new peer
 		peer = models.NewStudent(msg.PeerID, msg.PeerName, msg.Address, msg.Port)
 		d.peerRegistry.Register(peer)
+		if d.syncPeer != nil {
+			d.syncPeer(peer)
+		}
 
 		// Emit join event
 		d.eventChan <- DiscoveryEvent{
@@ -235,6 +264,30 @@ func (d *Discovery) HandleLeave(peerID string) {
 	}
 }
 
+// RemovePeer drops a peer from the network (used when an admin kicks or
+// bans it); unlike a leave, the peer is removed from the registry
+func (d *Discovery) RemovePeer(peerID string) bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	delete(d.knownPeers, peerID)
+
+	peer, exists := d.peerRegistry.Get(peerID)
+	if !exists {
+		return false
+	}
+	d.peerRegistry.Unregister(peerID)
+
+	if d.isRunning {
+		d.eventChan <- DiscoveryEvent{
+			Type:   EventPeerKicked,
+			PeerID: peerID,
+			Peer:   peer,
+		}
+	}
+	return true
+}
+
 // ============================================================================
 // HEARTBEAT
 // ============================================================================
@@ -275,7 +328,7 @@ func (d *Discovery) pingPeer(peerID string) {
 		return
 	}
 
-	address := fmt.Sprintf("%s:%d", peer.IPAddress, peer.Port)
+	address := net.JoinHostPort(peer.IPAddress, strconv.Itoa(peer.Port))
 
 	// Try to connect
 	conn, err := net.DialTimeout("tcp", address, 5*time.Second)
@@ -413,7 +466,7 @@ func (d *Discovery) broadcastMessage(msg *DiscoveryMessage) error {
 
 	for _, peer := range peers {
 		go func(p *models.Student) {
-			address := fmt.Sprintf("%s:%d", p.IPAddress, p.Port)
+			address := net.JoinHostPort(p.IPAddress, strconv.Itoa(p.Port))
 			conn, err := net.DialTimeout("tcp", address, 5*time.Second)
 			if err != nil {
 				return
@@ -0,0 +1,143 @@
+/*
+================================================================================
+TRUST GOSSIP - P2P Academic Library "The Knowledge Exchange"
+================================================================================
+This file periodically exchanges signed trust reports with online peers.
+
+Go Concepts Used:
+- Goroutines: Background gossip and concurrent pushes
+- time.Ticker: Periodic gossip rounds
+- net/http: Pushing reports to peer gateways
+- JSON: Report encoding
+================================================================================
+*/
+
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"knowledge-exchange/analytics"
+	"knowledge-exchange/models"
+)
+
+// ============================================================================
+// TRUST GOSSIP STRUCT
+// ============================================================================
+
+// TrustGossip pushes this node's trust report to online peers and
+// recomputes global trust after each round
+type TrustGossip struct {
+	trust        *analytics.TrustManager
+	peerRegistry *models.PeerRegistry
+	localPeerID  string
+	interval     time.Duration
+	client       *http.Client
+
+	mutex     sync.Mutex
+	stopChan  chan struct{}
+	isRunning bool
+}
+
+// NewTrustGossip creates a new gossip service
+func NewTrustGossip(trust *analytics.TrustManager, peerRegistry *models.PeerRegistry, localPeerID string, interval time.Duration) *TrustGossip {
+	return &TrustGossip{
+		trust:        trust,
+		peerRegistry: peerRegistry,
+		localPeerID:  localPeerID,
+		interval:     interval,
+		client:       &http.Client{Timeout: 10 * time.Second},
+		stopChan:     make(chan struct{}),
+	}
+}
+
+// ============================================================================
+// SERVICE LIFECYCLE
+// ============================================================================
+
+// Start begins periodic gossip rounds
+func (g *TrustGossip) Start() {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.isRunning {
+		return
+	}
+	g.isRunning = true
+
+	go func() {
+		ticker := time.NewTicker(g.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				g.gossipRound()
+			case <-g.stopChan:
+				return
+			}
+		}
+	}()
+
+	log.Println("Trust gossip started")
+}
+
+// Stop stops gossiping
+func (g *TrustGossip) Stop() {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.isRunning {
+		g.isRunning = false
+		close(g.stopChan)
+	}
+}
+
+// ============================================================================
+// GOSSIP ROUND
+// ============================================================================
+
+// gossipRound signs a fresh report, pushes it to online peers and
+// recomputes global trust from everything received so far
+func (g *TrustGossip) gossipRound() {
+	report, err := g.trust.LocalReport()
+	if err != nil {
+		log.Printf("Trust gossip: failed to build report: %v", err)
+		return
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		log.Printf("Trust gossip: failed to encode report: %v", err)
+		return
+	}
+
+	for _, peer := range g.peerRegistry.GetOnlinePeers() {
+		if peer.ID == g.localPeerID || peer.IPAddress == "" || peer.Port == 0 {
+			continue
+		}
+		go g.push(peer, data)
+	}
+
+	g.trust.Recompute()
+}
+
+// push sends a report to a single peer's gossip endpoint
+func (g *TrustGossip) push(peer *models.Student, data []byte) {
+	address := net.JoinHostPort(peer.IPAddress, strconv.Itoa(peer.Port))
+	url := fmt.Sprintf("http://%s/api/trust/gossip", address)
+
+	resp, err := g.client.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,346 @@
+/*
+================================================================================
+MODERATION HANDLERS - P2P Academic Library "The Knowledge Exchange"
+================================================================================
+This file implements pinned course material and the report queue.
+
+Instructors pin files as official material for a course; pins are shown
+to everyone and flagged on file listings. Members report files or peers,
+and moderators work the queue by dismissing a report or acting on it
+(removing the file or banning the peer), which needs the matching
+permission as well.
+
+Go Concepts Used:
+- HTTP handlers: Request/response handling
+- JSON encoding/decoding
+- Error handling
+================================================================================
+*/
+
+package gateway
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"knowledge-exchange/auth"
+	"knowledge-exchange/models"
+)
+
+// MaxReportReasonLength caps the text of a report or a moderator's note
+const MaxReportReasonLength = 1000
+
+// ============================================================================
+// REQUEST/RESPONSE TYPES
+// ============================================================================
+
+// PinRequest pins or unpins a file
+type PinRequest struct {
+	CID  string `json:"cid"`
+	Note string `json:"note,omitempty"` // e.g. "CS101 week 3 reading"
+}
+
+// PinnedFileInfo is a pinned file with who pinned it and why
+type PinnedFileInfo struct {
+	FileInfo
+	Note     string    `json:"note"`
+	PinnedBy string    `json:"pinned_by"` // Username
+	PinnedAt time.Time `json:"pinned_at"`
+}
+
+// CreateReportRequest reports a file or a peer
+type CreateReportRequest struct {
+	TargetType string `json:"target_type"` // "file" or "peer"
+	TargetID   string `json:"target_id"`   // File CID or peer ID
+	Reason     string `json:"reason"`
+}
+
+// ResolveReportRequest closes a report
+type ResolveReportRequest struct {
+	ID     string `json:"id"`
+	Action string `json:"action"` // "dismiss", "remove_file" or "ban_peer"
+	Note   string `json:"note,omitempty"`
+}
+
+// ============================================================================
+// PINNED FILES
+// ============================================================================
+
+// pinnedFilesHandler lists pinned files that are still in the index,
+// newest pin first
+func (r *Router) pinnedFilesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		pins := r.server.moderation.ListPinnedFiles()
+
+		files := make([]PinnedFileInfo, 0, len(pins))
+		for _, pin := range pins {
+			f, ok := r.server.indexer.GetFile(pin.ID)
+			if !ok {
+				if f, ok = r.server.fileIndex.Get(pin.ID); !ok {
+					continue
+				}
+			}
+
+			files = append(files, PinnedFileInfo{
+				FileInfo: FileInfo{
+					CID:        f.CID,
+					Name:       f.FileName,
+					Size:       f.Size,
+					Type:       f.FileType,
+					Subject:    f.Subject,
+					OwnerID:    f.OwnerID,
+					Downloads:  f.DownloadCount,
+					Rating:     f.AverageRating,
+					Available:  f.IsAvailable,
+					UploadedAt: f.UploadTime,
+					Pinned:     true,
+				},
+				Note:     pin.Reason,
+				PinnedBy: r.server.usernameOf(pin.By),
+				PinnedAt: pin.CreatedAt,
+			})
+		}
+
+		r.server.sendJSON(w, http.StatusOK, APIResponse{
+			Success: true,
+			Data:    files,
+		})
+	}
+}
+
+// pinFileHandler pins a file as official material; pinning it again
+// updates the note
+func (r *Router) pinFileHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var body PinRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.CID == "" {
+			r.server.sendError(w, http.StatusBadRequest, "File CID required")
+			return
+		}
+
+		if !r.server.fileExists(body.CID) {
+			r.server.sendError(w, http.StatusNotFound, "File not found")
+			return
+		}
+
+		staff, _ := auth.UserFromContext(req.Context())
+		if err := r.server.moderation.PinFile(body.CID, strings.TrimSpace(body.Note), staff.ID); err != nil {
+			r.server.sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		log.Printf("%s %s pinned file %s: %s", staff.Role, staff.Username, body.CID, body.Note)
+
+		r.server.sendJSON(w, http.StatusOK, APIResponse{
+			Success: true,
+			Message: "File pinned",
+		})
+	}
+}
+
+// unpinFileHandler removes a file's pin
+func (r *Router) unpinFileHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var body PinRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.CID == "" {
+			r.server.sendError(w, http.StatusBadRequest, "File CID required")
+			return
+		}
+
+		if err := r.server.moderation.UnpinFile(body.CID); err != nil {
+			r.server.sendError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		staff, _ := auth.UserFromContext(req.Context())
+		log.Printf("%s %s unpinned file %s", staff.Role, staff.Username, body.CID)
+
+		r.server.sendJSON(w, http.StatusOK, APIResponse{
+			Success: true,
+			Message: "File unpinned",
+		})
+	}
+}
+
+// isPinned checks if a file is pinned as official material
+func (s *Server) isPinned(cid string) bool {
+	_, pinned := s.moderation.GetPin(cid)
+	return pinned
+}
+
+// ============================================================================
+// REPORTS
+// ============================================================================
+
+// createReportHandler files a report of a file or peer for moderators
+func (r *Router) createReportHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var body CreateReportRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			r.server.sendError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		body.Reason = strings.TrimSpace(body.Reason)
+		if body.TargetID == "" || body.Reason == "" {
+			r.server.sendError(w, http.StatusBadRequest, "Target and reason required")
+			return
+		}
+		if len(body.Reason) > MaxReportReasonLength {
+			r.server.sendError(w, http.StatusBadRequest, "Reason is too long")
+			return
+		}
+
+		switch body.TargetType {
+		case models.ReportTargetFile:
+			if !r.server.fileExists(body.TargetID) {
+				r.server.sendError(w, http.StatusNotFound, "File not found")
+				return
+			}
+		case models.ReportTargetPeer:
+			if _, ok := r.server.peerRegistry.Get(body.TargetID); !ok {
+				r.server.sendError(w, http.StatusNotFound, "Peer not found")
+				return
+			}
+		default:
+			r.server.sendError(w, http.StatusBadRequest, "Target type must be file or peer")
+			return
+		}
+
+		user, _ := auth.UserFromContext(req.Context())
+		report, err := r.server.reports.Create(models.Report{
+			TargetType: body.TargetType,
+			TargetID:   body.TargetID,
+			Reason:     body.Reason,
+			ReporterID: user.ID,
+		})
+		if err != nil {
+			r.server.sendError(w, http.StatusConflict, err.Error())
+			return
+		}
+
+		r.server.sendJSON(w, http.StatusCreated, APIResponse{
+			Success: true,
+			Message: "Report submitted; a moderator will review it",
+			Data:    report,
+		})
+	}
+}
+
+// listReportsHandler lists reports, oldest first
+// Query: ?status=open (default), resolved, dismissed or all
+func (r *Router) listReportsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		status := req.URL.Query().Get("status")
+		switch status {
+		case "":
+			status = models.ReportOpen
+		case "all":
+			status = ""
+		case models.ReportOpen, models.ReportResolved, models.ReportDismissed:
+		default:
+			r.server.sendError(w, http.StatusBadRequest, "Unknown report status")
+			return
+		}
+
+		r.server.sendJSON(w, http.StatusOK, APIResponse{
+			Success: true,
+			Data:    r.server.reports.List(status),
+		})
+	}
+}
+
+// resolveReportHandler closes a report, carrying out its action first
+// Removing a file or banning a peer needs that permission too, so a
+// moderator role without it can only dismiss
+func (r *Router) resolveReportHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var body ResolveReportRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.ID == "" {
+			r.server.sendError(w, http.StatusBadRequest, "Report ID required")
+			return
+		}
+		body.Note = strings.TrimSpace(body.Note)
+		if len(body.Note) > MaxReportReasonLength {
+			r.server.sendError(w, http.StatusBadRequest, "Note is too long")
+			return
+		}
+
+		report, ok := r.server.reports.Get(body.ID)
+		if !ok {
+			r.server.sendError(w, http.StatusNotFound, "Report not found")
+			return
+		}
+		if !report.IsOpen() {
+			r.server.sendError(w, http.StatusConflict, "Report was already handled")
+			return
+		}
+
+		staff, _ := auth.UserFromContext(req.Context())
+		reason := "report " + report.ID + ": " + report.Reason
+		status := models.ReportResolved
+
+		switch body.Action {
+		case models.ReportActionDismiss:
+			status = models.ReportDismissed
+
+		case models.ReportActionRemoveFile:
+			if report.TargetType != models.ReportTargetFile {
+				r.server.sendError(w, http.StatusBadRequest, "Only a file report can remove a file")
+				return
+			}
+			if !r.server.can(staff, models.PermRemoveFiles) {
+				r.server.sendError(w, http.StatusForbidden, "Your role can't remove files")
+				return
+			}
+			if err := r.server.removeFile(report.TargetID, reason, staff); err != nil {
+				r.server.sendError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+
+		case models.ReportActionBanPeer:
+			if report.TargetType != models.ReportTargetPeer {
+				r.server.sendError(w, http.StatusBadRequest, "Only a peer report can ban a peer")
+				return
+			}
+			if !r.server.can(staff, models.PermBanPeers) {
+				r.server.sendError(w, http.StatusForbidden, "Your role can't ban peers")
+				return
+			}
+			if err := r.server.banPeer(report.TargetID, reason, staff); err != nil {
+				r.server.sendError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+
+		default:
+			r.server.sendError(w, http.StatusBadRequest, "Action must be dismiss, remove_file or ban_peer")
+			return
+		}
+
+		report, err := r.server.reports.Resolve(report.ID, status, body.Action, body.Note, staff.ID)
+		if err != nil {
+			r.server.sendError(w, http.StatusConflict, err.Error())
+			return
+		}
+
+		log.Printf("%s %s %s report %s (%s)", staff.Role, staff.Username, status, report.ID, body.Action)
+
+		r.server.sendJSON(w, http.StatusOK, APIResponse{
+			Success: true,
+			Message: "Report " + status,
+			Data:    report,
+		})
+	}
+}
+
+// usernameOf returns a user's name for display, or "" if they're gone
+func (s *Server) usernameOf(userID string) string {
+	user, err := s.userStore.GetByID(userID)
+	if err != nil {
+		return ""
+	}
+	return user.Username
+}
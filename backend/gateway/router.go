@@ -16,11 +16,16 @@ package gateway
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
 	"log"
+	"mime"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"knowledge-exchange/analytics"
 	"knowledge-exchange/models"
 )
 
@@ -54,40 +59,142 @@ func NewRouter(server *Server) *Router {
 // ============================================================================
 
 // setupRoutes configures all API routes
+// Routes are grouped by who may call them:
+//   - public: anyone; the caller's identity is attached when a token or
+//     API key is sent
+//   - authed: requires a session token (account management; no API keys)
+//   - readFiles, writeFiles, rate: a session token or an API key with
+//     the matching scope, for a role with the matching permission
+//   - report: a session token, for a role allowed to report
+//   - staff(permission): a session token or admin-scoped API key, for a
+//     role with the permission
 func (r *Router) setupRoutes() {
-	// Health and status
-	r.handle("GET", "/api/health", r.healthHandler())
-	r.handle("GET", "/api/status", r.server.HandleStatus)
+	public := r.group(r.identifyMiddleware)
+	authed := r.group(r.authMiddleware)
+	readFiles := r.group(r.scopeMiddleware(models.ScopeReadFiles), r.permissionMiddleware(models.PermDownloadFiles))
+	writeFiles := r.group(r.scopeMiddleware(models.ScopeWriteFiles), r.permissionMiddleware(models.PermUploadFiles))
+	rate := r.group(r.scopeMiddleware(models.ScopeRate), r.permissionMiddleware(models.PermRate))
+	report := r.group(r.authMiddleware, r.permissionMiddleware(models.PermReport))
+	staff := func(permission string) *routeGroup {
+		return r.group(r.scopeMiddleware(models.ScopeAdmin), r.permissionMiddleware(permission))
+	}
 
-	// Authentication (public routes)
-	r.handle("POST", "/api/auth/register", r.registerHandler())
-	r.handle("POST", "/api/auth/login", r.loginHandler())
-	r.handle("POST", "/api/auth/logout", r.logoutHandler())
-	r.handle("GET", "/api/auth/me", r.meHandler())
+	// Health and status
+	public.handle("GET", "/api/health", r.healthHandler())
+	public.handle("GET", "/api/status", r.server.HandleStatus)
+
+	// Authentication
+	public.handle("GET", "/api/auth/setup/status", r.setupStatusHandler())
+	public.handle("POST", "/api/auth/setup", r.setupHandler())
+	public.handle("POST", "/api/auth/register", r.registerHandler())
+	public.handle("POST", "/api/auth/login", r.loginHandler())
+	public.handle("POST", "/api/auth/login/2fa", r.loginTwoFactorHandler())
+	public.handle("GET", "/api/auth/sso", r.ssoProvidersHandler())
+	public.handle("POST", "/api/auth/sso/start", r.ssoStartHandler())
+	public.handle("POST", "/api/auth/sso/callback", r.ssoCallbackHandler())
+	public.handle("POST", "/api/auth/logout", r.logoutHandler())
+	public.handle("POST", "/api/auth/refresh", r.refreshHandler())
+	public.handle("GET", "/api/auth/jwks", r.jwksHandler())
+	public.handle("GET", "/.well-known/jwks.json", r.jwksHandler())
+	authed.handle("GET", "/api/auth/me", r.meHandler())
+	authed.handle("POST", "/api/auth/password", r.changePasswordHandler())
+	public.handle("POST", "/api/auth/password/forgot", r.forgotPasswordHandler())
+	public.handle("POST", "/api/auth/password/reset", r.resetPasswordHandler())
+	authed.handle("POST", "/api/auth/verify/request", r.requestVerificationHandler())
+	public.handle("POST", "/api/auth/verify/confirm", r.confirmVerificationHandler())
+	authed.handle("GET", "/api/auth/2fa", r.twoFactorStatusHandler())
+	authed.handle("POST", "/api/auth/2fa/setup", r.twoFactorSetupHandler())
+	authed.handle("POST", "/api/auth/2fa/enable", r.twoFactorEnableHandler())
+	authed.handle("POST", "/api/auth/2fa/disable", r.twoFactorDisableHandler())
+	authed.handle("POST", "/api/auth/2fa/recovery-codes", r.recoveryCodesHandler())
+	authed.handle("GET", "/api/auth/keys", r.listAPIKeysHandler())
+	authed.handle("POST", "/api/auth/keys/create", r.createAPIKeyHandler())
+	authed.handle("POST", "/api/auth/keys/revoke", r.revokeAPIKeyHandler())
+	authed.handle("POST", "/api/auth/peers/unbind", r.unbindPeerHandler())
 
 	// Peer management
-	r.handle("POST", "/api/peers/register", r.server.HandleRegister)
-	r.handle("GET", "/api/peers", r.server.HandleGetPeers)
-	r.handle("GET", "/api/peers/online", r.onlinePeersHandler())
+	public.handle("POST", "/api/peers/register", r.server.HandleRegister)
+	public.handle("GET", "/api/peers", r.server.HandleGetPeers)
+	public.handle("GET", "/api/peers/online", r.onlinePeersHandler())
 
 	// File operations
-	r.handle("GET", "/api/files", r.server.HandleGetFiles)
-	r.handle("GET", "/api/files/search", r.server.HandleSearch)
-	r.handle("POST", "/api/files/upload", r.uploadHandler())
-	r.handle("GET", "/api/files/download", r.downloadHandler())
+	public.handle("GET", "/api/files", r.server.HandleGetFiles)
+	public.handle("GET", "/api/files/search", r.server.HandleSearch)
+	writeFiles.handle("POST", "/api/files/upload", r.uploadHandler())
+	readFiles.handle("GET", "/api/files/download", r.downloadHandler())
+	public.handle("GET", "/api/files/top", r.topFilesHandler())
+	public.handle("GET", "/api/files/pinned", r.pinnedFilesHandler())
+	staff(models.PermPinFiles).handle("POST", "/api/files/pin", r.pinFileHandler())
+	staff(models.PermPinFiles).handle("POST", "/api/files/unpin", r.unpinFileHandler())
+
+	// Transfers
+	public.handle("GET", "/api/transfers", r.transfersHandler())
 
 	// Reputation
-	r.handle("GET", "/api/reputation", r.server.HandleGetReputation)
-	r.handle("GET", "/api/reputation/history", r.reputationHistoryHandler())
-	r.handle("GET", "/api/reputation/top", r.topContributorsHandler())
+	public.handle("GET", "/api/reputation", r.server.HandleGetReputation)
+	public.handle("GET", "/api/reputation/history", r.reputationHistoryHandler())
+	public.handle("GET", "/api/reputation/top", r.topContributorsHandler())
+	public.handle("GET", "/api/reputation/ledger", r.ledgerHandler())
+	public.handle("GET", "/api/reputation/audit", r.server.HandleReputationAudit)
+
+	// Global trust
+	public.handle("POST", "/api/trust/gossip", r.server.HandleTrustGossip)
+	public.handle("GET", "/api/trust/report", r.server.HandleTrustReport)
+	public.handle("GET", "/api/trust/global", r.server.HandleGlobalTrust)
 
 	// Ratings
-	r.handle("POST", "/api/ratings/file", r.server.HandleRateFile)
-	r.handle("POST", "/api/ratings/peer", r.ratePeerHandler())
-	r.handle("GET", "/api/ratings", r.getRatingsHandler())
+	rate.handle("POST", "/api/ratings/file", r.server.HandleRateFile)
+	rate.handle("POST", "/api/ratings/peer", r.ratePeerHandler())
+	public.handle("GET", "/api/ratings", r.getRatingsHandler())
+	rate.handle("POST", "/api/ratings/update", r.updateRatingHandler())
+	rate.handle("POST", "/api/ratings/delete", r.deleteRatingHandler())
+	public.handle("GET", "/api/ratings/history", r.ratingHistoryHandler())
 
 	// Statistics
-	r.handle("GET", "/api/stats", r.server.HandleGetStats)
+	public.handle("GET", "/api/stats", r.server.HandleGetStats)
+
+	// Reports and moderation
+	report.handle("POST", "/api/reports", r.createReportHandler())
+	staff(models.PermHandleReports).handle("GET", "/api/moderation/reports", r.listReportsHandler())
+	staff(models.PermHandleReports).handle("POST", "/api/moderation/reports/resolve", r.resolveReportHandler())
+
+	// Admin: users
+	users := staff(models.PermManageUsers)
+	users.handle("GET", "/api/admin/users", r.listUsersHandler())
+	users.handle("POST", "/api/admin/users/deactivate", r.setActiveHandler(false))
+	users.handle("POST", "/api/admin/users/reactivate", r.setActiveHandler(true))
+	users.handle("POST", "/api/admin/users/revoke-sessions", r.revokeSessionsHandler())
+	users.handle("POST", "/api/admin/users/2fa/reset", r.resetTwoFactorHandler())
+	users.handle("GET", "/api/admin/auth/lockouts", r.lockoutsHandler())
+	users.handle("POST", "/api/admin/auth/unlock", r.unlockHandler())
+
+	// Admin: roles and permissions
+	roles := staff(models.PermManageRoles)
+	roles.handle("POST", "/api/admin/users/role", r.setRoleHandler())
+	roles.handle("GET", "/api/admin/roles", r.listRolesHandler())
+	roles.handle("POST", "/api/admin/roles/save", r.saveRoleHandler())
+	roles.handle("POST", "/api/admin/roles/delete", r.deleteRoleHandler())
+
+	// Admin: files and peers
+	staff(models.PermRemoveFiles).handle("POST", "/api/admin/files/remove", r.removeFileHandler())
+	staff(models.PermRemoveFiles).handle("GET", "/api/admin/files/removed", r.removedFilesHandler())
+	peers := staff(models.PermBanPeers)
+	peers.handle("POST", "/api/admin/peers/kick", r.kickPeerHandler())
+	peers.handle("POST", "/api/admin/peers/ban", r.banPeerHandler())
+	peers.handle("POST", "/api/admin/peers/unban", r.unbanPeerHandler())
+	peers.handle("GET", "/api/admin/peers/bans", r.bansHandler())
+
+	// Admin: signing keys, rating abuse and reputation policy
+	admin := staff(models.PermManageSystem)
+	admin.handle("GET", "/api/admin/auth/keys", r.listKeysHandler())
+	admin.handle("POST", "/api/admin/auth/keys/rotate", r.rotateKeyHandler())
+	admin.handle("GET", "/api/admin/sybil/clusters", r.sybilClustersHandler())
+	admin.handle("POST", "/api/admin/sybil/scan", r.sybilScanHandler())
+	admin.handle("GET", "/api/admin/reputation/policy", r.server.HandleGetPolicy)
+	admin.handle("POST", "/api/admin/reputation/policy/update", r.server.HandleUpdatePolicy)
+	admin.handle("POST", "/api/admin/reputation/recompute", r.server.HandleRecomputeReputation)
+	admin.handle("POST", "/api/admin/reputation/compact", r.server.HandleCompactReputation)
+	admin.handle("POST", "/api/admin/reputation/adjust", r.adjustReputationHandler())
 
 	// Static files (for frontend)
 	r.mux.Handle("/", http.FileServer(http.Dir("../frontend")))
@@ -105,7 +212,7 @@ func (r *Router) handle(method, pattern string, handler http.HandlerFunc) {
 			if req.Method == "OPTIONS" {
 				w.Header().Set("Access-Control-Allow-Origin", "*")
 				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
 				w.WriteHeader(http.StatusOK)
 				return
 			}
@@ -122,6 +229,33 @@ func (r *Router) GetHandler() http.Handler {
 	return r.mux
 }
 
+// ============================================================================
+// ROUTE GROUPS
+// ============================================================================
+
+// Middleware wraps a handler with extra behaviour
+type Middleware func(http.Handler) http.Handler
+
+// routeGroup registers routes that share a middleware chain
+type routeGroup struct {
+	router     *Router
+	middleware []Middleware
+}
+
+// group creates a route group; middleware runs in the order given
+func (r *Router) group(middleware ...Middleware) *routeGroup {
+	return &routeGroup{router: r, middleware: middleware}
+}
+
+// handle registers a route wrapped in the group's middleware
+func (g *routeGroup) handle(method, pattern string, handler http.HandlerFunc) {
+	h := http.Handler(handler)
+	for i := len(g.middleware) - 1; i >= 0; i-- {
+		h = g.middleware[i](h)
+	}
+	g.router.handle(method, pattern, h.ServeHTTP)
+}
+
 // ============================================================================
 // MIDDLEWARE
 // ============================================================================
@@ -163,7 +297,7 @@ func (r *Router) corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
 
 		if req.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
@@ -223,8 +357,8 @@ func (r *Router) onlinePeersHandler() http.HandlerFunc {
 			peerInfos[i] = PeerInfo{
 				ID:         p.ID,
 				Name:       p.Name,
-				Reputation: p.ReputationScore,
-				IsOnline:   p.IsOnline,
+				Reputation: p.Reputation(),
+				IsOnline:   p.Online(),
 			}
 		}
 
@@ -245,10 +379,10 @@ func (r *Router) uploadHandler() http.HandlerFunc {
 			return
 		}
 
-		// Get uploader ID
-		ownerID := req.FormValue("owner_id")
-		if ownerID == "" {
-			r.server.sendError(w, http.StatusBadRequest, "Owner ID required")
+		// Uploader is the caller's peer (owner_id only selects which one)
+		ownerID, err := r.server.resolveActor(req, req.FormValue("owner_id"))
+		if err != nil {
+			r.server.sendError(w, http.StatusForbidden, err.Error())
 			return
 		}
 
@@ -262,7 +396,7 @@ func (r *Router) uploadHandler() http.HandlerFunc {
 
 		// Read file content
 		content := make([]byte, header.Size)
-		_, err = file.Read(content)
+		_, err = io.ReadFull(file, content)
 		if err != nil {
 			r.server.sendError(w, http.StatusInternalServerError, "Failed to read file")
 			return
@@ -277,12 +411,21 @@ func (r *Router) uploadHandler() http.HandlerFunc {
 
 		// Create academic file
 		academicFile := models.NewAcademicFile(header.Filename, ownerID, header.Size, ext, content)
+		if r.server.moderation.IsFileRemoved(academicFile.CID) {
+			r.server.sendError(w, http.StatusForbidden, "This file has been removed by an administrator")
+			return
+		}
 
-		// Add to index
-		r.server.GetFileIndex().Add(academicFile)
+		// Keep the content so this node can serve downloads of it
+		err = r.server.indexer.StoreContent(r.server.uploadsDir(), academicFile.CID, ext, content)
+		if err != nil {
+			log.Printf("Failed to store upload %s: %v", academicFile.CID, err)
+			r.server.sendError(w, http.StatusInternalServerError, "Failed to store file")
+			return
+		}
 
-		// Record upload for reputation
-		r.server.GetReputationService().RecordUpload(ownerID)
+		// Add to index; reputation is earned when peers download it
+		r.server.GetFileIndex().Add(academicFile)
 
 		r.server.sendJSON(w, http.StatusCreated, APIResponse{
 			Success: true,
@@ -300,10 +443,14 @@ func (r *Router) uploadHandler() http.HandlerFunc {
 func (r *Router) downloadHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
 		cid := req.URL.Query().Get("cid")
-		requesterID := req.URL.Query().Get("requester_id")
+		if cid == "" {
+			r.server.sendError(w, http.StatusBadRequest, "CID required")
+			return
+		}
 
-		if cid == "" || requesterID == "" {
-			r.server.sendError(w, http.StatusBadRequest, "CID and requester_id required")
+		requesterID, err := r.server.resolveActor(req, req.URL.Query().Get("requester_id"))
+		if err != nil {
+			r.server.sendError(w, http.StatusForbidden, err.Error())
 			return
 		}
 
@@ -321,18 +468,60 @@ func (r *Router) downloadHandler() http.HandlerFunc {
 			return
 		}
 
-		// Record download
-		r.server.GetReputationService().RecordDownload(requesterID)
+		// Files this node doesn't hold are fetched from the peers that do
+		if _, held := r.server.indexer.GetLocalFilePath(file.CID); !held {
+			r.server.sendJSON(w, http.StatusOK, APIResponse{
+				Success: true,
+				Message: "Download initiated",
+				Data: map[string]interface{}{
+					"cid":       file.CID,
+					"file_name": file.FileName,
+					"size":      file.Size,
+					"owner":     file.OwnerID,
+				},
+			})
+			return
+		}
+
+		// Serve the bytes; once they all match the checksum the requester's
+		// peer holds a verified copy it may rate, and reputation moves
+		// with the bytes
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": file.FileName}))
+		w.Header().Set("Content-Length", strconv.FormatInt(file.Size, 10))
+		if err := r.server.transferManager.ServeDownload(w, file, requesterID); err != nil {
+			// Headers are sent; the short body tells the client it failed
+			log.Printf("Download of %s by %s failed: %v", file.CID, requesterID, err)
+			return
+		}
 		file.RecordDownload()
+	}
+}
+
+// transfersHandler returns active transfers with speed/ETA and peer throughput
+func (r *Router) transfersHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		tm := r.server.GetTransferManager()
+
+		// Single peer history when requested
+		if peerID := req.URL.Query().Get("peer_id"); peerID != "" {
+			throughput, exists := tm.GetPeerThroughput(peerID)
+			if !exists {
+				r.server.sendError(w, http.StatusNotFound, "No transfer history for peer")
+				return
+			}
+			r.server.sendJSON(w, http.StatusOK, APIResponse{
+				Success: true,
+				Data:    throughput,
+			})
+			return
+		}
 
 		r.server.sendJSON(w, http.StatusOK, APIResponse{
 			Success: true,
-			Message: "Download initiated",
 			Data: map[string]interface{}{
-				"cid":       file.CID,
-				"file_name": file.FileName,
-				"size":      file.Size,
-				"owner":     file.OwnerID,
+				"active": tm.GetActiveTransfers(),
+				"peers":  tm.GetAllPeerThroughput(),
 			},
 		})
 	}
@@ -355,25 +544,179 @@ func (r *Router) reputationHistoryHandler() http.HandlerFunc {
 	}
 }
 
-// topContributorsHandler returns top contributors
+// topContributorsHandler returns a page of ranked contributors
+// Query: method, page, page_size, prior_weight, prior_mean, half_life_days
 func (r *Router) topContributorsHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
-		contributors := r.server.GetReputationService().GetTopContributors(10)
+		opts, page, pageSize, err := parseRankingQuery(req, analytics.DefaultContributorRankingOptions())
+		if err != nil {
+			r.server.sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 
-		peerInfos := make([]PeerInfo, len(contributors))
-		for i, p := range contributors {
-			peerInfos[i] = PeerInfo{
-				ID:         p.ID,
-				Name:       p.Name,
-				Reputation: p.ReputationScore,
-				Uploads:    p.TotalUploads,
-				Downloads:  p.TotalDownloads,
+		ranked, err := r.server.GetRatingService().RankContributors(r.server.GetPeerRegistry(), opts)
+		if err != nil {
+			r.server.sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		start, end, pageSize := analytics.PageBounds(len(ranked), page, pageSize)
+		items := make([]RankedPeerInfo, 0, end-start)
+		for _, c := range ranked[start:end] {
+			p := c.Student
+			uploads, downloads := p.Activity()
+			items = append(items, RankedPeerInfo{
+				PeerInfo: PeerInfo{
+					ID:         p.ID,
+					Name:       p.Name,
+					Reputation: p.Reputation(),
+					IsOnline:   p.Online(),
+					Uploads:    uploads,
+					Downloads:  downloads,
+				},
+				Score:       c.Score,
+				RatingCount: c.Ratings,
+			})
+		}
+
+		r.server.sendJSON(w, http.StatusOK, APIResponse{
+			Success: true,
+			Data: PageInfo{
+				Items:    items,
+				Page:     page,
+				PageSize: pageSize,
+				Total:    len(ranked),
+				Method:   opts.Method,
+			},
+		})
+	}
+}
+
+// topFilesHandler returns a page of ranked files
+// Query: method, page, page_size, prior_weight, prior_mean, half_life_days
+func (r *Router) topFilesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		opts, page, pageSize, err := parseRankingQuery(req, analytics.DefaultFileRankingOptions())
+		if err != nil {
+			r.server.sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		ranked, err := r.server.GetRatingService().RankFiles(r.server.GetFileIndex(), opts)
+		if err != nil {
+			r.server.sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		start, end, pageSize := analytics.PageBounds(len(ranked), page, pageSize)
+		items := make([]RankedFileInfo, 0, end-start)
+		for _, rf := range ranked[start:end] {
+			f := rf.File
+			items = append(items, RankedFileInfo{
+				FileInfo: FileInfo{
+					CID:        f.CID,
+					Name:       f.FileName,
+					Size:       f.Size,
+					Type:       f.FileType,
+					Subject:    f.Subject,
+					OwnerID:    f.OwnerID,
+					Downloads:  f.DownloadCount,
+					Rating:     f.AverageRating,
+					Available:  f.IsAvailable,
+					UploadedAt: f.UploadTime,
+					Pinned:     r.server.isPinned(f.CID),
+				},
+				Score:       rf.Score,
+				RatingCount: rf.Ratings,
+			})
+		}
+
+		r.server.sendJSON(w, http.StatusOK, APIResponse{
+			Success: true,
+			Data: PageInfo{
+				Items:    items,
+				Page:     page,
+				PageSize: pageSize,
+				Total:    len(ranked),
+				Method:   opts.Method,
+			},
+		})
+	}
+}
+
+// parseRankingQuery reads ranking options and pagination from the query,
+// starting from the endpoint's defaults
+func parseRankingQuery(req *http.Request, opts analytics.RankingOptions) (analytics.RankingOptions, int, int, error) {
+	query := req.URL.Query()
+
+	if method := query.Get("method"); method != "" {
+		opts.Method = method
+	}
+
+	floatParams := map[string]*float64{
+		"prior_weight": &opts.PriorWeight,
+		"prior_mean":   &opts.PriorMean,
+	}
+	for name, target := range floatParams {
+		if raw := query.Get(name); raw != "" {
+			value, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				return opts, 0, 0, fmt.Errorf("invalid %s", name)
 			}
+			*target = value
+		}
+	}
+
+	if raw := query.Get("half_life_days"); raw != "" {
+		days, err := strconv.ParseFloat(raw, 64)
+		if err != nil || days < 0 {
+			return opts, 0, 0, fmt.Errorf("invalid half_life_days")
+		}
+		opts.HalfLife = time.Duration(days * float64(24*time.Hour))
+	}
+
+	page, pageSize := 1, analytics.DefaultPageSize
+	if raw := query.Get("page"); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 1 {
+			return opts, 0, 0, fmt.Errorf("invalid page")
+		}
+		page = value
+	}
+	if raw := query.Get("page_size"); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 1 {
+			return opts, 0, 0, fmt.Errorf("invalid page_size")
+		}
+		pageSize = value
+	}
+
+	if err := opts.Validate(); err != nil {
+		return opts, 0, 0, err
+	}
+	return opts, page, pageSize, nil
+}
+
+// ledgerHandler returns bandwidth accounting for a peer, or all peers
+func (r *Router) ledgerHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		ledger := r.server.GetReputationService().GetLedger()
+
+		peerID := req.URL.Query().Get("peer_id")
+		if peerID == "" {
+			r.server.sendJSON(w, http.StatusOK, APIResponse{
+				Success: true,
+				Data:    ledger.GetAllTotals(),
+			})
+			return
 		}
 
 		r.server.sendJSON(w, http.StatusOK, APIResponse{
 			Success: true,
-			Data:    peerInfos,
+			Data: map[string]interface{}{
+				"totals":         ledger.GetTotals(peerID),
+				"counterparties": ledger.GetCounterparties(peerID),
+			},
 		})
 	}
 }
@@ -393,8 +736,14 @@ func (r *Router) ratePeerHandler() http.HandlerFunc {
 			return
 		}
 
+		raterID, err := r.server.resolveActor(req, body.RaterID)
+		if err != nil {
+			r.server.sendError(w, http.StatusForbidden, err.Error())
+			return
+		}
+
 		rating, err := r.server.GetRatingService().RatePeer(
-			body.RaterID, body.TargetID, body.Score, body.Comment,
+			raterID, body.TargetID, body.Score, body.Comment,
 		)
 		if err != nil {
 			r.server.sendError(w, http.StatusBadRequest, err.Error())
@@ -409,6 +758,124 @@ func (r *Router) ratePeerHandler() http.HandlerFunc {
 	}
 }
 
+// updateRatingHandler edits an existing rating
+func (r *Router) updateRatingHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var body struct {
+			RaterID  string  `json:"rater_id"`
+			RatingID string  `json:"rating_id"`
+			Score    float64 `json:"score"`
+			Comment  string  `json:"comment"`
+		}
+
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			r.server.sendError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		raterID, err := r.server.resolveActor(req, body.RaterID)
+		if err != nil {
+			r.server.sendError(w, http.StatusForbidden, err.Error())
+			return
+		}
+
+		rating, err := r.server.GetRatingService().UpdateRating(
+			raterID, body.RatingID, body.Score, body.Comment,
+		)
+		if err != nil {
+			r.server.sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		r.server.sendJSON(w, http.StatusOK, APIResponse{
+			Success: true,
+			Message: "Rating updated successfully",
+			Data:    rating,
+		})
+	}
+}
+
+// deleteRatingHandler retracts a rating
+func (r *Router) deleteRatingHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var body struct {
+			RaterID  string `json:"rater_id"`
+			RatingID string `json:"rating_id"`
+		}
+
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			r.server.sendError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		raterID, err := r.server.resolveActor(req, body.RaterID)
+		if err != nil {
+			r.server.sendError(w, http.StatusForbidden, err.Error())
+			return
+		}
+
+		if err := r.server.GetRatingService().DeleteRating(raterID, body.RatingID); err != nil {
+			r.server.sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		r.server.sendJSON(w, http.StatusOK, APIResponse{
+			Success: true,
+			Message: "Rating deleted successfully",
+		})
+	}
+}
+
+// ratingHistoryHandler returns the audit trail of a rating
+func (r *Router) ratingHistoryHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		ratingID := req.URL.Query().Get("rating_id")
+		if ratingID == "" {
+			r.server.sendError(w, http.StatusBadRequest, "Rating ID required")
+			return
+		}
+
+		history := r.server.GetRatingService().GetRatingHistory(ratingID)
+		if len(history) == 0 {
+			r.server.sendError(w, http.StatusNotFound, "Rating not found")
+			return
+		}
+
+		r.server.sendJSON(w, http.StatusOK, APIResponse{
+			Success: true,
+			Data:    history,
+		})
+	}
+}
+
+// sybilClustersHandler returns clusters flagged by the last analysis
+func (r *Router) sybilClustersHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		detector := r.server.GetSybilDetector()
+
+		r.server.sendJSON(w, http.StatusOK, APIResponse{
+			Success: true,
+			Data: map[string]interface{}{
+				"clusters": detector.GetClusters(),
+				"stats":    detector.GetStats(),
+			},
+		})
+	}
+}
+
+// sybilScanHandler runs the rating analysis immediately
+func (r *Router) sybilScanHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		clusters := r.server.GetSybilDetector().Analyze()
+
+		r.server.sendJSON(w, http.StatusOK, APIResponse{
+			Success: true,
+			Message: "Rating analysis complete",
+			Data:    clusters,
+		})
+	}
+}
+
 // getRatingsHandler returns ratings for a target
 func (r *Router) getRatingsHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
@@ -0,0 +1,234 @@
+/*
+================================================================================
+GATEWAY ROUTER TESTS - P2P Academic Library "The Knowledge Exchange"
+================================================================================
+Drives the HTTP API end to end: accounts with bound peers upload, download
+and rate files through the gateway the way the frontend does.
+================================================================================
+*/
+
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"knowledge-exchange/auth"
+	"knowledge-exchange/utils"
+)
+
+// ============================================================================
+// HELPERS
+// ============================================================================
+
+// newTestGateway starts a gateway on a loopback server with its data in a
+// temporary directory
+func newTestGateway(t *testing.T) (*Server, *httptest.Server) {
+	t.Helper()
+
+	config := utils.DefaultConfig()
+	config.PeerID = "gateway-peer"
+	config.DataDir = t.TempDir()
+	config.SharedFilesDir = t.TempDir()
+
+	server := NewServer(config)
+	ts := httptest.NewServer(server.router.GetHandler())
+	t.Cleanup(ts.Close)
+	return server, ts
+}
+
+// call sends a request with an optional bearer token
+func call(t *testing.T, method, url, token, contentType string, body io.Reader) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+// callJSON sends a JSON body and decodes the JSON reply into out
+func callJSON(t *testing.T, method, url, token string, body, out interface{}) int {
+	t.Helper()
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("encode body: %v", err)
+	}
+	resp := call(t, method, url, token, "application/json", bytes.NewReader(data))
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("decode %s reply: %v", url, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// signUp registers and logs in an account, then binds a peer to it
+// Returns the session token and the bound peer's ID
+func signUp(t *testing.T, ts *httptest.Server, name string) (string, string) {
+	t.Helper()
+
+	email := name + "@university.edu"
+	password := name + "-password"
+	credentials := map[string]string{"email": email, "username": name, "password": password}
+	if status := callJSON(t, "POST", ts.URL+"/api/auth/register", "", credentials, nil); status != http.StatusCreated {
+		t.Fatalf("register %s: status %d", name, status)
+	}
+
+	var login AuthResponse
+	if status := callJSON(t, "POST", ts.URL+"/api/auth/login", "", credentials, &login); status != http.StatusOK || login.Data == nil {
+		t.Fatalf("login %s: status %d: %s", name, status, login.Error)
+	}
+	token := login.Data.Token
+
+	var registered struct {
+		Data PeerInfo `json:"data"`
+	}
+	peer := map[string]interface{}{"name": name + "-laptop", "ip_address": "127.0.0.1", "port": 9000}
+	if status := callJSON(t, "POST", ts.URL+"/api/peers/register", token, peer, &registered); status != http.StatusCreated {
+		t.Fatalf("register peer for %s: status %d", name, status)
+	}
+	return token, registered.Data.ID
+}
+
+// upload shares a file through the gateway and returns its CID
+func upload(t *testing.T, ts *httptest.Server, token, fileName string, content []byte) string {
+	t.Helper()
+
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	part, err := writer.CreateFormFile("file", fileName)
+	if err != nil {
+		t.Fatalf("create form file: %v", err)
+	}
+	part.Write(content)
+	writer.Close()
+
+	resp := call(t, "POST", ts.URL+"/api/files/upload", token, writer.FormDataContentType(), &form)
+	var reply struct {
+		Data struct {
+			CID string `json:"cid"`
+		} `json:"data"`
+		Error string `json:"error"`
+	}
+	json.NewDecoder(resp.Body).Decode(&reply)
+	if resp.StatusCode != http.StatusCreated || reply.Data.CID == "" {
+		t.Fatalf("upload: status %d: %s", resp.StatusCode, reply.Error)
+	}
+	return reply.Data.CID
+}
+
+// rateFile submits a file rating and returns the status and any error
+func rateFile(t *testing.T, ts *httptest.Server, token, cid string) (int, string) {
+	t.Helper()
+
+	var reply APIResponse
+	status := callJSON(t, "POST", ts.URL+"/api/ratings/file", token,
+		map[string]interface{}{"file_cid": cid, "score": 5, "comment": "Clear notes"}, &reply)
+	return status, reply.Error
+}
+
+// ============================================================================
+// TESTS
+// ============================================================================
+
+// TestDownloadThenRateFile checks a bound peer may rate a file once it has
+// downloaded it through the gateway, and not before
+func TestDownloadThenRateFile(t *testing.T) {
+	server, ts := newTestGateway(t)
+
+	ownerToken, ownerPeer := signUp(t, ts, "alice")
+	raterToken, raterPeer := signUp(t, ts, "bob")
+
+	content := []byte(strings.Repeat("Dijkstra's algorithm finds shortest paths.\n", 2000))
+	cid := upload(t, ts, ownerToken, "graphs.txt", content)
+
+	// Without a download the rating is refused
+	if status, reason := rateFile(t, ts, raterToken, cid); status != http.StatusBadRequest || !strings.Contains(reason, "download") {
+		t.Fatalf("rating before download: status %d (%s), want refusal", status, reason)
+	}
+
+	// The owner can't rate their own file
+	if status, _ := rateFile(t, ts, ownerToken, cid); status != http.StatusBadRequest {
+		t.Fatalf("owner rating own file: status %d, want refusal", status)
+	}
+
+	resp := call(t, "GET", ts.URL+"/api/files/download?cid="+cid, raterToken, "", nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("download: status %d", resp.StatusCode)
+	}
+	received, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read download: %v", err)
+	}
+	if !bytes.Equal(received, content) {
+		t.Fatalf("downloaded %d bytes that don't match the %d uploaded", len(received), len(content))
+	}
+
+	// The delivery is recorded against the rater's bound peer
+	if _, ok := server.transferManager.CompletedDownload(raterPeer, cid); !ok {
+		t.Fatal("no verified delivery recorded for the rater's peer")
+	}
+	if !server.reputationService.GetLedger().HasTransferred(ownerPeer, raterPeer) {
+		t.Fatal("ledger has no transfer from the owner's peer to the rater's")
+	}
+
+	if status, reason := rateFile(t, ts, raterToken, cid); status != http.StatusCreated {
+		t.Fatalf("rating after download: status %d: %s", status, reason)
+	}
+}
+
+// TestDeactivatedLoginNeedsPassword checks a deactivated account only says
+// so to the right password, and wrong guesses still count toward lockout
+func TestDeactivatedLoginNeedsPassword(t *testing.T) {
+	server, ts := newTestGateway(t)
+	signUp(t, ts, "carol")
+
+	user, err := server.userStore.GetByEmail("carol@university.edu")
+	if err != nil {
+		t.Fatalf("find user: %v", err)
+	}
+	if err := server.userStore.SetActive(user.ID, false); err != nil {
+		t.Fatalf("deactivate: %v", err)
+	}
+
+	wrong := map[string]string{"email": "carol@university.edu", "password": "not-her-password"}
+	free := auth.DefaultLockoutPolicy().AccountAttempts
+	for i := 1; i <= free; i++ {
+		var reply AuthResponse
+		if status := callJSON(t, "POST", ts.URL+"/api/auth/login", "", wrong, &reply); status != http.StatusUnauthorized || strings.Contains(reply.Error, "deactivated") {
+			t.Fatalf("wrong password %d: status %d (%s), want the generic refusal", i, status, reply.Error)
+		}
+	}
+	if status := callJSON(t, "POST", ts.URL+"/api/auth/login", "", wrong, nil); status != http.StatusTooManyRequests {
+		t.Fatalf("wrong password %d: status %d, want a lockout", free+1, status)
+	}
+	if err := server.loginGuard.Unlock(auth.AccountKey("carol@university.edu"), "admin"); err != nil {
+		t.Fatalf("unlock: %v", err)
+	}
+
+	var reply AuthResponse
+	right := map[string]string{"email": "carol@university.edu", "password": "carol-password"}
+	if status := callJSON(t, "POST", ts.URL+"/api/auth/login", "", right, &reply); status != http.StatusUnauthorized || !strings.Contains(reply.Error, "deactivated") {
+		t.Fatalf("right password: status %d (%s), want the deactivated refusal", status, reply.Error)
+	}
+}
@@ -20,12 +20,14 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"path/filepath"
 	"sync"
 	"time"
 
 	"knowledge-exchange/analytics"
 	"knowledge-exchange/auth"
 	"knowledge-exchange/library"
+	"knowledge-exchange/mail"
 	"knowledge-exchange/models"
 	"knowledge-exchange/storage"
 	"knowledge-exchange/utils"
@@ -43,6 +45,14 @@ type Server struct {
 	// Authentication services
 	authService *auth.Service
 	userStore   *storage.UserStore
+	roles       *storage.RoleStore
+	loginGuard  *auth.LoginGuard
+	mailer      mail.Mailer
+	sso         *auth.SingleSignOn
+
+	// setupToken lets the operator create the first admin (empty once used)
+	setupToken string
+	setupMutex sync.Mutex
 
 	// Services
 	peerRegistry      *models.PeerRegistry
@@ -53,6 +63,11 @@ type Server struct {
 	reputationService *analytics.ReputationService
 	ratingService     *analytics.RatingService
 	throttlingManager *analytics.ThrottlingManager
+	sybilDetector     *analytics.SybilDetector
+
+	// Moderation: banned peers, removed and pinned files, and reports
+	moderation *storage.ModerationStore
+	reports    *storage.ReportStore
 
 	// Router
 	router *Router
@@ -60,6 +75,13 @@ type Server struct {
 	// Discovery service
 	discovery *Discovery
 
+	// Global trust (nil unless trust gossip is enabled)
+	trustManager *analytics.TrustManager
+	trustGossip  *TrustGossip
+
+	// Persisted reputation event log (opened in Start)
+	eventLog *analytics.EventLog
+
 	// Server state
 	isRunning bool
 	mutex     sync.RWMutex
@@ -75,8 +97,52 @@ type Server struct {
 // NewServer creates a new Gateway server
 func NewServer(config *utils.Config) *Server {
 	// Initialize authentication services
-	authService := auth.NewService()
-	userStore := storage.NewUserStore()
+	authService := auth.NewService(loadSigningKeys(config))
+	userStore, err := storage.OpenUserStore(filepath.Join(config.DataDir, "users.json"))
+	if err != nil {
+		log.Printf("Warning: accounts won't survive a restart, failed to load users: %v", err)
+		userStore = storage.NewUserStore()
+	}
+	if config.DevMode {
+		if err := userStore.CreateDevAdmin(); err != nil {
+			log.Printf("Warning: failed to create development admin: %v", err)
+		}
+	} else if disabled, err := userStore.DisableDevAdmin(); err != nil {
+		log.Printf("Warning: failed to disable development admin: %v", err)
+	} else if disabled {
+		log.Printf("Disabled the development admin account left over from development mode")
+	}
+
+	// Roles and their permissions
+	roles, err := storage.NewRoleStore(filepath.Join(config.DataDir, "roles.json"))
+	if err != nil {
+		log.Printf("Warning: role changes won't survive a restart, failed to load roles: %v", err)
+		roles, _ = storage.NewRoleStore("")
+	}
+
+	// Persist sessions and revocations; fall back to memory on error
+	tokenStore, err := storage.NewTokenStore(filepath.Join(config.DataDir, "auth_tokens.json"))
+	if err != nil {
+		log.Printf("Warning: sessions won't survive a restart, failed to load token store: %v", err)
+		tokenStore, _ = storage.NewTokenStore("")
+	}
+	authService.SetTokenStore(tokenStore)
+
+	// API keys for scripts and headless peers
+	apiKeyStore, err := storage.NewAPIKeyStore(filepath.Join(config.DataDir, "api_keys.json"))
+	if err != nil {
+		log.Printf("Warning: API keys won't survive a restart, failed to load API keys: %v", err)
+		apiKeyStore, _ = storage.NewAPIKeyStore("")
+	}
+	authService.SetAPIKeyStore(apiKeyStore)
+
+	// Persist failed-login counters so lockouts survive a restart
+	attemptStore, err := storage.NewAttemptStore(filepath.Join(config.DataDir, "login_attempts.json"))
+	if err != nil {
+		log.Printf("Warning: lockouts won't survive a restart, failed to load login attempts: %v", err)
+		attemptStore, _ = storage.NewAttemptStore("")
+	}
+	loginGuard := auth.NewLoginGuard(attemptStore, auth.DefaultLockoutPolicy())
 
 	// Initialize core data structures
 	peerRegistry := models.NewPeerRegistry()
@@ -89,11 +155,53 @@ func NewServer(config *utils.Config) *Server {
 	reputationService := analytics.NewReputationService(peerRegistry)
 	ratingService := analytics.NewRatingService(reputationService)
 	throttlingManager := analytics.NewThrottlingManager()
+	sybilDetector := analytics.NewSybilDetector(ratingService, reputationService.GetLedger(), peerRegistry)
+	ratingService.SetSybilDetector(sybilDetector)
+	ratingService.SetFileIndex(fileIndex)
+	ratingService.SetDownloadVerifier(transferManager)
 	discovery := NewDiscovery(peerRegistry)
 
+	// Restore bans and removed files; fall back to memory on error
+	moderation, err := storage.NewModerationStore(filepath.Join(config.DataDir, "moderation.json"))
+	if err != nil {
+		log.Printf("Warning: moderation won't survive a restart, failed to load bans: %v", err)
+		moderation, _ = storage.NewModerationStore("")
+	}
+	for _, removed := range moderation.ListRemovedFiles() {
+		indexer.BlockFile(removed.ID)
+	}
+	discovery.SetBanCheck(moderation.IsPeerBanned)
+	discovery.SetPeerSync(reputationService.SyncStudent)
+	reports, err := storage.NewReportStore(filepath.Join(config.DataDir, "reports.json"))
+	if err != nil {
+		log.Printf("Warning: reports won't survive a restart, failed to load reports: %v", err)
+		reports, _ = storage.NewReportStore("")
+	}
+
+	// Feed completed transfers into the bandwidth ledger; the file's owner
+	// is credited for what this node serves
+	transferManager.SetLocalPeerID(config.PeerID)
+	transferManager.SetCompletionHandler(func(t library.TransferSnapshot) {
+		if t.Direction == "upload" {
+			ownerID := config.PeerID
+			if file, ok := fileIndex.Get(t.CID); ok && file.OwnerID != "" {
+				ownerID = file.OwnerID
+			} else if file, ok := indexer.GetFile(t.CID); ok && file.OwnerID != "" {
+				ownerID = file.OwnerID
+			}
+			reputationService.RecordDelivery(ownerID, t.PeerID, t.SentBytes)
+		} else {
+			reputationService.RecordDelivery(t.PeerID, config.PeerID, t.SentBytes)
+		}
+	})
+
 	server := &Server{
 		authService:       authService,
 		userStore:         userStore,
+		roles:             roles,
+		loginGuard:        loginGuard,
+		mailer:            newMailer(config),
+		sso:               newSingleSignOn(config),
 		peerRegistry:      peerRegistry,
 		fileIndex:         fileIndex,
 		indexer:           indexer,
@@ -102,23 +210,153 @@ func NewServer(config *utils.Config) *Server {
 		reputationService: reputationService,
 		ratingService:     ratingService,
 		throttlingManager: throttlingManager,
+		sybilDetector:     sybilDetector,
 		discovery:         discovery,
+		moderation:        moderation,
+		reports:           reports,
 		isRunning:         false,
 		config:            config,
 	}
 
+	// Enable EigenTrust gossip if configured
+	if config.EnableTrustGossip {
+		server.setupTrustGossip()
+	}
+
 	// Create router with server reference
 	server.router = NewRouter(server)
 
 	return server
 }
 
+// uploadsDir is where content uploaded through the gateway is kept
+func (s *Server) uploadsDir() string {
+	return filepath.Join(s.config.DataDir, "uploads")
+}
+
+// newMailer returns the SMTP mailer when a server is configured, otherwise
+// one that writes messages to DataDir/mail
+func newMailer(config *utils.Config) mail.Mailer {
+	if config.SMTPHost != "" {
+		return mail.NewSMTPMailer(config.SMTPHost, config.SMTPPort, config.SMTPUsername, config.SMTPPassword, config.MailFrom)
+	}
+	log.Printf("No SMTP server configured; outgoing mail is written to %s", filepath.Join(config.DataDir, "mail"))
+	return mail.NewFileMailer(filepath.Join(config.DataDir, "mail"), config.MailFrom)
+}
+
+// newSingleSignOn registers the configured OpenID Connect provider, if any
+// A bad configuration is logged and leaves single sign-on off
+func newSingleSignOn(config *utils.Config) *auth.SingleSignOn {
+	sso := auth.NewSingleSignOn()
+	if config.OIDCIssuer == "" {
+		return sso
+	}
+
+	redirectURL := config.OIDCRedirectURL
+	if redirectURL == "" {
+		redirectURL = config.PublicURL + "/sso/callback"
+	}
+	provider, err := auth.NewOIDCProvider(auth.OIDCConfig{
+		Name:         "oidc",
+		DisplayName:  config.OIDCDisplayName,
+		Issuer:       config.OIDCIssuer,
+		ClientID:     config.OIDCClientID,
+		ClientSecret: config.OIDCClientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       config.OIDCScopes,
+	})
+	if err != nil {
+		log.Printf("Warning: single sign-on is off: %v", err)
+		return sso
+	}
+
+	sso.Register(provider)
+	log.Printf("✓ Single sign-on through %s (%s)", config.OIDCIssuer, config.OIDCDisplayName)
+	return sso
+}
+
+// loadSigningKeys returns the JWT key ring: the configured secret if set,
+// otherwise keys persisted in DataDir (generated on first run)
+func loadSigningKeys(config *utils.Config) *auth.KeyRing {
+	if config.JWTSecret != "" {
+		return auth.NewPinnedKeyRing(config.JWTSecret)
+	}
+
+	keys, err := auth.LoadKeyRing(filepath.Join(config.DataDir, "auth_keys.json"), config.JWTAlgorithm)
+	if err == nil {
+		return keys
+	}
+
+	// Fall back to a throwaway key so the node still starts
+	log.Printf("Warning: tokens won't survive a restart, failed to load signing keys: %v", err)
+	key, genErr := auth.GenerateSigningKey(auth.AlgHS256)
+	if genErr != nil {
+		log.Fatalf("Failed to generate signing key: %v", genErr)
+	}
+	return auth.NewKeyRing(key)
+}
+
+// setupTrustGossip creates the trust manager and gossip service and makes
+// global trust the source of reputation scores
+func (s *Server) setupTrustGossip() {
+	keyPath := filepath.Join(s.config.DataDir, "trust_node.key")
+	nodeKey, err := analytics.LoadOrCreateNodeKey(keyPath)
+	if err != nil {
+		log.Printf("Warning: trust gossip disabled, failed to load node key: %v", err)
+		return
+	}
+
+	s.trustManager = analytics.NewTrustManager(
+		s.config.PeerID,
+		nodeKey,
+		s.ratingService.GetRatingStore(),
+		s.reputationService.GetLedger(),
+		s.config.PreTrustedPeers,
+	)
+	// Only nodes this peer has discovered or registered may gossip
+	s.trustManager.SetNodeCheck(func(nodeID string) bool {
+		_, known := s.peerRegistry.Get(nodeID)
+		return known && !s.moderation.IsPeerBanned(nodeID)
+	})
+	s.reputationService.SetTrustManager(s.trustManager)
+	s.trustGossip = NewTrustGossip(s.trustManager, s.peerRegistry, s.config.PeerID, s.config.TrustGossipInterval)
+}
+
 // ============================================================================
 // SERVER LIFECYCLE
 // ============================================================================
 
 // Start starts the HTTP server
 func (s *Server) Start() error {
+	// Load and validate the reputation policy before anything uses it
+	if s.config.ReputationPolicyFile != "" {
+		policy, err := analytics.LoadReputationPolicy(s.config.ReputationPolicyFile)
+		if err != nil {
+			return err
+		}
+		if err := s.SetReputationPolicy(policy); err != nil {
+			return err
+		}
+		log.Printf("Loaded reputation policy from %s", s.config.ReputationPolicyFile)
+	}
+
+	// Rebuild reputation from the persisted event log under that policy
+	eventLog, err := analytics.OpenEventLog(s.config.DataDir)
+	if err != nil {
+		return fmt.Errorf("failed to open reputation log: %w", err)
+	}
+	s.reputationService.UseEventLog(eventLog)
+	s.eventLog = eventLog
+
+	// First run: print a one-time token for creating the first admin
+	if !s.userStore.HasAdmin() {
+		token, err := s.newSetupToken()
+		if err != nil {
+			return fmt.Errorf("failed to create setup token: %w", err)
+		}
+		log.Printf("No admin account exists. Create one with POST /api/auth/setup using setup token: %s", token)
+	}
+
 	s.mutex.Lock()
 	if s.isRunning {
 		s.mutex.Unlock()
@@ -130,7 +368,11 @@ func (s *Server) Start() error {
 	// Start services
 	s.reputationService.Start()
 	s.ratingService.Start()
+	s.sybilDetector.Start()
 	s.discovery.Start()
+	if s.trustGossip != nil {
+		s.trustGossip.Start()
+	}
 
 	// Start file watcher
 	s.indexer.StartWatcher(s.config.PeerID, 30*time.Second)
@@ -169,9 +411,18 @@ func (s *Server) Stop() error {
 	// Stop services
 	s.reputationService.Stop()
 	s.ratingService.Stop()
+	s.sybilDetector.Stop()
 	s.discovery.Stop()
+	if s.trustGossip != nil {
+		s.trustGossip.Stop()
+	}
 	s.indexer.StopWatcher()
 	s.throttlingManager.StopAll()
+	if s.eventLog != nil {
+		if err := s.eventLog.Close(); err != nil {
+			log.Printf("Warning: failed to close reputation log: %v", err)
+		}
+	}
 
 	// Shutdown HTTP server with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -209,6 +460,29 @@ type PeerInfo struct {
 	Downloads  int     `json:"downloads"`
 }
 
+// PageInfo wraps one page of a ranked or listed collection
+type PageInfo struct {
+	Items    interface{} `json:"items"`
+	Page     int         `json:"page"`
+	PageSize int         `json:"page_size"`
+	Total    int         `json:"total"`
+	Method   string      `json:"method,omitempty"`
+}
+
+// RankedPeerInfo is a peer with its ranking score
+type RankedPeerInfo struct {
+	PeerInfo
+	Score       float64 `json:"score"`
+	RatingCount int     `json:"rating_count"`
+}
+
+// RankedFileInfo is a file with its ranking score
+type RankedFileInfo struct {
+	FileInfo
+	Score       float64 `json:"score"`
+	RatingCount int     `json:"rating_count"`
+}
+
 // FileInfo contains public file information
 type FileInfo struct {
 	CID        string    `json:"cid"`
@@ -221,6 +495,7 @@ type FileInfo struct {
 	Rating     float64   `json:"rating"`
 	Available  bool      `json:"available"`
 	UploadedAt time.Time `json:"uploaded_at"`
+	Pinned     bool      `json:"pinned"` // Official course material
 }
 
 // ============================================================================
@@ -258,10 +533,23 @@ func (s *Server) HandleRegister(w http.ResponseWriter, r *http.Request) {
 
 	// Create new peer
 	peerID := utils.GeneratePeerID(req.Name, req.IPAddress, req.Port)
+	if s.moderation.IsPeerBanned(peerID) {
+		s.sendError(w, http.StatusForbidden, "This peer has been banned")
+		return
+	}
 	student := models.NewStudent(peerID, req.Name, req.IPAddress, req.Port)
 
-	// Register
+	// Bind the peer to the caller's account when logged in
+	if user, ok := auth.UserFromContext(r.Context()); ok {
+		if err := s.userStore.BindPeer(user.ID, peerID); err != nil {
+			s.sendError(w, http.StatusConflict, err.Error())
+			return
+		}
+	}
+
+	// Register, restoring any reputation already earned under this ID
 	s.peerRegistry.Register(student)
+	s.reputationService.SyncStudent(student)
 
 	s.sendJSON(w, http.StatusCreated, APIResponse{
 		Success: true,
@@ -269,8 +557,8 @@ func (s *Server) HandleRegister(w http.ResponseWriter, r *http.Request) {
 		Data: PeerInfo{
 			ID:         student.ID,
 			Name:       student.Name,
-			Reputation: student.ReputationScore,
-			IsOnline:   student.IsOnline,
+			Reputation: student.Reputation(),
+			IsOnline:   student.Online(),
 		},
 	})
 }
@@ -281,13 +569,14 @@ func (s *Server) HandleGetPeers(w http.ResponseWriter, r *http.Request) {
 
 	peerList := make([]PeerInfo, len(peers))
 	for i, p := range peers {
+		uploads, downloads := p.Activity()
 		peerList[i] = PeerInfo{
 			ID:         p.ID,
 			Name:       p.Name,
-			Reputation: p.ReputationScore,
-			IsOnline:   p.IsOnline,
-			Uploads:    p.TotalUploads,
-			Downloads:  p.TotalDownloads,
+			Reputation: p.Reputation(),
+			IsOnline:   p.Online(),
+			Uploads:    uploads,
+			Downloads:  downloads,
 		}
 	}
 
@@ -320,6 +609,7 @@ func (s *Server) HandleSearch(w http.ResponseWriter, r *http.Request) {
 			Rating:     f.AverageRating,
 			Available:  f.IsAvailable,
 			UploadedAt: f.UploadTime,
+			Pinned:     s.isPinned(f.CID),
 		}
 	}
 
@@ -346,6 +636,7 @@ func (s *Server) HandleGetFiles(w http.ResponseWriter, r *http.Request) {
 			Rating:     f.AverageRating,
 			Available:  f.IsAvailable,
 			UploadedAt: f.UploadTime,
+			Pinned:     s.isPinned(f.CID),
 		}
 	}
 
@@ -358,27 +649,52 @@ func (s *Server) HandleGetFiles(w http.ResponseWriter, r *http.Request) {
 // HandleGetReputation returns reputation for a peer
 func (s *Server) HandleGetReputation(w http.ResponseWriter, r *http.Request) {
 	peerID := r.URL.Query().Get("peer_id")
+
+	// Without a peer ID, report the caller's account (same view as /api/auth/me)
 	if peerID == "" {
-		s.sendError(w, http.StatusBadRequest, "Peer ID required")
+		user, ok := auth.UserFromContext(r.Context())
+		if !ok {
+			s.sendError(w, http.StatusBadRequest, "Peer ID required")
+			return
+		}
+
+		public := s.publicUser(user)
+		s.sendJSON(w, http.StatusOK, APIResponse{
+			Success: true,
+			Data: map[string]interface{}{
+				"user_id":         public.ID,
+				"peer_ids":        public.PeerIDs,
+				"reputation":      public.Reputation,
+				"total_uploads":   public.TotalUploads,
+				"total_downloads": public.TotalDownloads,
+			},
+		})
 		return
 	}
 
-	reputation, err := s.reputationService.GetReputation(peerID)
-	if err != nil {
+	if _, err := s.reputationService.GetReputation(peerID); err != nil {
 		s.sendError(w, http.StatusNotFound, err.Error())
 		return
 	}
 
+	standing := s.reputationService.GetStanding(peerID)
 	canDownload, reason := s.reputationService.CanDownload(peerID)
 
+	data := map[string]interface{}{
+		"peer_id":         peerID,
+		"reputation":      standing.Reputation,
+		"total_uploads":   standing.TotalUploads,
+		"total_downloads": standing.TotalDownloads,
+		"can_download":    canDownload,
+		"reason":          reason,
+	}
+	if user, err := s.userStore.GetByPeerID(peerID); err == nil {
+		data["user_id"] = user.ID
+	}
+
 	s.sendJSON(w, http.StatusOK, APIResponse{
 		Success: true,
-		Data: map[string]interface{}{
-			"peer_id":      peerID,
-			"reputation":   reputation,
-			"can_download": canDownload,
-			"reason":       reason,
-		},
+		Data:    data,
 	})
 }
 
@@ -396,7 +712,13 @@ func (s *Server) HandleRateFile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rating, err := s.ratingService.RateFile(req.RaterID, req.FileCID, req.Score, req.Comment)
+	raterID, err := s.resolveActor(r, req.RaterID)
+	if err != nil {
+		s.sendError(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	rating, err := s.ratingService.RateFile(raterID, req.FileCID, req.Score, req.Comment)
 	if err != nil {
 		s.sendError(w, http.StatusBadRequest, err.Error())
 		return
@@ -419,6 +741,10 @@ func (s *Server) HandleGetStats(w http.ResponseWriter, r *http.Request) {
 		"reputation": s.reputationService.GetStats(),
 		"ratings":    s.ratingService.GetGlobalStats(),
 		"throttling": s.throttlingManager.GetStats(),
+		"sybil":      s.sybilDetector.GetStats(),
+	}
+	if s.trustManager != nil {
+		stats["trust"] = s.trustManager.GetStats()
 	}
 
 	s.sendJSON(w, http.StatusOK, APIResponse{
@@ -427,6 +753,185 @@ func (s *Server) HandleGetStats(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// HandleTrustGossip accepts a signed trust report from another node
+func (s *Server) HandleTrustGossip(w http.ResponseWriter, r *http.Request) {
+	if s.trustManager == nil {
+		s.sendError(w, http.StatusServiceUnavailable, "Trust gossip is disabled")
+		return
+	}
+
+	var report analytics.TrustReport
+	r.Body = http.MaxBytesReader(w, r.Body, analytics.TrustReportMaxBytes)
+	if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+		s.sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := s.trustManager.AcceptReport(&report); err != nil {
+		s.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.sendJSON(w, http.StatusAccepted, APIResponse{
+		Success: true,
+		Message: "Trust report accepted",
+	})
+}
+
+// HandleTrustReport returns this node's current signed trust report
+func (s *Server) HandleTrustReport(w http.ResponseWriter, r *http.Request) {
+	if s.trustManager == nil {
+		s.sendError(w, http.StatusServiceUnavailable, "Trust gossip is disabled")
+		return
+	}
+
+	report, err := s.trustManager.LocalReport()
+	if err != nil {
+		s.sendError(w, http.StatusInternalServerError, "Failed to build trust report")
+		return
+	}
+
+	s.sendJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    report,
+	})
+}
+
+// SetReputationPolicy validates and activates a reputation policy across
+// the reputation service and bandwidth throttling
+func (s *Server) SetReputationPolicy(policy analytics.ReputationPolicy) error {
+	if err := s.reputationService.SetPolicy(policy); err != nil {
+		return err
+	}
+	s.throttlingManager.SetTierPolicy(policy.Tiers)
+	return nil
+}
+
+// HandleGetPolicy returns the active reputation policy
+func (s *Server) HandleGetPolicy(w http.ResponseWriter, r *http.Request) {
+	s.sendJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    s.reputationService.GetPolicy(),
+	})
+}
+
+// HandleUpdatePolicy replaces the active reputation policy
+// Fields missing from the body keep their current values
+func (s *Server) HandleUpdatePolicy(w http.ResponseWriter, r *http.Request) {
+	policy := s.reputationService.GetPolicy()
+	if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+		s.sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := s.SetReputationPolicy(policy); err != nil {
+		s.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	log.Println("Reputation policy updated")
+
+	// Optionally re-derive every score under the new policy
+	if r.URL.Query().Get("recompute") == "true" {
+		if _, err := s.reputationService.Recompute(); err != nil {
+			s.sendError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	s.sendJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Message: "Reputation policy updated",
+		Data:    policy,
+	})
+}
+
+// HandleReputationAudit returns a student's reputation trail
+func (s *Server) HandleReputationAudit(w http.ResponseWriter, r *http.Request) {
+	peerID := r.URL.Query().Get("peer_id")
+	if peerID == "" {
+		s.sendError(w, http.StatusBadRequest, "Peer ID required")
+		return
+	}
+
+	trail, err := s.reputationService.AuditStudent(peerID)
+	if err != nil {
+		s.sendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	s.sendJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    trail,
+	})
+}
+
+// HandleRecomputeReputation replays the full event log under the active policy
+func (s *Server) HandleRecomputeReputation(w http.ResponseWriter, r *http.Request) {
+	replayed, err := s.reputationService.Recompute()
+	if err != nil {
+		s.sendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	s.sendJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Message: "Reputation recomputed",
+		Data:    map[string]interface{}{"events_replayed": replayed},
+	})
+}
+
+// HandleCompactReputation snapshots scores and archives the event log
+func (s *Server) HandleCompactReputation(w http.ResponseWriter, r *http.Request) {
+	snapshot, err := s.reputationService.Compact()
+	if err != nil {
+		s.sendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	s.sendJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Message: "Reputation log compacted",
+		Data: map[string]interface{}{
+			"seq":        snapshot.Seq,
+			"students":   len(snapshot.Scores),
+			"created_at": snapshot.CreatedAt,
+		},
+	})
+}
+
+// HandleGlobalTrust returns global trust for one peer, or all peers
+func (s *Server) HandleGlobalTrust(w http.ResponseWriter, r *http.Request) {
+	if s.trustManager == nil {
+		s.sendError(w, http.StatusServiceUnavailable, "Trust gossip is disabled")
+		return
+	}
+
+	peerID := r.URL.Query().Get("peer_id")
+	if peerID == "" {
+		s.sendJSON(w, http.StatusOK, APIResponse{
+			Success: true,
+			Data:    s.trustManager.GetAllTrust(),
+		})
+		return
+	}
+
+	trust, exists := s.trustManager.GlobalTrust(peerID)
+	if !exists {
+		s.sendError(w, http.StatusNotFound, "No trust score for peer")
+		return
+	}
+	reputation, _ := s.trustManager.TrustToReputation(peerID)
+
+	s.sendJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"peer_id":      peerID,
+			"global_trust": trust,
+			"reputation":   reputation,
+		},
+	})
+}
+
 // ============================================================================
 // HELPER METHODS
 // ============================================================================
@@ -458,4 +963,5 @@ func (s *Server) GetTransferManager() *library.TransferManager       { return s.
 func (s *Server) GetReputationService() *analytics.ReputationService { return s.reputationService }
 func (s *Server) GetRatingService() *analytics.RatingService         { return s.ratingService }
 func (s *Server) GetThrottlingManager() *analytics.ThrottlingManager { return s.throttlingManager }
+func (s *Server) GetSybilDetector() *analytics.SybilDetector         { return s.sybilDetector }
 func (s *Server) GetDiscovery() *Discovery                           { return s.discovery }
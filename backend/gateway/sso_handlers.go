@@ -0,0 +1,189 @@
+/*
+================================================================================
+SINGLE SIGN-ON HANDLERS - P2P Academic Library "The Knowledge Exchange"
+================================================================================
+This file implements the endpoints for signing in through an identity
+provider (OpenID Connect).
+
+The frontend asks to start a login and sends the browser to the URL it
+gets back. The provider redirects to the frontend's callback page with a
+code and the state; the page checks the state is the one it started with
+and posts both here, which signs the user in like a password login
+(including the second factor when they enabled one).
+
+Go Concepts Used:
+- HTTP handlers: Request/response handling
+- JSON encoding/decoding
+- Context: Bounding provider requests by the client request
+================================================================================
+*/
+
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"knowledge-exchange/auth"
+)
+
+// ============================================================================
+// REQUEST/RESPONSE TYPES
+// ============================================================================
+
+// SSOProviderInfo describes a provider for the login page
+type SSOProviderInfo struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+}
+
+// SSOStartRequest picks the provider to sign in with
+type SSOStartRequest struct {
+	Provider string `json:"provider"`
+}
+
+// SSOStart is where to send the browser
+type SSOStart struct {
+	AuthorizationURL string `json:"authorization_url"`
+	State            string `json:"state"`
+	ExpiresIn        int64  `json:"expires_in"` // Seconds to finish signing in
+}
+
+// SSOCallbackRequest carries the provider's redirect parameters
+type SSOCallbackRequest struct {
+	State string `json:"state"`
+	Code  string `json:"code"`
+}
+
+// ============================================================================
+// HANDLERS
+// ============================================================================
+
+// ssoProvidersHandler lists the identity providers users can sign in with
+func (r *Router) ssoProvidersHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		providers := r.server.sso.Providers()
+		infos := make([]SSOProviderInfo, len(providers))
+		for i, provider := range providers {
+			infos[i] = SSOProviderInfo{
+				Name:        provider.Name(),
+				DisplayName: provider.DisplayName(),
+			}
+		}
+
+		r.server.sendJSON(w, http.StatusOK, APIResponse{
+			Success: true,
+			Data:    infos,
+		})
+	}
+}
+
+// ssoStartHandler starts a login at an identity provider
+func (r *Router) ssoStartHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var body SSOStartRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.Provider == "" {
+			sendJSON(w, http.StatusBadRequest, AuthResponse{
+				Success: false,
+				Error:   "Provider required",
+			})
+			return
+		}
+
+		authURL, state, err := r.server.sso.Begin(req.Context(), body.Provider)
+		switch {
+		case errors.Is(err, auth.ErrUnknownProvider):
+			sendJSON(w, http.StatusNotFound, AuthResponse{
+				Success: false,
+				Error:   "Unknown identity provider",
+			})
+			return
+		case errors.Is(err, auth.ErrTooManySSOLogins):
+			sendJSON(w, http.StatusServiceUnavailable, AuthResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		case err != nil:
+			log.Printf("Single sign-on with %s failed to start: %v", body.Provider, err)
+			sendJSON(w, http.StatusBadGateway, AuthResponse{
+				Success: false,
+				Error:   "The identity provider is unavailable, please try again later",
+			})
+			return
+		}
+
+		sendJSON(w, http.StatusOK, APIResponse{
+			Success: true,
+			Data: SSOStart{
+				AuthorizationURL: authURL,
+				State:            state,
+				ExpiresIn:        int64(auth.SSOLoginExpiration.Seconds()),
+			},
+		})
+	}
+}
+
+// ssoCallbackHandler finishes a login at an identity provider
+// The account is found by the provider's subject, linked by verified email
+// or created on first sign-in
+func (r *Router) ssoCallbackHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var body SSOCallbackRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.State == "" || body.Code == "" {
+			sendJSON(w, http.StatusBadRequest, AuthResponse{
+				Success: false,
+				Error:   "State and code required",
+			})
+			return
+		}
+
+		identity, err := r.server.sso.Complete(req.Context(), body.State, body.Code)
+		if errors.Is(err, auth.ErrInvalidSSOState) {
+			sendJSON(w, http.StatusUnauthorized, AuthResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+		if err != nil {
+			log.Printf("Single sign-on failed: %v", err)
+			sendJSON(w, http.StatusUnauthorized, AuthResponse{
+				Success: false,
+				Error:   "The identity provider did not confirm your sign-in, please try again",
+			})
+			return
+		}
+
+		user, created, err := auth.ResolveExternalUser(r.server.userStore, identity)
+		if err != nil {
+			sendJSON(w, http.StatusConflict, AuthResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+		if created {
+			log.Printf("New user via %s: %s (%s)", identity.Provider, user.Username, user.Email)
+		}
+
+		if !user.IsActive {
+			sendJSON(w, http.StatusUnauthorized, AuthResponse{
+				Success: false,
+				Error:   "Account is deactivated",
+			})
+			return
+		}
+
+		// The provider stands in for the password; a second factor the
+		// user enabled here is still required
+		if user.TwoFactorEnabled() {
+			r.sendTwoFactorChallenge(w, user)
+			return
+		}
+
+		r.completeLogin(w, user, "Login successful")
+	}
+}
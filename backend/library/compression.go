@@ -0,0 +1,143 @@
+/*
+================================================================================
+TRANSFER COMPRESSION - P2P Academic Library "The Knowledge Exchange"
+================================================================================
+This file implements on-the-wire compression codecs for file transfers.
+
+Go Concepts Used:
+- io.Reader/io.Writer: Stream wrapping for transparent compression
+- Interfaces: io.WriteCloser/io.ReadCloser codec abstraction
+- Slices: Codec preference lists
+- Error handling: Unsupported codec reporting
+================================================================================
+*/
+
+package library
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// ============================================================================
+// CONSTANTS
+// ============================================================================
+
+const (
+	// Supported transfer codecs
+	CodecNone = "none"
+	CodecGzip = "gzip"
+	CodecZstd = "zstd"
+)
+
+// precompressedTypes lists file types whose content is already compressed
+// (ZIP-based office/ebook containers and PDF streams); recompressing them
+// costs CPU without shrinking the payload
+var precompressedTypes = map[string]bool{
+	".pdf":  true,
+	".docx": true,
+	".pptx": true,
+	".xlsx": true,
+	".epub": true,
+	".odt":  true,
+}
+
+// ============================================================================
+// NEGOTIATION
+// ============================================================================
+
+// SupportedCodecs returns the codecs this peer can handle, most preferred first
+func SupportedCodecs() []string {
+	return []string{CodecZstd, CodecGzip, CodecNone}
+}
+
+// IsCompressibleType reports whether a file type benefits from compression
+func IsCompressibleType(fileType string) bool {
+	return !precompressedTypes[strings.ToLower(fileType)]
+}
+
+// NegotiateCodec picks the codec to use for a transfer
+// Parameters:
+//   - accepted: Codecs offered by the requester, most preferred first
+//   - fileType: Extension of the file being sent
+//
+// Returns:
+//   - string: The first accepted codec we support, or CodecNone
+func NegotiateCodec(accepted []string, fileType string) string {
+	if !IsCompressibleType(fileType) {
+		return CodecNone
+	}
+
+	for _, codec := range accepted {
+		for _, supported := range SupportedCodecs() {
+			if codec == supported {
+				return codec
+			}
+		}
+	}
+	return CodecNone
+}
+
+// ============================================================================
+// STREAM WRAPPERS
+// ============================================================================
+
+// nopWriteCloser adapts an io.Writer for the "none" codec
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// zstdReadCloser adapts zstd.Decoder, whose Close has no return value
+type zstdReadCloser struct {
+	*zstd.Decoder
+}
+
+func (z zstdReadCloser) Close() error {
+	z.Decoder.Close()
+	return nil
+}
+
+// newCompressWriter wraps w with the given codec
+// Close must be called to flush the final compressed frame
+func newCompressWriter(w io.Writer, codec string) (io.WriteCloser, error) {
+	switch codec {
+	case CodecNone, "":
+		return nopWriteCloser{w}, nil
+	case CodecGzip:
+		return gzip.NewWriter(w), nil
+	case CodecZstd:
+		return zstd.NewWriter(w)
+	default:
+		return nil, fmt.Errorf("unsupported codec: %s", codec)
+	}
+}
+
+// newDecompressReader wraps r with the given codec
+func newDecompressReader(r io.Reader, codec string) (io.ReadCloser, error) {
+	switch codec {
+	case CodecNone, "":
+		return io.NopCloser(r), nil
+	case CodecGzip:
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		// Stop at the end of the sender's stream instead of waiting for more
+		gz.Multistream(false)
+		return gz, nil
+	case CodecZstd:
+		dec, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return zstdReadCloser{dec}, nil
+	default:
+		return nil, fmt.Errorf("unsupported codec: %s", codec)
+	}
+}
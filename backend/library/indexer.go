@@ -40,6 +40,9 @@ type Indexer struct {
 	// localFiles stores files available on this peer
 	localFiles map[string]string // CID -> file path
 
+	// blocked holds CIDs removed by an admin; they are never re-indexed
+	blocked map[string]bool
+
 	// watchDir is the directory being watched for new files
 	watchDir string
 
@@ -64,6 +67,7 @@ func NewIndexer(watchDir string) *Indexer {
 	return &Indexer{
 		fileIndex:  models.NewFileIndex(),
 		localFiles: make(map[string]string),
+		blocked:    make(map[string]bool),
 		watchDir:   watchDir,
 		isRunning:  false,
 		stopChan:   make(chan struct{}),
@@ -115,8 +119,12 @@ func (idx *Indexer) IndexFile(filePath, ownerID string) (*models.AcademicFile, e
 		content,
 	)
 
-	// Add to index
+	// Add to index unless an admin removed it
 	idx.mutex.Lock()
+	if idx.blocked[academicFile.CID] {
+		idx.mutex.Unlock()
+		return nil, fmt.Errorf("file %s has been removed by an administrator", academicFile.CID)
+	}
 	idx.fileIndex.Add(academicFile)
 	idx.localFiles[academicFile.CID] = filePath
 	idx.mutex.Unlock()
@@ -269,6 +277,45 @@ func (idx *Indexer) GetFileContent(cid string) ([]byte, error) {
 	return content, nil
 }
 
+// StoreContent saves uploaded content under dir and serves it as the local
+// copy of cid; the file's metadata stays wherever the caller indexed it
+// Parameters:
+//   - dir: Directory to save the content in
+//   - cid: Content Identifier of the content
+//   - fileType: File extension, kept on the saved copy
+//   - content: The file content
+//
+// Returns:
+//   - error: Error if the file was removed by an admin or can't be saved
+func (idx *Indexer) StoreContent(dir, cid, fileType string, content []byte) error {
+	idx.mutex.RLock()
+	blocked := idx.blocked[cid]
+	idx.mutex.RUnlock()
+	if blocked {
+		return fmt.Errorf("file %s has been removed by an administrator", cid)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	// Write to a temp file and rename so a crash never leaves a partial copy
+	path := filepath.Join(dir, cid+fileType)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, content, 0644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to save file: %w", err)
+	}
+
+	idx.mutex.Lock()
+	idx.localFiles[cid] = path
+	idx.mutex.Unlock()
+	return nil
+}
+
 // RemoveFile removes a file from the index
 func (idx *Indexer) RemoveFile(cid string) error {
 	idx.mutex.Lock()
@@ -281,6 +328,17 @@ func (idx *Indexer) RemoveFile(cid string) error {
 	return nil
 }
 
+// BlockFile removes a file from the index and keeps it from being indexed
+// again by later directory scans
+func (idx *Indexer) BlockFile(cid string) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	idx.fileIndex.Remove(cid)
+	delete(idx.localFiles, cid)
+	idx.blocked[cid] = true
+}
+
 // ============================================================================
 // DIRECTORY WATCHER
 // ============================================================================
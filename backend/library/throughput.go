@@ -0,0 +1,206 @@
+/*
+================================================================================
+THROUGHPUT METRICS - P2P Academic Library "The Knowledge Exchange"
+================================================================================
+This file tracks transfer speed, ETA and historical per-peer throughput.
+
+Go Concepts Used:
+- Structs: Rate meter and per-peer aggregates
+- Mutex: Thread-safe metric updates
+- Sorting: Ranking peers by observed throughput
+- time.Duration: Interval-based speed sampling
+================================================================================
+*/
+
+package library
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// CONSTANTS
+// ============================================================================
+
+const (
+	// SpeedSampleInterval is the minimum time between speed samples
+	SpeedSampleInterval = 250 * time.Millisecond
+
+	// SpeedSmoothing is the weight of the newest sample in the moving average
+	SpeedSmoothing = 0.3
+)
+
+// ============================================================================
+// RATE METER
+// ============================================================================
+
+// rateMeter computes instantaneous and moving-average throughput
+// It is not thread-safe; the owning Transfer guards it with its mutex
+type rateMeter struct {
+	lastSample   time.Time
+	lastBytes    int64
+	instantSpeed float64 // bytes per second over the last sample window
+	averageSpeed float64 // exponentially weighted moving average
+}
+
+// newRateMeter creates a rate meter starting at the given time
+func newRateMeter(start time.Time) rateMeter {
+	return rateMeter{lastSample: start}
+}
+
+// observe records the cumulative byte count at time now
+// Samples closer together than SpeedSampleInterval are folded into the next one
+func (m *rateMeter) observe(total int64, now time.Time) {
+	elapsed := now.Sub(m.lastSample)
+	if elapsed < SpeedSampleInterval {
+		return
+	}
+
+	m.instantSpeed = float64(total-m.lastBytes) / elapsed.Seconds()
+	if m.averageSpeed == 0 {
+		m.averageSpeed = m.instantSpeed
+	} else {
+		m.averageSpeed = SpeedSmoothing*m.instantSpeed + (1-SpeedSmoothing)*m.averageSpeed
+	}
+
+	m.lastSample = now
+	m.lastBytes = total
+}
+
+// eta estimates the remaining time for the given number of bytes
+// Returns 0 when no speed has been observed yet
+func (m *rateMeter) eta(remaining int64) time.Duration {
+	if m.averageSpeed <= 0 || remaining <= 0 {
+		return 0
+	}
+	return time.Duration(float64(remaining) / m.averageSpeed * float64(time.Second))
+}
+
+// ============================================================================
+// PER-PEER THROUGHPUT
+// ============================================================================
+
+// PeerThroughput holds historical throughput for a single peer
+type PeerThroughput struct {
+	PeerID         string    `json:"peer_id"`
+	Transfers      int       `json:"transfers"`
+	BytesUploaded  int64     `json:"bytes_uploaded"`
+	BytesReceived  int64     `json:"bytes_received"`
+	TotalDuration  float64   `json:"total_duration_seconds"`
+	AverageSpeed   float64   `json:"average_speed"` // total bytes / total duration
+	RecentSpeed    float64   `json:"recent_speed"`  // moving average across transfers
+	LastTransferAt time.Time `json:"last_transfer_at"`
+}
+
+// throughputTracker aggregates completed transfers by peer
+type throughputTracker struct {
+	peers map[string]*PeerThroughput
+	mutex sync.RWMutex
+}
+
+// newThroughputTracker creates an empty tracker
+func newThroughputTracker() *throughputTracker {
+	return &throughputTracker{
+		peers: make(map[string]*PeerThroughput),
+	}
+}
+
+// record adds a completed transfer to the peer's history
+func (tt *throughputTracker) record(snap TransferSnapshot) {
+	duration := snap.EndTime.Sub(snap.StartTime).Seconds()
+	if duration <= 0 || snap.SentBytes <= 0 {
+		return
+	}
+	speed := float64(snap.SentBytes) / duration
+
+	tt.mutex.Lock()
+	defer tt.mutex.Unlock()
+
+	pt, exists := tt.peers[snap.PeerID]
+	if !exists {
+		pt = &PeerThroughput{PeerID: snap.PeerID}
+		tt.peers[snap.PeerID] = pt
+	}
+
+	pt.Transfers++
+	if snap.Direction == "upload" {
+		pt.BytesUploaded += snap.SentBytes
+	} else {
+		pt.BytesReceived += snap.SentBytes
+	}
+	pt.TotalDuration += duration
+	pt.AverageSpeed = float64(pt.BytesUploaded+pt.BytesReceived) / pt.TotalDuration
+	if pt.RecentSpeed == 0 {
+		pt.RecentSpeed = speed
+	} else {
+		pt.RecentSpeed = SpeedSmoothing*speed + (1-SpeedSmoothing)*pt.RecentSpeed
+	}
+	pt.LastTransferAt = snap.EndTime
+}
+
+// get returns a copy of a peer's throughput history
+func (tt *throughputTracker) get(peerID string) (PeerThroughput, bool) {
+	tt.mutex.RLock()
+	defer tt.mutex.RUnlock()
+
+	pt, exists := tt.peers[peerID]
+	if !exists {
+		return PeerThroughput{}, false
+	}
+	return *pt, true
+}
+
+// all returns copies of every peer's throughput, fastest first
+func (tt *throughputTracker) all() []PeerThroughput {
+	tt.mutex.RLock()
+	result := make([]PeerThroughput, 0, len(tt.peers))
+	for _, pt := range tt.peers {
+		result = append(result, *pt)
+	}
+	tt.mutex.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].RecentSpeed > result[j].RecentSpeed
+	})
+	return result
+}
+
+// ============================================================================
+// TRANSFER MANAGER ACCESSORS
+// ============================================================================
+
+// GetPeerThroughput returns historical throughput for a peer
+func (tm *TransferManager) GetPeerThroughput(peerID string) (PeerThroughput, bool) {
+	return tm.throughput.get(peerID)
+}
+
+// GetAllPeerThroughput returns throughput for all peers, fastest first
+func (tm *TransferManager) GetAllPeerThroughput() []PeerThroughput {
+	return tm.throughput.all()
+}
+
+// RankPeersBySpeed orders candidate peers by their recent throughput
+// Peers without history keep their relative order after all known peers
+// Parameters:
+//   - peerIDs: Candidate seeders for a file
+//
+// Returns:
+//   - []string: The same peers, fastest first
+func (tm *TransferManager) RankPeersBySpeed(peerIDs []string) []string {
+	ranked := make([]string, len(peerIDs))
+	copy(ranked, peerIDs)
+
+	speeds := make(map[string]float64, len(ranked))
+	for _, id := range ranked {
+		if pt, exists := tm.throughput.get(id); exists {
+			speeds[id] = pt.RecentSpeed
+		}
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return speeds[ranked[i]] > speeds[ranked[j]]
+	})
+	return ranked
+}
@@ -15,14 +15,20 @@ Go Concepts Used:
 package library
 
 import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"knowledge-exchange/models"
 	"knowledge-exchange/utils"
 )
 
@@ -54,6 +60,10 @@ type TransferRequest struct {
 	CID         string    `json:"cid"`
 	RequesterID string    `json:"requester_id"`
 	Timestamp   time.Time `json:"timestamp"`
+
+	// AcceptCodecs lists compression codecs the requester can decode,
+	// most preferred first. Empty means uncompressed only.
+	AcceptCodecs []string `json:"accept_codecs,omitempty"`
 }
 
 // TransferResponse represents the response to a transfer request
@@ -61,17 +71,47 @@ type TransferResponse struct {
 	CID      string `json:"cid"`
 	Accepted bool   `json:"accepted"`
 	Reason   string `json:"reason,omitempty"`
-	FileSize int64  `json:"file_size"`
-	Checksum string `json:"checksum"`
+	FileSize int64  `json:"file_size"` // Uncompressed size
+	Checksum string `json:"checksum"`  // Checksum of the uncompressed content
+	Codec    string `json:"codec,omitempty"`
+	PeerID   string `json:"peer_id,omitempty"` // Serving peer's ID
 }
 
 // Transfer represents an active file transfer
+// Identity fields are fixed at creation; mutable state is guarded by mutex
+// and must be read through Snapshot
 type Transfer struct {
+	ID         string
+	CID        string
+	FileName   string
+	PeerID     string
+	Direction  string // "upload" or "download"
+	Codec      string // Negotiated wire compression codec
+	TotalBytes int64
+	StartTime  time.Time
+
+	// Mutable state
+	status    string
+	sentBytes int64
+	endTime   time.Time
+	errMsg    string
+	meter     rateMeter
+	mutex     sync.RWMutex
+
+	// cancelChan is closed when the transfer is cancelled
+	cancelChan chan struct{}
+	cancelOnce sync.Once
+}
+
+// TransferSnapshot is a point-in-time copy of a transfer's state
+// Snapshots are safe to share across goroutines and to encode as JSON
+type TransferSnapshot struct {
 	ID         string    `json:"id"`
 	CID        string    `json:"cid"`
 	FileName   string    `json:"file_name"`
 	PeerID     string    `json:"peer_id"`
-	Direction  string    `json:"direction"` // "upload" or "download"
+	Direction  string    `json:"direction"`
+	Codec      string    `json:"codec"`
 	Status     string    `json:"status"`
 	TotalBytes int64     `json:"total_bytes"`
 	SentBytes  int64     `json:"sent_bytes"`
@@ -79,6 +119,135 @@ type Transfer struct {
 	EndTime    time.Time `json:"end_time,omitempty"`
 	Error      string    `json:"error,omitempty"`
 	Progress   float64   `json:"progress"`
+
+	// Throughput metrics (bytes per second)
+	Speed        float64 `json:"speed"`
+	AverageSpeed float64 `json:"average_speed"`
+	ETASeconds   float64 `json:"eta_seconds"`
+}
+
+// ErrTransferCancelled is returned when a transfer is cancelled mid-stream
+var ErrTransferCancelled = errors.New("transfer cancelled")
+
+// ErrNotHeldLocally is returned when this node has no copy of a file to serve
+var ErrNotHeldLocally = errors.New("file not available locally")
+
+// transferSeq keeps IDs unique for transfers of one file started at once
+var transferSeq atomic.Int64
+
+// newTransfer creates an active transfer record
+func newTransfer(cid, fileName, peerID, direction, codec string, totalBytes int64) *Transfer {
+	now := time.Now()
+	return &Transfer{
+		ID:         utils.HashString(fmt.Sprintf("%s-%d-%d", cid, now.UnixNano(), transferSeq.Add(1))),
+		CID:        cid,
+		FileName:   fileName,
+		PeerID:     peerID,
+		Direction:  direction,
+		Codec:      codec,
+		TotalBytes: totalBytes,
+		StartTime:  now,
+		status:     TransferActive,
+		meter:      newRateMeter(now),
+		cancelChan: make(chan struct{}),
+	}
+}
+
+// Snapshot returns a consistent copy of the transfer state
+func (t *Transfer) Snapshot() TransferSnapshot {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	snap := TransferSnapshot{
+		ID:         t.ID,
+		CID:        t.CID,
+		FileName:   t.FileName,
+		PeerID:     t.PeerID,
+		Direction:  t.Direction,
+		Codec:      t.Codec,
+		Status:     t.status,
+		TotalBytes: t.TotalBytes,
+		SentBytes:  t.sentBytes,
+		StartTime:  t.StartTime,
+		EndTime:    t.endTime,
+		Error:      t.errMsg,
+	}
+	if t.TotalBytes > 0 {
+		snap.Progress = float64(t.sentBytes) / float64(t.TotalBytes) * 100
+	}
+
+	if t.status == TransferActive {
+		snap.Speed = t.meter.instantSpeed
+		snap.AverageSpeed = t.meter.averageSpeed
+		snap.ETASeconds = t.meter.eta(t.TotalBytes - t.sentBytes).Seconds()
+	} else if elapsed := t.endTime.Sub(t.StartTime).Seconds(); elapsed > 0 {
+		// Finished transfers report their overall speed
+		snap.AverageSpeed = float64(t.sentBytes) / elapsed
+	}
+	return snap
+}
+
+// Status returns the current transfer status
+func (t *Transfer) Status() string {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return t.status
+}
+
+// addBytes records transferred bytes and updates the rate meter
+// Returns the new total and the current moving-average speed
+func (t *Transfer) addBytes(n int64) (int64, float64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.sentBytes += n
+	t.meter.observe(t.sentBytes, time.Now())
+	return t.sentBytes, t.meter.averageSpeed
+}
+
+// finish moves an active transfer into a terminal state
+// Returns false if the transfer had already finished (e.g. was cancelled)
+func (t *Transfer) finish(status, errMsg string) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.status != TransferActive {
+		return false
+	}
+	t.status = status
+	t.errMsg = errMsg
+	t.endTime = time.Now()
+	return true
+}
+
+// fail marks the transfer as failed with the given error
+func (t *Transfer) fail(err error) {
+	t.finish(TransferFailed, err.Error())
+}
+
+// abort fails a transfer that is still active when its goroutine exits
+// Every normal exit path sets the final status itself, so this only catches
+// paths that returned without one
+func (t *Transfer) abort() {
+	t.finish(TransferFailed, "transfer ended before completing")
+}
+
+// cancel marks the transfer as cancelled and signals the streaming goroutine
+func (t *Transfer) cancel() bool {
+	if !t.finish(TransferCancelled, "") {
+		return false
+	}
+	t.cancelOnce.Do(func() { close(t.cancelChan) })
+	return true
+}
+
+// isCancelled reports whether the transfer has been cancelled
+func (t *Transfer) isCancelled() bool {
+	select {
+	case <-t.cancelChan:
+		return true
+	default:
+		return false
+	}
 }
 
 // ============================================================================
@@ -91,7 +260,8 @@ type ProgressUpdate struct {
 	BytesSent  int64
 	TotalBytes int64
 	Progress   float64
-	Speed      float64 // bytes per second
+	Speed      float64 // moving-average bytes per second
+	ETA        time.Duration
 }
 
 // ============================================================================
@@ -109,17 +279,30 @@ type TransferManager struct {
 	// Semaphore for concurrent transfer limiting
 	semaphore chan struct{}
 
-	// Mutex for thread-safe operations
+	// Mutex for thread-safe access to the transfers map
 	mutex sync.RWMutex
 
 	// Indexer reference for file access
 	indexer *Indexer
 
-	// Stats
-	totalUploads    int64
-	totalDownloads  int64
-	bytesUploaded   int64
-	bytesDownloaded int64
+	// Historical per-peer throughput
+	throughput *throughputTracker
+
+	// localPeerID identifies this peer in responses and completion reports
+	localPeerID string
+
+	// onComplete is called with every successfully completed transfer
+	onComplete func(TransferSnapshot)
+
+	// deliveries maps "peerID|cid" to the completed download that gave the
+	// local peer a verified copy of the file
+	deliveries map[string]string
+
+	// Stats (updated atomically from transfer goroutines)
+	totalUploads    atomic.Int64
+	totalDownloads  atomic.Int64
+	bytesUploaded   atomic.Int64
+	bytesDownloaded atomic.Int64
 }
 
 // ============================================================================
@@ -133,9 +316,33 @@ func NewTransferManager(indexer *Indexer) *TransferManager {
 		progressChan: make(chan ProgressUpdate, 100),
 		semaphore:    make(chan struct{}, MaxConcurrentTransfers),
 		indexer:      indexer,
+		throughput:   newThroughputTracker(),
+		deliveries:   make(map[string]string),
 	}
 }
 
+// SetLocalPeerID sets the peer ID this manager serves files as
+func (tm *TransferManager) SetLocalPeerID(peerID string) {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+	tm.localPeerID = peerID
+}
+
+// SetCompletionHandler registers a callback for completed transfers
+// The callback runs on the transfer goroutine and must not block
+func (tm *TransferManager) SetCompletionHandler(handler func(TransferSnapshot)) {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+	tm.onComplete = handler
+}
+
+// LocalPeerID returns the peer ID this manager serves files as
+func (tm *TransferManager) LocalPeerID() string {
+	tm.mutex.RLock()
+	defer tm.mutex.RUnlock()
+	return tm.localPeerID
+}
+
 // ============================================================================
 // UPLOAD METHODS
 // ============================================================================
@@ -172,20 +379,14 @@ func (tm *TransferManager) HandleUploadRequest(conn net.Conn, request *TransferR
 		})
 	}
 
+	// Pick a wire codec; already-compressed formats are sent as-is
+	codec := NegotiateCodec(request.AcceptCodecs, file.FileType)
+
 	// Create transfer record
-	transfer := &Transfer{
-		ID:         utils.HashString(fmt.Sprintf("%s-%d", request.CID, time.Now().UnixNano())),
-		CID:        request.CID,
-		FileName:   file.FileName,
-		PeerID:     request.RequesterID,
-		Direction:  "upload",
-		Status:     TransferActive,
-		TotalBytes: file.Size,
-		StartTime:  time.Now(),
-	}
+	transfer := newTransfer(request.CID, file.FileName, request.RequesterID, "upload", codec, file.Size)
 
 	tm.addTransfer(transfer)
-	defer tm.completeTransfer(transfer.ID)
+	defer transfer.abort()
 
 	// Send acceptance response
 	err := tm.sendResponse(conn, &TransferResponse{
@@ -193,67 +394,120 @@ func (tm *TransferManager) HandleUploadRequest(conn net.Conn, request *TransferR
 		Accepted: true,
 		FileSize: file.Size,
 		Checksum: file.Checksum,
+		Codec:    codec,
+		PeerID:   tm.LocalPeerID(),
 	})
 	if err != nil {
-		transfer.Status = TransferFailed
-		transfer.Error = err.Error()
+		transfer.fail(err)
 		return err
 	}
 
-	// Open and stream file
-	return tm.streamFile(conn, filePath, transfer)
+	// Open and stream file; the requester ID is only what the remote peer
+	// claims, so no peer is recorded as holding the copy
+	return tm.streamFile(conn, filePath, file.Checksum, "", transfer)
 }
 
-// streamFile streams a file over the connection
-func (tm *TransferManager) streamFile(conn net.Conn, filePath string, transfer *Transfer) error {
+// ServeDownload streams a file this node holds to a peer whose identity
+// the caller has verified, such as a user downloading through the gateway
+// Once every byte is written and matches the file's checksum, the peer is
+// recorded as holding a verified copy of the file
+// Parameters:
+//   - w: Where the file content is written
+//   - file: The file to send
+//   - peerID: ID of the receiving peer
+//
+// Returns:
+//   - error: ErrNotHeldLocally, or an error if the transfer fails
+func (tm *TransferManager) ServeDownload(w io.Writer, file *models.AcademicFile, peerID string) error {
+	filePath, exists := tm.indexer.GetLocalFilePath(file.CID)
+	if !exists {
+		return ErrNotHeldLocally
+	}
+
+	// Acquire semaphore (limits concurrent transfers)
+	tm.semaphore <- struct{}{}
+	defer func() { <-tm.semaphore }()
+
+	transfer := newTransfer(file.CID, file.FileName, peerID, "upload", CodecNone, file.Size)
+
+	tm.addTransfer(transfer)
+	defer transfer.abort()
+
+	return tm.streamFile(w, filePath, file.Checksum, peerID, transfer)
+}
+
+// streamFile streams a file to the receiving peer
+// The content is hashed as it is read; a copy that doesn't match checksum
+// fails the transfer. holder is the receiving peer to record as holding a
+// verified copy, or "" to record none
+func (tm *TransferManager) streamFile(w io.Writer, filePath, checksum, holder string, transfer *Transfer) error {
 	// Open the file
 	file, err := os.Open(filePath)
 	if err != nil {
+		transfer.fail(err)
 		return fmt.Errorf("failed to open file: %w", err)
 	}
 	defer file.Close()
 
+	// Wrap the connection with the negotiated codec
+	out, err := newCompressWriter(w, transfer.Codec)
+	if err != nil {
+		transfer.fail(err)
+		return err
+	}
+
 	// Create buffer for reading
 	buffer := make([]byte, TransferBufferSize)
+	hash := sha256.New()
 
 	// Stream the file
 	for {
+		// Stop if the transfer was cancelled
+		if transfer.isCancelled() {
+			return ErrTransferCancelled
+		}
+
 		// Read from file
 		n, err := file.Read(buffer)
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			transfer.Status = TransferFailed
-			transfer.Error = err.Error()
+			transfer.fail(err)
 			return fmt.Errorf("failed to read file: %w", err)
 		}
+		hash.Write(buffer[:n])
 
-		// Write to connection
-		_, err = conn.Write(buffer[:n])
+		// Write to connection (progress counts uncompressed bytes)
+		_, err = out.Write(buffer[:n])
 		if err != nil {
-			transfer.Status = TransferFailed
-			transfer.Error = err.Error()
+			transfer.fail(err)
 			return fmt.Errorf("failed to send data: %w", err)
 		}
 
 		// Update progress
-		transfer.SentBytes += int64(n)
-		transfer.Progress = float64(transfer.SentBytes) / float64(transfer.TotalBytes) * 100
-
-		// Send progress update
-		tm.progressChan <- ProgressUpdate{
-			TransferID: transfer.ID,
-			BytesSent:  transfer.SentBytes,
-			TotalBytes: transfer.TotalBytes,
-			Progress:   transfer.Progress,
-		}
+		sent, speed := transfer.addBytes(int64(n))
+		tm.reportProgress(transfer, sent, speed)
+	}
+
+	// Flush the final compressed frame
+	if err := out.Close(); err != nil {
+		transfer.fail(err)
+		return fmt.Errorf("failed to flush data: %w", err)
+	}
+
+	// The local copy changed since it was indexed
+	if checksum != "" && hex.EncodeToString(hash.Sum(nil)) != checksum {
+		transfer.finish(TransferFailed, "Checksum verification failed")
+		return fmt.Errorf("checksum verification failed")
 	}
 
-	transfer.Status = TransferCompleted
-	transfer.EndTime = time.Now()
-	tm.totalUploads++
-	tm.bytesUploaded += transfer.TotalBytes
+	if !transfer.finish(TransferCompleted, "") {
+		return ErrTransferCancelled
+	}
+	tm.recordCompletion(transfer, holder)
+	tm.totalUploads.Add(1)
+	tm.bytesUploaded.Add(transfer.TotalBytes)
 
 	return nil
 }
@@ -285,9 +539,10 @@ func (tm *TransferManager) Download(peerAddress, cid, savePath, requesterID stri
 
 	// Send transfer request
 	request := &TransferRequest{
-		CID:         cid,
-		RequesterID: requesterID,
-		Timestamp:   time.Now(),
+		CID:          cid,
+		RequesterID:  requesterID,
+		Timestamp:    time.Now(),
+		AcceptCodecs: SupportedCodecs(),
 	}
 
 	requestData, err := json.Marshal(request)
@@ -306,11 +561,20 @@ func (tm *TransferManager) Download(peerAddress, cid, savePath, requesterID stri
 	}
 
 	// Receive response
-	responseMsg, err := utils.ReceiveMessage(conn)
+	// The file follows the response line directly, so read through a
+	// buffer that keeps any file bytes that arrived with it
+	reader := bufio.NewReaderSize(conn, TransferBufferSize)
+	conn.SetReadDeadline(time.Now().Add(utils.ReadTimeout))
+	line, err := reader.ReadBytes('\n')
 	if err != nil {
 		return fmt.Errorf("failed to receive response: %w", err)
 	}
+	conn.SetReadDeadline(time.Time{})
 
+	var responseMsg utils.Message
+	if err := json.Unmarshal(line, &responseMsg); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
 	var response TransferResponse
 	if err := json.Unmarshal(responseMsg.Payload, &response); err != nil {
 		return fmt.Errorf("failed to parse response: %w", err)
@@ -320,88 +584,106 @@ func (tm *TransferManager) Download(peerAddress, cid, savePath, requesterID stri
 		return fmt.Errorf("transfer rejected: %s", response.Reason)
 	}
 
-	// Create transfer record
-	transfer := &Transfer{
-		ID:         utils.HashString(fmt.Sprintf("%s-%d", cid, time.Now().UnixNano())),
-		CID:        cid,
-		PeerID:     peerAddress,
-		Direction:  "download",
-		Status:     TransferActive,
-		TotalBytes: response.FileSize,
-		StartTime:  time.Now(),
+	// Identify the serving peer by ID when it reports one
+	servingPeer := response.PeerID
+	if servingPeer == "" {
+		servingPeer = peerAddress
 	}
 
+	// Create transfer record
+	transfer := newTransfer(cid, "", servingPeer, "download", response.Codec, response.FileSize)
+
 	tm.addTransfer(transfer)
-	defer tm.completeTransfer(transfer.ID)
+	defer transfer.abort()
 
 	// Receive file
-	return tm.receiveFile(conn, savePath, response.FileSize, response.Checksum, transfer)
+	return tm.receiveFile(reader, savePath, response.FileSize, response.Checksum, transfer)
 }
 
 // receiveFile receives a file from a connection
-func (tm *TransferManager) receiveFile(conn net.Conn, savePath string, fileSize int64, checksum string, transfer *Transfer) error {
+func (tm *TransferManager) receiveFile(conn io.Reader, savePath string, fileSize int64, checksum string, transfer *Transfer) error {
 	// Create output file
 	file, err := os.Create(savePath)
 	if err != nil {
+		transfer.fail(err)
 		return fmt.Errorf("failed to create file: %w", err)
 	}
 	defer file.Close()
 
+	// Decode the negotiated codec; checksum is verified on the decoded content
+	in, err := newDecompressReader(conn, transfer.Codec)
+	if err != nil {
+		transfer.fail(err)
+		return fmt.Errorf("failed to open decoder: %w", err)
+	}
+	defer in.Close()
+
 	// Buffer for receiving
 	buffer := make([]byte, TransferBufferSize)
 	var received int64
 
 	// Receive data
 	for received < fileSize {
-		n, err := conn.Read(buffer)
-		if err == io.EOF {
+		// Stop if the transfer was cancelled
+		if transfer.isCancelled() {
+			file.Close()
+			os.Remove(savePath)
+			return ErrTransferCancelled
+		}
+
+		n, err := in.Read(buffer)
+		if n == 0 && err == io.EOF {
 			break
 		}
+		if err == io.EOF {
+			err = nil
+		}
 		if err != nil {
-			transfer.Status = TransferFailed
-			transfer.Error = err.Error()
+			transfer.fail(err)
 			return fmt.Errorf("failed to receive data: %w", err)
 		}
 
 		// Write to file
 		_, err = file.Write(buffer[:n])
 		if err != nil {
-			transfer.Status = TransferFailed
-			transfer.Error = err.Error()
+			transfer.fail(err)
 			return fmt.Errorf("failed to write data: %w", err)
 		}
 
-		received += int64(n)
-		transfer.SentBytes = received
-		transfer.Progress = float64(received) / float64(fileSize) * 100
+		var speed float64
+		received, speed = transfer.addBytes(int64(n))
+		tm.reportProgress(transfer, received, speed)
+	}
 
-		// Send progress update
-		tm.progressChan <- ProgressUpdate{
-			TransferID: transfer.ID,
-			BytesSent:  received,
-			TotalBytes: fileSize,
-			Progress:   transfer.Progress,
-		}
+	// The sender closed the connection early
+	if received < fileSize {
+		file.Close()
+		os.Remove(savePath)
+		err := fmt.Errorf("connection closed after %d of %d bytes", received, fileSize)
+		transfer.fail(err)
+		return err
 	}
 
 	// Verify checksum
 	computedChecksum, err := utils.HashFile(savePath)
 	if err != nil {
+		transfer.fail(err)
 		return fmt.Errorf("failed to compute checksum: %w", err)
 	}
 
 	if computedChecksum != checksum {
 		// Remove corrupted file
 		os.Remove(savePath)
-		transfer.Status = TransferFailed
-		transfer.Error = "Checksum verification failed"
+		transfer.finish(TransferFailed, "Checksum verification failed")
 		return fmt.Errorf("checksum verification failed")
 	}
 
-	transfer.Status = TransferCompleted
-	transfer.EndTime = time.Now()
-	tm.totalDownloads++
-	tm.bytesDownloaded += fileSize
+	if !transfer.finish(TransferCompleted, "") {
+		return ErrTransferCancelled
+	}
+	tm.recordCompletion(transfer, tm.LocalPeerID())
+	tm.totalDownloads.Add(1)
+	tm.bytesDownloaded.Add(fileSize)
 
 	return nil
 }
@@ -417,52 +699,112 @@ func (tm *TransferManager) sendResponse(conn net.Conn, response *TransferRespons
 		return err
 	}
 
+	sender := tm.LocalPeerID()
+	if sender == "" {
+		sender = "local"
+	}
+
 	msg := &utils.Message{
 		Type:    utils.MsgTypeResponse,
-		Sender:  "local",
+		Sender:  sender,
 		Payload: data,
 	}
 
 	return utils.SendMessage(conn, msg)
 }
 
-// addTransfer adds a transfer to the active list
-func (tm *TransferManager) addTransfer(t *Transfer) {
-	tm.mutex.Lock()
-	defer tm.mutex.Unlock()
-	tm.transfers[t.ID] = t
+// reportProgress publishes a progress update without blocking the transfer
+// Updates are dropped if no one is draining the progress channel
+func (tm *TransferManager) reportProgress(transfer *Transfer, sent int64, speed float64) {
+	update := ProgressUpdate{
+		TransferID: transfer.ID,
+		BytesSent:  sent,
+		TotalBytes: transfer.TotalBytes,
+		Speed:      speed,
+	}
+	if transfer.TotalBytes > 0 {
+		update.Progress = float64(sent) / float64(transfer.TotalBytes) * 100
+	}
+	if speed > 0 && sent < transfer.TotalBytes {
+		update.ETA = time.Duration(float64(transfer.TotalBytes-sent) / speed * float64(time.Second))
+	}
+
+	select {
+	case tm.progressChan <- update:
+	default:
+	}
 }
 
-// completeTransfer marks a transfer as complete
-func (tm *TransferManager) completeTransfer(id string) {
+// recordCompletion updates throughput history, remembers which peer now
+// holds a verified copy, and notifies the completion handler
+// holder is "" when no peer's copy can be vouched for: a peer we upload to
+// over the network only claims its ID and never confirms the checksum
+func (tm *TransferManager) recordCompletion(transfer *Transfer, holder string) {
+	snap := transfer.Snapshot()
+	tm.throughput.record(snap)
+
 	tm.mutex.Lock()
-	defer tm.mutex.Unlock()
+	if holder != "" {
+		tm.deliveries[deliveryKey(holder, snap.CID)] = snap.ID
+	}
+	handler := tm.onComplete
+	tm.mutex.Unlock()
 
-	if t, exists := tm.transfers[id]; exists {
-		if t.Status == TransferActive {
-			t.Status = TransferCompleted
-		}
-		t.EndTime = time.Now()
+	if handler != nil {
+		handler(snap)
 	}
 }
 
-// GetTransfer returns a transfer by ID
-func (tm *TransferManager) GetTransfer(id string) (*Transfer, bool) {
+// CompletedDownload returns the transfer through which a peer obtained a file
+// Returns false if the peer has no verified completed download of the CID
+func (tm *TransferManager) CompletedDownload(peerID, cid string) (string, bool) {
 	tm.mutex.RLock()
 	defer tm.mutex.RUnlock()
+
+	transferID, exists := tm.deliveries[deliveryKey(peerID, cid)]
+	return transferID, exists
+}
+
+// deliveryKey builds the deliveries map key for a peer and file
+func deliveryKey(peerID, cid string) string {
+	return peerID + "|" + cid
+}
+
+// addTransfer adds a transfer to the active list
+func (tm *TransferManager) addTransfer(t *Transfer) {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+	tm.transfers[t.ID] = t
+}
+
+// GetTransfer returns a snapshot of a transfer by ID
+func (tm *TransferManager) GetTransfer(id string) (TransferSnapshot, bool) {
+	tm.mutex.RLock()
 	t, exists := tm.transfers[id]
-	return t, exists
+	tm.mutex.RUnlock()
+
+	if !exists {
+		return TransferSnapshot{}, false
+	}
+	return t.Snapshot(), true
 }
 
-// GetActiveTransfers returns all active transfers
-func (tm *TransferManager) GetActiveTransfers() []*Transfer {
+// GetActiveTransfers returns snapshots of all active transfers
+func (tm *TransferManager) GetActiveTransfers() []TransferSnapshot {
 	tm.mutex.RLock()
 	defer tm.mutex.RUnlock()
 
-	var active []*Transfer
+	return tm.activeSnapshotsLocked()
+}
+
+// activeSnapshotsLocked collects active transfer snapshots
+// Caller must hold tm.mutex
+func (tm *TransferManager) activeSnapshotsLocked() []TransferSnapshot {
+	var active []TransferSnapshot
 	for _, t := range tm.transfers {
-		if t.Status == TransferActive {
-			active = append(active, t)
+		snap := t.Snapshot()
+		if snap.Status == TransferActive {
+			active = append(active, snap)
 		}
 	}
 	return active
@@ -476,32 +818,40 @@ func (tm *TransferManager) GetProgressChannel() <-chan ProgressUpdate {
 // GetStats returns transfer statistics
 func (tm *TransferManager) GetStats() map[string]interface{} {
 	tm.mutex.RLock()
-	defer tm.mutex.RUnlock()
+	active := tm.activeSnapshotsLocked()
+	tm.mutex.RUnlock()
+
+	// Aggregate current throughput across active transfers
+	var currentSpeed float64
+	for _, snap := range active {
+		currentSpeed += snap.AverageSpeed
+	}
 
 	return map[string]interface{}{
-		"total_uploads":    tm.totalUploads,
-		"total_downloads":  tm.totalDownloads,
-		"bytes_uploaded":   tm.bytesUploaded,
-		"bytes_downloaded": tm.bytesDownloaded,
-		"active_transfers": len(tm.GetActiveTransfers()),
+		"total_uploads":    tm.totalUploads.Load(),
+		"total_downloads":  tm.totalDownloads.Load(),
+		"bytes_uploaded":   tm.bytesUploaded.Load(),
+		"bytes_downloaded": tm.bytesDownloaded.Load(),
+		"active_transfers": len(active),
+		"current_speed":    currentSpeed,
+		"peer_throughput":  tm.throughput.all(),
 	}
 }
 
 // CancelTransfer cancels an active transfer
+// The streaming goroutine observes the cancellation before its next chunk
 func (tm *TransferManager) CancelTransfer(id string) error {
-	tm.mutex.Lock()
-	defer tm.mutex.Unlock()
-
+	tm.mutex.RLock()
 	t, exists := tm.transfers[id]
+	tm.mutex.RUnlock()
+
 	if !exists {
 		return fmt.Errorf("transfer not found: %s", id)
 	}
 
-	if t.Status != TransferActive {
+	if !t.cancel() {
 		return fmt.Errorf("transfer is not active")
 	}
 
-	t.Status = TransferCancelled
-	t.EndTime = time.Now()
 	return nil
 }
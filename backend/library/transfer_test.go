@@ -0,0 +1,420 @@
+/*
+================================================================================
+TRANSFER SERVICE TESTS - P2P Academic Library "The Knowledge Exchange"
+================================================================================
+Exercises the transfer manager over real loopback connections: parallel
+transfers, cancellation mid-stream and stats read while transfers run.
+Run with -race.
+================================================================================
+*/
+
+package library
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"knowledge-exchange/utils"
+)
+
+// ============================================================================
+// HELPERS
+// ============================================================================
+
+// peerServer serves a transfer manager's files on a loopback listener
+type peerServer struct {
+	addr     string
+	listener net.Listener
+	handlers sync.WaitGroup // One per accepted connection
+}
+
+// servePeer accepts transfer requests for tm until the test ends
+// wrap, if set, replaces the connection handed to HandleUploadRequest
+func servePeer(t *testing.T, tm *TransferManager, wrap func(net.Conn) net.Conn) *peerServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	server := &peerServer{addr: listener.Addr().String(), listener: listener}
+	t.Cleanup(func() {
+		listener.Close()
+		server.handlers.Wait()
+	})
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			server.handlers.Add(1)
+			go func() {
+				defer server.handlers.Done()
+				defer conn.Close()
+
+				msg, err := utils.ReceiveMessage(conn)
+				if err != nil {
+					return
+				}
+				var request TransferRequest
+				if err := json.Unmarshal(msg.Payload, &request); err != nil {
+					return
+				}
+				if wrap != nil {
+					conn = wrap(conn)
+				}
+				tm.HandleUploadRequest(conn, &request)
+			}()
+		}
+	}()
+
+	return server
+}
+
+// shareFile writes random content to a shared file and indexes it
+func shareFile(t *testing.T, tm *TransferManager, name string, size int) (string, []byte) {
+	t.Helper()
+
+	content := make([]byte, size)
+	rand.Read(content)
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+
+	file, err := tm.indexer.IndexFile(path, "owner-peer")
+	if err != nil {
+		t.Fatalf("index %s: %v", name, err)
+	}
+	return file.CID, content
+}
+
+// gatedConn lets the first writes through and holds the rest until release
+// is closed, so a test can act while a transfer is mid-stream
+type gatedConn struct {
+	net.Conn
+	writes  atomic.Int32
+	open    int32
+	release chan struct{}
+}
+
+func (c *gatedConn) Write(p []byte) (int, error) {
+	if c.writes.Add(1) > c.open {
+		<-c.release
+	}
+	return c.Conn.Write(p)
+}
+
+// waitForTransfer polls until an active transfer in a direction has sent
+// some bytes
+func waitForTransfer(t *testing.T, tm *TransferManager, direction string) TransferSnapshot {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, snap := range tm.GetActiveTransfers() {
+			if snap.Direction == direction && snap.SentBytes > 0 {
+				return snap
+			}
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("no active %s transfer", direction)
+	return TransferSnapshot{}
+}
+
+// ============================================================================
+// TESTS
+// ============================================================================
+
+// TestConcurrentTransfers runs more transfers than the concurrency limit at
+// once while other goroutines read stats and snapshots
+func TestConcurrentTransfers(t *testing.T) {
+	const transfers = 8
+	const size = 256*1024 + 123
+
+	server := NewTransferManager(NewIndexer(t.TempDir()))
+	server.SetLocalPeerID("server-peer")
+	var completions atomic.Int32
+	server.SetCompletionHandler(func(snap TransferSnapshot) {
+		if snap.Status != TransferCompleted || snap.SentBytes != size {
+			t.Errorf("completion handler got %s with %d bytes", snap.Status, snap.SentBytes)
+		}
+		completions.Add(1)
+	})
+	peer := servePeer(t, server, nil)
+
+	client := NewTransferManager(NewIndexer(t.TempDir()))
+	client.SetLocalPeerID("client-peer")
+
+	cids := make([]string, transfers)
+	contents := make([][]byte, transfers)
+	for i := range cids {
+		cids[i], contents[i] = shareFile(t, server, "notes"+string(rune('a'+i))+".pdf", size)
+	}
+
+	// Readers run until every transfer is done
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				server.GetStats()
+				client.GetStats()
+				for _, snap := range client.GetActiveTransfers() {
+					client.GetTransfer(snap.ID)
+				}
+				server.mutex.RLock()
+				for _, transfer := range server.transfers {
+					transfer.Snapshot()
+				}
+				server.mutex.RUnlock()
+			}
+		}()
+	}
+
+	saveDir := t.TempDir()
+	errs := make([]error, transfers)
+	var downloads sync.WaitGroup
+	for i := range cids {
+		downloads.Add(1)
+		go func(i int) {
+			defer downloads.Done()
+			errs[i] = client.Download(peer.addr, cids[i], filepath.Join(saveDir, cids[i]), "client-peer")
+		}(i)
+	}
+	downloads.Wait()
+	close(done)
+	readers.Wait()
+
+	for i, cid := range cids {
+		if errs[i] != nil {
+			t.Fatalf("download %d: %v", i, errs[i])
+		}
+		got, err := os.ReadFile(filepath.Join(saveDir, cid))
+		if err != nil || !bytes.Equal(got, contents[i]) {
+			t.Fatalf("download %d: content mismatch (%v)", i, err)
+		}
+		if _, ok := client.CompletedDownload("client-peer", cid); !ok {
+			t.Errorf("client has no completed download of %s", cid)
+		}
+		// Only the receiver verified the copy
+		if _, ok := server.CompletedDownload("client-peer", cid); ok {
+			t.Errorf("server recorded a delivery of %s it couldn't verify", cid)
+		}
+	}
+
+	// The server side finishes after the client has every byte
+	peer.listener.Close()
+	peer.handlers.Wait()
+
+	clientStats := client.GetStats()
+	if clientStats["total_downloads"] != int64(transfers) || clientStats["bytes_downloaded"] != int64(transfers*size) {
+		t.Errorf("client stats = %v", clientStats)
+	}
+	serverStats := server.GetStats()
+	if serverStats["total_uploads"] != int64(transfers) || serverStats["bytes_uploaded"] != int64(transfers*size) {
+		t.Errorf("server stats = %v", serverStats)
+	}
+	if serverStats["active_transfers"] != 0 || clientStats["active_transfers"] != 0 {
+		t.Errorf("transfers still active: server %v, client %v", serverStats["active_transfers"], clientStats["active_transfers"])
+	}
+	if got := completions.Load(); got != transfers {
+		t.Errorf("completion handler called %d times, want %d", got, transfers)
+	}
+}
+
+// TestCancelUploadMidTransfer cancels the serving side after the first chunk
+func TestCancelUploadMidTransfer(t *testing.T) {
+	server := NewTransferManager(NewIndexer(t.TempDir()))
+	server.SetCompletionHandler(func(snap TransferSnapshot) {
+		t.Errorf("completion handler called for cancelled transfer %s", snap.ID)
+	})
+
+	// Let the response and one chunk through, then hold the stream
+	gate := make(chan struct{})
+	peer := servePeer(t, server, func(conn net.Conn) net.Conn {
+		return &gatedConn{Conn: conn, open: 2, release: gate}
+	})
+
+	client := NewTransferManager(NewIndexer(t.TempDir()))
+	client.SetLocalPeerID("client-peer")
+	cid, _ := shareFile(t, server, "lecture.pdf", 1024*1024)
+	savePath := filepath.Join(t.TempDir(), "lecture.pdf")
+
+	result := make(chan error, 1)
+	go func() {
+		result <- client.Download(peer.addr, cid, savePath, "client-peer")
+	}()
+
+	upload := waitForTransfer(t, server, "upload")
+	if err := server.CancelTransfer(upload.ID); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	close(gate)
+
+	if err := <-result; err == nil {
+		t.Fatal("download of a cancelled upload succeeded")
+	}
+	peer.listener.Close()
+	peer.handlers.Wait()
+
+	if snap, _ := server.GetTransfer(upload.ID); snap.Status != TransferCancelled {
+		t.Errorf("upload status = %s, want %s", snap.Status, TransferCancelled)
+	}
+	if err := server.CancelTransfer(upload.ID); err == nil {
+		t.Error("cancelling a finished transfer succeeded")
+	}
+	for _, snap := range client.transfersSnapshot() {
+		if snap.Status != TransferFailed {
+			t.Errorf("download status = %s, want %s", snap.Status, TransferFailed)
+		}
+	}
+	if _, err := os.Stat(savePath); !os.IsNotExist(err) {
+		t.Error("partial download was left on disk")
+	}
+	if _, ok := client.CompletedDownload("client-peer", cid); ok {
+		t.Error("cancelled transfer counted as a completed download")
+	}
+	if stats := server.GetStats(); stats["total_uploads"] != int64(0) {
+		t.Errorf("server stats = %v", stats)
+	}
+	if stats := client.GetStats(); stats["total_downloads"] != int64(0) {
+		t.Errorf("client stats = %v", stats)
+	}
+}
+
+// TestCancelDownloadMidTransfer cancels the receiving side; the server's
+// upload fails once the client hangs up
+func TestCancelDownloadMidTransfer(t *testing.T) {
+	server := NewTransferManager(NewIndexer(t.TempDir()))
+	gate := make(chan struct{})
+	peer := servePeer(t, server, func(conn net.Conn) net.Conn {
+		return &gatedConn{Conn: conn, open: 2, release: gate}
+	})
+
+	client := NewTransferManager(NewIndexer(t.TempDir()))
+	cid, _ := shareFile(t, server, "slides.pdf", 4*1024*1024)
+	savePath := filepath.Join(t.TempDir(), "slides.pdf")
+
+	result := make(chan error, 1)
+	go func() {
+		result <- client.Download(peer.addr, cid, savePath, "client-peer")
+	}()
+
+	download := waitForTransfer(t, client, "download")
+	if err := client.CancelTransfer(download.ID); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	close(gate)
+
+	if err := <-result; !errors.Is(err, ErrTransferCancelled) {
+		t.Fatalf("download error = %v, want %v", err, ErrTransferCancelled)
+	}
+	peer.listener.Close()
+	peer.handlers.Wait()
+
+	if snap, _ := client.GetTransfer(download.ID); snap.Status != TransferCancelled {
+		t.Errorf("download status = %s, want %s", snap.Status, TransferCancelled)
+	}
+	for _, snap := range server.transfersSnapshot() {
+		if snap.Status != TransferFailed {
+			t.Errorf("upload status = %s, want %s", snap.Status, TransferFailed)
+		}
+	}
+	if _, err := os.Stat(savePath); !os.IsNotExist(err) {
+		t.Error("partial download was left on disk")
+	}
+}
+
+// TestTransferStateConcurrency races progress, snapshots and finishing on
+// one transfer; exactly one terminal state wins
+func TestTransferStateConcurrency(t *testing.T) {
+	const writers, chunks = 8, 100
+	transfer := newTransfer("cid", "file.pdf", "peer", "upload", CodecNone, writers*chunks)
+
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < chunks; j++ {
+				transfer.addBytes(1)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < chunks; j++ {
+				transfer.Snapshot()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if snap := transfer.Snapshot(); snap.SentBytes != writers*chunks || snap.Progress != 100 {
+		t.Fatalf("sent %d bytes (%.1f%%), want %d", snap.SentBytes, snap.Progress, writers*chunks)
+	}
+
+	var wins atomic.Int32
+	for i := 0; i < writers; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if transfer.cancel() {
+				wins.Add(1)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if transfer.finish(TransferCompleted, "") {
+				wins.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if wins.Load() != 1 {
+		t.Fatalf("%d goroutines finished the transfer, want 1", wins.Load())
+	}
+	final := transfer.Status()
+	transfer.abort()
+	if transfer.Status() != final {
+		t.Errorf("abort changed a finished transfer from %s to %s", final, transfer.Status())
+	}
+
+	active := newTransfer("cid", "file.pdf", "peer", "upload", CodecNone, 10)
+	active.abort()
+	if active.Status() != TransferFailed {
+		t.Errorf("aborted active transfer status = %s, want %s", active.Status(), TransferFailed)
+	}
+}
+
+// transfersSnapshot returns snapshots of every transfer, finished or not
+func (tm *TransferManager) transfersSnapshot() []TransferSnapshot {
+	tm.mutex.RLock()
+	defer tm.mutex.RUnlock()
+
+	snaps := make([]TransferSnapshot, 0, len(tm.transfers))
+	for _, transfer := range tm.transfers {
+		snaps = append(snaps, transfer.Snapshot())
+	}
+	return snaps
+}
@@ -0,0 +1,164 @@
+/*
+================================================================================
+MAILER - P2P Academic Library "The Knowledge Exchange"
+================================================================================
+This file implements outgoing email for account recovery and verification.
+
+Mail goes through the Mailer interface so the transport can be swapped:
+- SMTPMailer: Delivers through an SMTP server (STARTTLS when offered)
+- FileMailer: Writes each message to a file and the log, for local
+  development and testing without a mail server
+
+Go Concepts Used:
+- Interfaces: Pluggable transports
+- net/smtp: SMTP delivery
+- File I/O: Writing messages to disk
+================================================================================
+*/
+
+package mail
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ============================================================================
+// MAILER INTERFACE
+// ============================================================================
+
+// Message is a plain-text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends email
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// format renders a message with its headers
+func (m Message) format(from string) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", m.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", m.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(m.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// validate rejects header injection through the recipient or subject
+func (m Message) validate() error {
+	if m.To == "" {
+		return errors.New("recipient required")
+	}
+	if strings.ContainsAny(m.To, "\r\n") || strings.ContainsAny(m.Subject, "\r\n") {
+		return errors.New("invalid characters in message headers")
+	}
+	return nil
+}
+
+// ============================================================================
+// SMTP MAILER
+// ============================================================================
+
+// SMTPMailer delivers mail through an SMTP server
+type SMTPMailer struct {
+	host     string
+	port     int
+	username string
+	password string
+	from     string
+}
+
+// NewSMTPMailer creates an SMTP mailer
+// Parameters:
+//   - host, port: The SMTP server
+//   - username, password: Credentials; an empty username sends without auth
+//   - from: Sender address
+func NewSMTPMailer(host string, port int, username, password, from string) *SMTPMailer {
+	return &SMTPMailer{
+		host:     host,
+		port:     port,
+		username: username,
+		password: password,
+		from:     from,
+	}
+}
+
+// Send delivers a message
+func (m *SMTPMailer) Send(msg Message) error {
+	if err := msg.validate(); err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if m.username != "" {
+		auth = smtp.PlainAuth("", m.username, m.password, m.host)
+	}
+
+	address := net.JoinHostPort(m.host, strconv.Itoa(m.port))
+	return smtp.SendMail(address, auth, m.from, []string{msg.To}, msg.format(m.from))
+}
+
+// ============================================================================
+// FILE MAILER
+// ============================================================================
+
+// FileMailer writes messages to a directory instead of sending them
+// With an empty directory messages are only logged
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+// NewFileMailer creates a file mailer
+// Parameters:
+//   - dir: Directory for .eml files; "" only logs
+//   - from: Sender address written into the messages
+func NewFileMailer(dir, from string) *FileMailer {
+	return &FileMailer{
+		dir:  dir,
+		from: from,
+	}
+}
+
+// Send writes a message to a file and logs it
+func (m *FileMailer) Send(msg Message) error {
+	if err := msg.validate(); err != nil {
+		return err
+	}
+
+	if m.dir == "" {
+		log.Printf("Mail to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+		return nil
+	}
+
+	if err := os.MkdirAll(m.dir, 0700); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102-150405"), uuid.New().String()[:8])
+	path := filepath.Join(m.dir, name)
+	if err := os.WriteFile(path, msg.format(m.from), 0600); err != nil {
+		return err
+	}
+
+	log.Printf("Mail to %s (%s) written to %s", msg.To, msg.Subject, path)
+	return nil
+}
@@ -242,6 +242,16 @@ func (f *AcademicFile) AddRating(rating float64) {
 	f.AverageRating = (totalScore + rating) / float64(f.TotalRatings)
 }
 
+// SetRatingAggregate overwrites the rating aggregate
+// Used when ratings are edited or removed and the average must be recomputed
+// Parameters:
+//   - average: The recomputed average rating
+//   - total: The number of ratings remaining
+func (f *AcademicFile) SetRatingAggregate(average float64, total int) {
+	f.AverageRating = average
+	f.TotalRatings = total
+}
+
 // VerifyIntegrity checks if downloaded content matches the checksum
 // Parameters:
 //   - content: Downloaded file content
@@ -0,0 +1,80 @@
+/*
+================================================================================
+API KEY MODEL - P2P Academic Library "The Knowledge Exchange"
+================================================================================
+This file defines API keys: long-lived credentials for scripts and headless
+peers, limited to a set of scopes.
+
+Like refresh tokens only a hash of each key is kept; the key itself is shown
+once when it is created. The prefix is kept in the clear so users can tell
+their keys apart.
+
+Go Concepts Used:
+- Structs: Data models
+- Methods: Business logic on models
+- Time: Expiry handling
+================================================================================
+*/
+
+package models
+
+import "time"
+
+// ============================================================================
+// SCOPES
+// ============================================================================
+
+// API key scopes
+const (
+	ScopeReadFiles  = "read:files"  // Download files
+	ScopeWriteFiles = "write:files" // Upload files
+	ScopeRate       = "rate"        // Create, update and delete ratings
+	ScopeAdmin      = "admin"       // Admin endpoints (admins only)
+)
+
+// IsValidScope checks if a scope name is known
+func IsValidScope(scope string) bool {
+	switch scope {
+	case ScopeReadFiles, ScopeWriteFiles, ScopeRate, ScopeAdmin:
+		return true
+	}
+	return false
+}
+
+// ============================================================================
+// API KEY MODEL
+// ============================================================================
+
+// APIKey is a stored API key
+type APIKey struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Name      string    `json:"name"`
+	Prefix    string    `json:"prefix"` // First characters of the key, for display
+	KeyHash   string    `json:"key_hash"`
+	Scopes    []string  `json:"scopes"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	LastUsed  time.Time `json:"last_used,omitempty"`
+	RevokedAt time.Time `json:"revoked_at,omitempty"` // Zero while the key is live
+}
+
+// IsRevoked checks if the key was revoked
+func (k *APIKey) IsRevoked() bool {
+	return !k.RevokedAt.IsZero()
+}
+
+// IsExpired checks if the key is past its expiry
+func (k *APIKey) IsExpired() bool {
+	return time.Now().After(k.ExpiresAt)
+}
+
+// HasScope checks if the key was granted a scope
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
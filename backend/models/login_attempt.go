@@ -0,0 +1,55 @@
+/*
+================================================================================
+LOGIN ATTEMPT MODEL - P2P Academic Library "The Knowledge Exchange"
+================================================================================
+This file defines failed-login tracking and the security audit trail.
+
+Failed logins are counted per key, where a key is either an account
+("account:<email>") or a client address ("ip:<address>"). Each failure past
+the free attempts locks the key for exponentially longer.
+
+Go Concepts Used:
+- Structs: Data models
+- Methods: Business logic on models
+- Time: Lockout windows
+================================================================================
+*/
+
+package models
+
+import "time"
+
+// ============================================================================
+// LOGIN ATTEMPTS
+// ============================================================================
+
+// LoginAttempts tracks recent failed logins for one account or address
+type LoginAttempts struct {
+	Key         string    `json:"key"`
+	Failures    int       `json:"failures"`
+	LastFailure time.Time `json:"last_failure"`
+	LockedUntil time.Time `json:"locked_until,omitempty"`
+}
+
+// IsLocked checks if the key is locked out at a point in time
+func (a *LoginAttempts) IsLocked(now time.Time) bool {
+	return now.Before(a.LockedUntil)
+}
+
+// ============================================================================
+// SECURITY AUDIT
+// ============================================================================
+
+// Security event types
+const (
+	SecurityLockout = "LOCKOUT"
+	SecurityUnlock  = "UNLOCK"
+)
+
+// SecurityEvent is one entry in the security audit trail
+type SecurityEvent struct {
+	Type      string    `json:"type"`
+	Key       string    `json:"key"`
+	Detail    string    `json:"detail"`
+	Timestamp time.Time `json:"timestamp"`
+}
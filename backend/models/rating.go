@@ -53,8 +53,32 @@ type Rating struct {
 	// Comment is an optional text review
 	Comment string `json:"comment"`
 
+	// TransferID references the completed download a file rating is based on
+	// Empty when the rater holds the file locally instead
+	TransferID string `json:"transfer_id,omitempty"`
+
 	// Timestamp records when the rating was given
 	Timestamp time.Time `json:"timestamp"`
+
+	// UpdatedAt records the last edit, zero if never edited
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// Rating revision actions
+const (
+	RevisionCreate = "create"
+	RevisionUpdate = "update"
+	RevisionDelete = "delete"
+)
+
+// RatingRevision is one entry in a rating's audit history
+type RatingRevision struct {
+	RatingID      string    `json:"rating_id"`
+	Action        string    `json:"action"`
+	Score         float64   `json:"score"`
+	Comment       string    `json:"comment"`
+	PreviousScore float64   `json:"previous_score,omitempty"`
+	Timestamp     time.Time `json:"timestamp"`
 }
 
 // RatingStats holds aggregated rating statistics
@@ -154,6 +178,9 @@ type RatingStore struct {
 	// byTarget groups ratings by their target ID for quick lookup
 	byTarget map[string][]*Rating
 
+	// history holds the audit trail of every rating, including deleted ones
+	history map[string][]RatingRevision
+
 	// mutex provides thread-safe access
 	mutex sync.RWMutex
 }
@@ -163,6 +190,7 @@ func NewRatingStore() *RatingStore {
 	return &RatingStore{
 		ratings:  make(map[string]*Rating),
 		byTarget: make(map[string][]*Rating),
+		history:  make(map[string][]RatingRevision),
 	}
 }
 
@@ -182,9 +210,123 @@ func (rs *RatingStore) Add(rating *Rating) error {
 	// Add to target's rating list
 	rs.byTarget[rating.TargetID] = append(rs.byTarget[rating.TargetID], rating)
 
+	rs.history[rating.ID] = append(rs.history[rating.ID], RatingRevision{
+		RatingID:  rating.ID,
+		Action:    RevisionCreate,
+		Score:     rating.Score,
+		Comment:   rating.Comment,
+		Timestamp: rating.Timestamp,
+	})
+
 	return nil
 }
 
+// Update changes the score and comment of an existing rating
+// The stored rating is replaced rather than mutated so slices previously
+// returned by GetByTarget stay consistent
+// Returns:
+//   - *Rating: The rating as it was before the update
+//   - *Rating: The updated rating
+//   - error: If the rating doesn't exist or the new values are invalid
+func (rs *RatingStore) Update(id string, score float64, comment string) (*Rating, *Rating, error) {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+
+	old, exists := rs.ratings[id]
+	if !exists {
+		return nil, nil, &RatingError{Message: "Rating not found"}
+	}
+
+	updated := *old
+	updated.Score = score
+	updated.Comment = comment
+	updated.UpdatedAt = time.Now()
+
+	if valid, msg := updated.IsValid(); !valid {
+		return nil, nil, &RatingError{Message: msg}
+	}
+
+	rs.ratings[id] = &updated
+	rs.replaceInTargetLocked(old, &updated)
+
+	rs.history[id] = append(rs.history[id], RatingRevision{
+		RatingID:      id,
+		Action:        RevisionUpdate,
+		Score:         score,
+		Comment:       comment,
+		PreviousScore: old.Score,
+		Timestamp:     updated.UpdatedAt,
+	})
+
+	return old, &updated, nil
+}
+
+// Delete removes a rating, keeping its audit history
+// Returns:
+//   - *Rating: The removed rating
+//   - error: If the rating doesn't exist
+func (rs *RatingStore) Delete(id string) (*Rating, error) {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+
+	old, exists := rs.ratings[id]
+	if !exists {
+		return nil, &RatingError{Message: "Rating not found"}
+	}
+
+	delete(rs.ratings, id)
+	rs.replaceInTargetLocked(old, nil)
+
+	rs.history[id] = append(rs.history[id], RatingRevision{
+		RatingID:      id,
+		Action:        RevisionDelete,
+		PreviousScore: old.Score,
+		Timestamp:     time.Now(),
+	})
+
+	return old, nil
+}
+
+// replaceInTargetLocked rebuilds a target's rating list with old swapped
+// for replacement (or dropped when replacement is nil)
+// Caller must hold rs.mutex for writing
+func (rs *RatingStore) replaceInTargetLocked(old, replacement *Rating) {
+	current := rs.byTarget[old.TargetID]
+	rebuilt := make([]*Rating, 0, len(current))
+	for _, r := range current {
+		if r.ID != old.ID {
+			rebuilt = append(rebuilt, r)
+		} else if replacement != nil {
+			rebuilt = append(rebuilt, replacement)
+		}
+	}
+
+	if len(rebuilt) == 0 {
+		delete(rs.byTarget, old.TargetID)
+		return
+	}
+	rs.byTarget[old.TargetID] = rebuilt
+}
+
+// GetHistory returns the audit trail for a rating, oldest first
+func (rs *RatingStore) GetHistory(id string) []RatingRevision {
+	rs.mutex.RLock()
+	defer rs.mutex.RUnlock()
+
+	history := make([]RatingRevision, len(rs.history[id]))
+	copy(history, rs.history[id])
+	return history
+}
+
+// Get retrieves a rating by ID
+func (rs *RatingStore) Get(id string) (*Rating, bool) {
+	rs.mutex.RLock()
+	defer rs.mutex.RUnlock()
+
+	rating, exists := rs.ratings[id]
+	return rating, exists
+}
+
 // GetByTarget returns all ratings for a specific target
 func (rs *RatingStore) GetByTarget(targetID string) []*Rating {
 	rs.mutex.RLock()
@@ -254,6 +396,18 @@ func (rs *RatingStore) HasRated(raterID, targetID string) bool {
 	return false
 }
 
+// GetAll returns every rating in the store
+func (rs *RatingStore) GetAll() []*Rating {
+	rs.mutex.RLock()
+	defer rs.mutex.RUnlock()
+
+	all := make([]*Rating, 0, len(rs.ratings))
+	for _, rating := range rs.ratings {
+		all = append(all, rating)
+	}
+	return all
+}
+
 // Count returns the total number of ratings
 func (rs *RatingStore) Count() int {
 	rs.mutex.RLock()
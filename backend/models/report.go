@@ -0,0 +1,67 @@
+/*
+================================================================================
+REPORT MODEL - P2P Academic Library "The Knowledge Exchange"
+================================================================================
+This file defines reports: members flag a file or a peer, and moderators
+review the report and resolve it by dismissing it or taking action.
+
+Go Concepts Used:
+- Structs: Data models
+- Constants: Target types, statuses and actions
+- Time: Timestamp handling
+================================================================================
+*/
+
+package models
+
+import "time"
+
+// ============================================================================
+// CONSTANTS
+// ============================================================================
+
+// Report targets
+const (
+	ReportTargetFile = "file"
+	ReportTargetPeer = "peer"
+)
+
+// Report statuses
+const (
+	ReportOpen      = "open"
+	ReportResolved  = "resolved"  // Action was taken
+	ReportDismissed = "dismissed" // No action needed
+)
+
+// Report resolutions
+const (
+	ReportActionDismiss    = "dismiss"
+	ReportActionRemoveFile = "remove_file"
+	ReportActionBanPeer    = "ban_peer"
+)
+
+// ============================================================================
+// REPORT MODEL
+// ============================================================================
+
+// Report is a member's report of a file or peer
+type Report struct {
+	ID         string    `json:"id"`
+	TargetType string    `json:"target_type"` // ReportTargetFile or ReportTargetPeer
+	TargetID   string    `json:"target_id"`   // File CID or peer ID
+	Reason     string    `json:"reason"`
+	ReporterID string    `json:"reporter_id"` // User ID
+	CreatedAt  time.Time `json:"created_at"`
+
+	// Set when a moderator handles the report
+	Status    string    `json:"status"`
+	Action    string    `json:"action,omitempty"`
+	Note      string    `json:"note,omitempty"`
+	HandledBy string    `json:"handled_by,omitempty"` // User ID
+	HandledAt time.Time `json:"handled_at,omitempty"`
+}
+
+// IsOpen checks if the report still needs a moderator
+func (r *Report) IsOpen() bool {
+	return r.Status == ReportOpen
+}
@@ -0,0 +1,158 @@
+/*
+================================================================================
+ROLE MODEL - P2P Academic Library "The Knowledge Exchange"
+================================================================================
+This file defines permissions and the roles that group them.
+
+Every user has one role; what they may do is the role's permission set.
+The built-in roles are created on first start and can be edited through
+the admin API (except admin, which always holds every permission). Admins
+can also add their own roles.
+
+Go Concepts Used:
+- Constants: Permission names
+- Structs: Data models
+- Methods: Business logic on models
+================================================================================
+*/
+
+package models
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+)
+
+// ============================================================================
+// PERMISSIONS
+// ============================================================================
+
+// Permissions
+const (
+	PermUploadFiles   = "files:upload"   // Share files
+	PermDownloadFiles = "files:download" // Download files
+	PermRate          = "ratings:write"  // Rate files and peers
+	PermReport        = "reports:create" // Report files and peers to moderators
+	PermPinFiles      = "files:pin"      // Pin files as official course material
+	PermHandleReports = "reports:handle" // Review and resolve reports
+	PermRemoveFiles   = "files:remove"   // Remove files from the index
+	PermBanPeers      = "peers:ban"      // Kick and ban peers
+	PermManageUsers   = "users:manage"   // Accounts, sessions, lockouts, 2FA resets
+	PermManageRoles   = "roles:manage"   // Edit roles and assign them (admin-equivalent)
+	PermManageSystem  = "system:manage"  // Signing keys, reputation policy, Sybil scans
+)
+
+// AllPermissions returns every permission, in a stable order
+func AllPermissions() []string {
+	return []string{
+		PermUploadFiles,
+		PermDownloadFiles,
+		PermRate,
+		PermReport,
+		PermPinFiles,
+		PermHandleReports,
+		PermRemoveFiles,
+		PermBanPeers,
+		PermManageUsers,
+		PermManageRoles,
+		PermManageSystem,
+	}
+}
+
+// IsValidPermission checks if a permission name is known
+func IsValidPermission(permission string) bool {
+	for _, p := range AllPermissions() {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPrivilegedPermission checks if a permission goes beyond what every
+// member has (staff actions)
+func IsPrivilegedPermission(permission string) bool {
+	switch permission {
+	case PermUploadFiles, PermDownloadFiles, PermRate, PermReport:
+		return false
+	}
+	return true
+}
+
+// ============================================================================
+// ROLE MODEL
+// ============================================================================
+
+// Role is a named set of permissions
+type Role struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+	BuiltIn     bool     `json:"built_in"` // Built-in roles can't be deleted
+}
+
+// rolePattern limits role names to lowercase slugs
+var rolePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,31}$`)
+
+// Validate checks the role name and permissions, and sorts and dedupes
+// the permission list
+func (r *Role) Validate() error {
+	if !rolePattern.MatchString(r.Name) {
+		return errors.New("role name must be 2-32 lowercase letters, digits, '-' or '_'")
+	}
+
+	seen := make(map[string]bool)
+	perms := make([]string, 0, len(r.Permissions))
+	for _, p := range r.Permissions {
+		if !IsValidPermission(p) {
+			return errors.New("unknown permission: " + p)
+		}
+		if !seen[p] {
+			seen[p] = true
+			perms = append(perms, p)
+		}
+	}
+	sort.Strings(perms)
+	r.Permissions = perms
+	return nil
+}
+
+// HasPermission checks if the role grants a permission
+func (r *Role) HasPermission(permission string) bool {
+	for _, p := range r.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultRoles returns the built-in roles
+func DefaultRoles() []Role {
+	member := []string{PermUploadFiles, PermDownloadFiles, PermRate, PermReport}
+
+	return []Role{
+		{
+			Name:        RoleUser,
+			Description: "Student: shares, downloads and rates material",
+			Permissions: member,
+		},
+		{
+			Name:        RoleInstructor,
+			Description: "Course instructor: can also pin official material",
+			Permissions: append(append([]string{}, member...), PermPinFiles),
+		},
+		{
+			Name:        RoleModerator,
+			Description: "Moderator: handles reports, removes files and bans peers",
+			Permissions: append(append([]string{}, member...),
+				PermHandleReports, PermRemoveFiles, PermBanPeers),
+		},
+		{
+			Name:        RoleAdmin,
+			Description: "Administrator: every permission",
+			Permissions: AllPermissions(),
+		},
+	}
+}
@@ -0,0 +1,72 @@
+/*
+================================================================================
+SESSION MODEL - P2P Academic Library "The Knowledge Exchange"
+================================================================================
+This file defines the stored form of refresh tokens and of single-use
+action tokens (password reset, email verification, two-factor login).
+
+Only a hash of each refresh token is kept. Tokens rotate on every use: the
+used token records which token replaced it, and all tokens descended from
+one login share a FamilyID so a replayed token can revoke the whole chain.
+
+Go Concepts Used:
+- Structs: Data models
+- Methods: Business logic on models
+- Time: Expiry handling
+================================================================================
+*/
+
+package models
+
+import "time"
+
+// ============================================================================
+// REFRESH TOKEN MODEL
+// ============================================================================
+
+// RefreshToken is a stored refresh token
+type RefreshToken struct {
+	TokenHash  string    `json:"token_hash"`
+	UserID     string    `json:"user_id"`
+	FamilyID   string    `json:"family_id"`
+	IssuedAt   time.Time `json:"issued_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	ReplacedBy string    `json:"replaced_by,omitempty"` // Hash of the token issued on rotation
+	Revoked    bool      `json:"revoked"`
+}
+
+// IsExpired checks if the token is past its expiry
+func (t *RefreshToken) IsExpired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
+
+// IsUsed checks if the token has already been rotated
+func (t *RefreshToken) IsUsed() bool {
+	return t.ReplacedBy != ""
+}
+
+// ============================================================================
+// ACTION TOKEN MODEL
+// ============================================================================
+
+// Action token purposes
+const (
+	PurposePasswordReset = "password_reset"
+	PurposeVerifyEmail   = "verify_email"
+	PurposeLoginTOTP     = "login_totp" // Password accepted, waiting for the second factor
+)
+
+// ActionToken is a stored single-use token emailed to a user
+// Like refresh tokens only the hash is kept
+type ActionToken struct {
+	TokenHash string    `json:"token_hash"`
+	UserID    string    `json:"user_id"`
+	Purpose   string    `json:"purpose"`
+	Email     string    `json:"email"` // Address the token was sent to
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// IsExpired checks if the token is past its expiry
+func (t *ActionToken) IsExpired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
@@ -8,8 +8,12 @@ Go Concepts Used:
 - Structs: Custom data types that group related fields together
 - Pointers: Used to modify struct values across different functions
 - Methods: Functions attached to structs using receiver syntax
+- Mutex: Guarding fields the reputation service updates while handlers read
 - Type Inference: Using := for local variable initialization
-- Constants: Defining fixed values like reputation thresholds
+
+Reputation bounds and thresholds belong to the active reputation policy;
+the reputation service derives each student's score and leecher status and
+mirrors them here.
 ================================================================================
 */
 
@@ -21,20 +25,6 @@ import (
 	"time"
 )
 
-// ============================================================================
-// CONSTANTS - Define system thresholds
-// ============================================================================
-
-// MinReputation is the minimum reputation score required to download files
-// Students below this threshold are considered "leechers" and face restrictions
-const MinReputation float64 = 3.0
-
-// MaxReputation is the maximum reputation score a student can achieve
-const MaxReputation float64 = 10.0
-
-// DefaultReputation is the starting reputation for new peers
-const DefaultReputation float64 = 5.0
-
 // ============================================================================
 // STRUCT DEFINITION - The core Student data type
 // ============================================================================
@@ -48,11 +38,13 @@ type Student struct {
 	// Name is the display name of the student
 	Name string `json:"name"`
 
-	// ReputationScore determines download privileges (range: 0.0 to 10.0)
+	// ReputationScore determines download privileges, within the range set
+	// by the reputation policy
 	// Higher scores = more privileges, lower scores = throttling/restrictions
 	ReputationScore float64 `json:"reputation_score"`
 
-	// IsLeecher indicates if the student downloads more than they upload
+	// IsLeecher indicates if the student downloads more than they upload,
+	// as judged by the reputation policy
 	// Leechers face bandwidth restrictions to encourage fair sharing
 	IsLeecher bool `json:"is_leecher"`
 
@@ -62,6 +54,10 @@ type Student struct {
 	// LastSeen records when the peer was last active
 	LastSeen time.Time `json:"last_seen"`
 
+	// JoinedAt records when the peer first registered
+	// Used to spot freshly created accounts in rating analysis
+	JoinedAt time.Time `json:"joined_at"`
+
 	// TotalUploads tracks the number of files this peer has shared
 	TotalUploads int `json:"total_uploads"`
 
@@ -73,6 +69,10 @@ type Student struct {
 
 	// Port is the port number this peer listens on
 	Port int `json:"port"`
+
+	// mutex guards the reputation, activity and online fields, which change
+	// while the student is registered; read them through the accessors
+	mutex sync.RWMutex
 }
 
 // ============================================================================
@@ -81,6 +81,8 @@ type Student struct {
 
 // NewStudent creates a new Student with default values
 // This is a factory function pattern common in Go
+// The reputation score is left for the reputation service to set when the
+// student is registered
 // Parameters:
 //   - id: Unique identifier for the student
 //   - name: Display name
@@ -91,17 +93,18 @@ type Student struct {
 //   - *Student: Pointer to the newly created student
 func NewStudent(id, name, ipAddress string, port int) *Student {
 	// Using := for type inference - Go automatically determines the type
+	now := time.Now()
 	return &Student{
-		ID:              id,
-		Name:            name,
-		ReputationScore: DefaultReputation, // Start with default reputation
-		IsLeecher:       false,             // Not a leecher by default
-		IsOnline:        true,              // Assume online when created
-		LastSeen:        time.Now(),
-		TotalUploads:    0,
-		TotalDownloads:  0,
-		IPAddress:       ipAddress,
-		Port:            port,
+		ID:             id,
+		Name:           name,
+		IsLeecher:      false, // Not a leecher by default
+		IsOnline:       true,  // Assume online when created
+		LastSeen:       now,
+		JoinedAt:       now,
+		TotalUploads:   0,
+		TotalDownloads: 0,
+		IPAddress:      ipAddress,
+		Port:           port,
 	}
 }
 
@@ -109,70 +112,79 @@ func NewStudent(id, name, ipAddress string, port int) *Student {
 // METHODS - Functions attached to the Student struct
 // ============================================================================
 
-// CanDownload checks if the student has sufficient reputation to download files
-// This uses the pointer receiver (*Student) to access the struct fields
-// Returns:
-//   - bool: true if reputation >= MinReputation, false otherwise
-func (s *Student) CanDownload() bool {
-	// Control flow: if/else to check reputation threshold
-	if s.ReputationScore >= MinReputation {
-		return true
-	}
-	return false
+// SetReputation replaces the reputation score
+// Used when the score is derived elsewhere, e.g. from the reputation log
+func (s *Student) SetReputation(score float64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.ReputationScore = score
 }
 
-// UpdateReputation adjusts the student's reputation score
-// Uses pointer receiver to modify the original struct
+// SetActivity replaces the upload and download counts and leecher status
+// Used when they are derived from the reputation log
 // Parameters:
-//   - delta: Amount to add (positive) or subtract (negative)
-func (s *Student) UpdateReputation(delta float64) {
-	// Update the score
-	s.ReputationScore += delta
-
-	// Ensure score stays within valid range using control flow
-	if s.ReputationScore > MaxReputation {
-		s.ReputationScore = MaxReputation
-	} else if s.ReputationScore < 0 {
-		s.ReputationScore = 0
+//   - uploads: Files shared
+//   - downloads: Files downloaded
+//   - leecher: Whether the reputation policy counts the student as a leecher
+func (s *Student) SetActivity(uploads, downloads int, leecher bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.TotalUploads = uploads
+	s.TotalDownloads = downloads
+	s.IsLeecher = leecher
+}
+
+// SetOnline updates the online status of the peer
+func (s *Student) SetOnline(status bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.IsOnline = status
+	if status {
+		s.LastSeen = time.Now()
 	}
+}
+
+// Reputation returns the reputation score
+func (s *Student) Reputation() float64 {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
 
-	// Update leecher status based on upload/download ratio
-	s.checkLeecherStatus()
+	return s.ReputationScore
 }
 
-// checkLeecherStatus determines if the student is a leecher
-// A leecher downloads significantly more than they upload
-func (s *Student) checkLeecherStatus() {
-	// If downloads are more than 3x uploads, mark as leecher
-	if s.TotalDownloads > 0 && s.TotalUploads > 0 {
-		ratio := float64(s.TotalDownloads) / float64(s.TotalUploads)
-		s.IsLeecher = ratio > 3.0
-	} else if s.TotalDownloads > 5 && s.TotalUploads == 0 {
-		// Downloaded files but never uploaded - definitely a leecher
-		s.IsLeecher = true
-	}
+// Activity returns the upload and download counts
+func (s *Student) Activity() (uploads, downloads int) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.TotalUploads, s.TotalDownloads
 }
 
-// RecordUpload increments upload count and rewards reputation
-func (s *Student) RecordUpload() {
-	s.TotalUploads++
-	s.UpdateReputation(0.5) // Reward for contributing
-	s.LastSeen = time.Now()
+// Leecher reports whether the student is marked as a leecher
+func (s *Student) Leecher() bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.IsLeecher
 }
 
-// RecordDownload increments download count
-func (s *Student) RecordDownload() {
-	s.TotalDownloads++
-	s.LastSeen = time.Now()
-	s.checkLeecherStatus()
+// Online reports whether the peer is connected
+func (s *Student) Online() bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.IsOnline
 }
 
-// SetOnline updates the online status of the peer
-func (s *Student) SetOnline(status bool) {
-	s.IsOnline = status
-	if status {
-		s.LastSeen = time.Now()
-	}
+// LastSeenAt returns when the peer was last active
+func (s *Student) LastSeenAt() time.Time {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.LastSeen
 }
 
 // GetAddress returns the full network address (IP:Port)
@@ -183,6 +195,9 @@ func (s *Student) GetAddress() string {
 // ToJSON converts the Student struct to JSON bytes
 // Uses Go's encoding/json package for serialization
 func (s *Student) ToJSON() ([]byte, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
 	return json.Marshal(s)
 }
 
@@ -193,6 +208,9 @@ func (s *Student) ToJSON() ([]byte, error) {
 // Returns:
 //   - error: nil if successful, error otherwise
 func (s *Student) FromJSON(data []byte) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
 	return json.Unmarshal(data, s)
 }
 
@@ -261,7 +279,7 @@ func (pr *PeerRegistry) GetOnlinePeers() []*Student {
 
 	// Loop through all peers in the map
 	for _, student := range pr.peers {
-		if student.IsOnline {
+		if student.Online() {
 			// Append to slice (dynamic array growth)
 			onlinePeers = append(onlinePeers, student)
 		}
@@ -24,10 +24,13 @@ import (
 // CONSTANTS
 // ============================================================================
 
-// User roles
+// Built-in roles (see role.go for their permissions)
+// RoleUser is the default role students sign up with
 const (
-	RoleUser  = "user"
-	RoleAdmin = "admin"
+	RoleUser       = "user"
+	RoleInstructor = "instructor"
+	RoleModerator  = "moderator"
+	RoleAdmin      = "admin"
 )
 
 // ============================================================================
@@ -45,11 +48,47 @@ type User struct {
 	LastLogin    time.Time `json:"last_login,omitempty"`
 	IsActive     bool      `json:"is_active"`
 
-	// P2P Network fields (from original Peer model)
-	PeerID         string  `json:"peer_id,omitempty"`
-	Reputation     float64 `json:"reputation"`
-	TotalUploads   int     `json:"total_uploads"`
-	TotalDownloads int     `json:"total_downloads"`
+	// EmailVerified is set once the user proves they own Email
+	EmailVerified bool `json:"email_verified"`
+
+	// TwoFactor holds TOTP enrollment; nil when the user never set it up
+	// Secrets are hidden from API responses and stored separately
+	TwoFactor *TwoFactor `json:"-"`
+
+	// Identities are the single sign-on accounts linked to this user
+	Identities []LinkedIdentity `json:"identities,omitempty"`
+
+	// P2P Network binding: the peers this account operates
+	// Reputation and upload/download counts live on those peers and are
+	// never stored on the user
+	PeerIDs []string `json:"peer_ids,omitempty"`
+}
+
+// TwoFactor is a user's TOTP (RFC 6238) enrollment
+type TwoFactor struct {
+	// Secret is the confirmed base32 TOTP secret; empty until the first
+	// code is verified
+	Secret string `json:"secret,omitempty"`
+
+	// PendingSecret is a secret handed out by setup but not yet confirmed
+	PendingSecret string `json:"pending_secret,omitempty"`
+
+	// RecoveryCodes are SHA-256 hashes of the unused recovery codes
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+
+	// LastStep is the last accepted time step, so a code can't be replayed
+	LastStep int64 `json:"last_step,omitempty"`
+
+	EnabledAt time.Time `json:"enabled_at,omitempty"`
+}
+
+// LinkedIdentity is an account at an OpenID Connect identity provider
+// An issuer never reuses a subject, so the pair identifies the person even
+// if their email changes
+type LinkedIdentity struct {
+	Issuer   string    `json:"issuer"`
+	Subject  string    `json:"subject"`
+	LinkedAt time.Time `json:"linked_at"`
 }
 
 // ============================================================================
@@ -74,8 +113,9 @@ func (u *User) Validate() error {
 		return errors.New("username must be at least 3 characters long")
 	}
 
-	if u.Role != RoleUser && u.Role != RoleAdmin {
-		return errors.New("invalid role")
+	// Whether the role exists is checked against the role store
+	if u.Role == "" {
+		return errors.New("role is required")
 	}
 
 	return nil
@@ -98,14 +138,38 @@ func (u *User) IsAdmin() bool {
 	return u.Role == RoleAdmin
 }
 
+// TwoFactorEnabled checks if logins need a second factor
+func (u *User) TwoFactorEnabled() bool {
+	return u.TwoFactor != nil && u.TwoFactor.Secret != ""
+}
+
+// Clone returns a deep copy of the user
+// Stores hand out copies, so changing one never touches a stored user
+func (u *User) Clone() *User {
+	clone := *u
+	if u.TwoFactor != nil {
+		twoFactor := *u.TwoFactor
+		twoFactor.RecoveryCodes = append([]string(nil), u.TwoFactor.RecoveryCodes...)
+		clone.TwoFactor = &twoFactor
+	}
+	clone.Identities = append([]LinkedIdentity(nil), u.Identities...)
+	clone.PeerIDs = append([]string(nil), u.PeerIDs...)
+	return &clone
+}
+
 // UpdateLastLogin updates the last login timestamp
 func (u *User) UpdateLastLogin() {
 	u.LastLogin = time.Now()
 }
 
-// CanDownload checks if user's reputation allows downloads
-func (u *User) CanDownload() bool {
-	return u.Reputation >= 3.0
+// HasPeer checks if a peer is bound to the user
+func (u *User) HasPeer(peerID string) bool {
+	for _, id := range u.PeerIDs {
+		if id == peerID {
+			return true
+		}
+	}
+	return false
 }
 
 // PublicUser returns a user object safe for public display (no sensitive info)
@@ -115,21 +179,30 @@ type PublicUser struct {
 	Username       string    `json:"username"`
 	Role           string    `json:"role"`
 	CreatedAt      time.Time `json:"created_at"`
+	EmailVerified  bool      `json:"email_verified"`
+	TwoFactor      bool      `json:"two_factor_enabled"`
+	Permissions    []string  `json:"permissions"`
+	PeerIDs        []string  `json:"peer_ids"`
 	Reputation     float64   `json:"reputation"`
 	TotalUploads   int       `json:"total_uploads"`
 	TotalDownloads int       `json:"total_downloads"`
 }
 
 // ToPublic converts a User to PublicUser
+// Reputation, counts and permissions are left for the caller to fill from
+// the bound peers and the user's role
 func (u *User) ToPublic() PublicUser {
+	peerIDs := make([]string, len(u.PeerIDs))
+	copy(peerIDs, u.PeerIDs)
+
 	return PublicUser{
-		ID:             u.ID,
-		Email:          u.Email,
-		Username:       u.Username,
-		Role:           u.Role,
-		CreatedAt:      u.CreatedAt,
-		Reputation:     u.Reputation,
-		TotalUploads:   u.TotalUploads,
-		TotalDownloads: u.TotalDownloads,
+		ID:            u.ID,
+		Email:         u.Email,
+		Username:      u.Username,
+		Role:          u.Role,
+		CreatedAt:     u.CreatedAt,
+		EmailVerified: u.EmailVerified,
+		TwoFactor:     u.TwoFactorEnabled(),
+		PeerIDs:       peerIDs,
 	}
 }
@@ -0,0 +1,211 @@
+/*
+================================================================================
+API KEY STORAGE - P2P Academic Library "The Knowledge Exchange"
+================================================================================
+This file implements persisted storage for API keys.
+
+Keys are looked up by the hash of the presented key. State is kept in memory
+and written to a JSON file in the data directory after every change. Last-use
+times are only written out once a minute per key, so a busy script doesn't
+rewrite the file on every request.
+
+Go Concepts Used:
+- Maps: Key lookup by hash
+- Sync.RWMutex: Thread-safe operations
+- File I/O: Atomic write via temp file + rename
+================================================================================
+*/
+
+package storage
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"knowledge-exchange/models"
+)
+
+// ============================================================================
+// CONSTANTS
+// ============================================================================
+
+const (
+	// lastUsedResolution is how stale a persisted last-use time may get
+	lastUsedResolution = time.Minute
+
+	// revokedKeyRetention is how long revoked and expired keys stay listed
+	revokedKeyRetention = 30 * 24 * time.Hour
+)
+
+// ============================================================================
+// API KEY STORE
+// ============================================================================
+
+// APIKeyStore manages API keys
+type APIKeyStore struct {
+	path string                    // Empty keeps the store in memory only
+	keys map[string]*models.APIKey // key hash -> key
+	mu   sync.RWMutex
+}
+
+// NewAPIKeyStore opens (or creates) an API key store backed by a file
+// Parameters:
+//   - path: JSON file to persist to; "" keeps the store in memory
+//
+// Returns:
+//   - *APIKeyStore: The loaded store
+//   - error: If an existing file can't be read or parsed
+func NewAPIKeyStore(path string) (*APIKeyStore, error) {
+	store := &APIKeyStore{
+		path: path,
+		keys: make(map[string]*models.APIKey),
+	}
+
+	if path == "" {
+		return store, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var stored []*models.APIKey
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, err
+	}
+	for _, key := range stored {
+		store.keys[key.KeyHash] = key
+	}
+
+	return store, nil
+}
+
+// ============================================================================
+// OPERATIONS
+// ============================================================================
+
+// SaveAPIKey stores a new key
+func (s *APIKeyStore) SaveAPIKey(key models.APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys[key.KeyHash] = &key
+	return s.saveLocked()
+}
+
+// GetAPIKey looks up a key by hash
+func (s *APIKeyStore) GetAPIKey(hash string) (models.APIKey, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key, exists := s.keys[hash]
+	if !exists {
+		return models.APIKey{}, false
+	}
+	return *key, true
+}
+
+// ListAPIKeys returns a user's keys, newest first
+func (s *APIKeyStore) ListAPIKeys(userID string) []models.APIKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]models.APIKey, 0)
+	for _, key := range s.keys {
+		if key.UserID == userID {
+			keys = append(keys, *key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.After(keys[j].CreatedAt)
+	})
+	return keys
+}
+
+// RevokeAPIKey revokes one of a user's keys
+// Returns false if the user has no key with that ID
+func (s *APIKeyStore) RevokeAPIKey(userID, keyID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range s.keys {
+		if key.ID == keyID && key.UserID == userID {
+			if !key.IsRevoked() {
+				key.RevokedAt = time.Now()
+			}
+			return true, s.saveLocked()
+		}
+	}
+	return false, nil
+}
+
+// TouchAPIKey records that a key was used
+func (s *APIKeyStore) TouchAPIKey(hash string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, exists := s.keys[hash]
+	if !exists {
+		return nil
+	}
+	persist := at.Sub(key.LastUsed) >= lastUsedResolution
+	key.LastUsed = at
+	if !persist {
+		return nil
+	}
+	return s.saveLocked()
+}
+
+// ============================================================================
+// PERSISTENCE
+// ============================================================================
+
+// saveLocked drops keys that stopped working long ago and writes the rest
+// to disk (owner-readable only)
+// Caller must hold s.mu
+func (s *APIKeyStore) saveLocked() error {
+	now := time.Now()
+	for hash, key := range s.keys {
+		ended := key.ExpiresAt
+		if key.IsRevoked() && key.RevokedAt.Before(ended) {
+			ended = key.RevokedAt
+		}
+		if now.Sub(ended) > revokedKeyRetention {
+			delete(s.keys, hash)
+		}
+	}
+
+	if s.path == "" {
+		return nil
+	}
+
+	stored := make([]*models.APIKey, 0, len(s.keys))
+	for _, key := range s.keys {
+		stored = append(stored, key)
+	}
+	sort.Slice(stored, func(i, j int) bool {
+		return stored[i].CreatedAt.Before(stored[j].CreatedAt)
+	})
+
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,219 @@
+/*
+================================================================================
+LOGIN ATTEMPT STORAGE - P2P Academic Library "The Knowledge Exchange"
+================================================================================
+This file implements persisted storage for failed-login counters and the
+security audit trail.
+
+State is kept in memory and written to a JSON file in the data directory
+after every change, so lockouts survive a restart. The audit trail keeps
+only the most recent events.
+
+Go Concepts Used:
+- Maps: Counter lookup by key
+- Slices: Bounded audit trail
+- Sync.RWMutex: Thread-safe operations
+- File I/O: Atomic write via temp file + rename
+================================================================================
+*/
+
+package storage
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"knowledge-exchange/models"
+)
+
+// ============================================================================
+// CONSTANTS
+// ============================================================================
+
+const (
+	// maxSecurityEvents bounds the persisted audit trail
+	maxSecurityEvents = 1000
+
+	// attemptRetention is how long counters are kept after the last
+	// failure once their lockout has ended
+	attemptRetention = 24 * time.Hour
+)
+
+// ============================================================================
+// ATTEMPT STORE
+// ============================================================================
+
+// attemptState is the persisted form of the attempt store
+type attemptState struct {
+	Attempts map[string]*models.LoginAttempts `json:"attempts"` // key -> counter
+	Audit    []models.SecurityEvent           `json:"audit"`    // Oldest first
+}
+
+// AttemptStore manages failed-login counters and security events
+type AttemptStore struct {
+	path  string // Empty keeps the store in memory only
+	state attemptState
+	mu    sync.RWMutex
+}
+
+// NewAttemptStore opens (or creates) an attempt store backed by a file
+// Parameters:
+//   - path: JSON file to persist to; "" keeps the store in memory
+//
+// Returns:
+//   - *AttemptStore: The loaded store
+//   - error: If an existing file can't be read or parsed
+func NewAttemptStore(path string) (*AttemptStore, error) {
+	store := &AttemptStore{
+		path: path,
+		state: attemptState{
+			Attempts: make(map[string]*models.LoginAttempts),
+		},
+	}
+
+	if path == "" {
+		return store, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &store.state); err != nil {
+		return nil, err
+	}
+
+	if store.state.Attempts == nil {
+		store.state.Attempts = make(map[string]*models.LoginAttempts)
+	}
+
+	return store, nil
+}
+
+// ============================================================================
+// ATTEMPTS
+// ============================================================================
+
+// GetAttempts returns the counter for a key
+func (s *AttemptStore) GetAttempts(key string) (models.LoginAttempts, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	attempts, exists := s.state.Attempts[key]
+	if !exists {
+		return models.LoginAttempts{}, false
+	}
+	return *attempts, true
+}
+
+// SaveAttempts stores the counter for a key
+func (s *AttemptStore) SaveAttempts(attempts models.LoginAttempts) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state.Attempts[attempts.Key] = &attempts
+	return s.saveLocked()
+}
+
+// ClearAttempts forgets a key's failures and any lockout
+// Returns false if the key had no counter
+func (s *AttemptStore) ClearAttempts(key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.state.Attempts[key]; !exists {
+		return false, nil
+	}
+	delete(s.state.Attempts, key)
+	return true, s.saveLocked()
+}
+
+// ListLocked returns every key locked at a point in time, soonest unlock
+// first
+func (s *AttemptStore) ListLocked(now time.Time) []models.LoginAttempts {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	locked := make([]models.LoginAttempts, 0)
+	for _, attempts := range s.state.Attempts {
+		if attempts.IsLocked(now) {
+			locked = append(locked, *attempts)
+		}
+	}
+	sort.Slice(locked, func(i, j int) bool {
+		return locked[i].LockedUntil.Before(locked[j].LockedUntil)
+	})
+	return locked
+}
+
+// ============================================================================
+// AUDIT TRAIL
+// ============================================================================
+
+// RecordEvent appends an event to the audit trail
+func (s *AttemptStore) RecordEvent(event models.SecurityEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state.Audit = append(s.state.Audit, event)
+	if excess := len(s.state.Audit) - maxSecurityEvents; excess > 0 {
+		s.state.Audit = append([]models.SecurityEvent(nil), s.state.Audit[excess:]...)
+	}
+	return s.saveLocked()
+}
+
+// RecentEvents returns up to limit audit events, newest first
+func (s *AttemptStore) RecentEvents(limit int) []models.SecurityEvent {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if limit <= 0 || limit > len(s.state.Audit) {
+		limit = len(s.state.Audit)
+	}
+
+	events := make([]models.SecurityEvent, 0, limit)
+	for i := len(s.state.Audit) - 1; i >= 0 && len(events) < limit; i-- {
+		events = append(events, s.state.Audit[i])
+	}
+	return events
+}
+
+// ============================================================================
+// PERSISTENCE
+// ============================================================================
+
+// saveLocked prunes stale counters and writes the store to disk
+// Caller must hold s.mu
+func (s *AttemptStore) saveLocked() error {
+	now := time.Now()
+	for key, attempts := range s.state.Attempts {
+		if !attempts.IsLocked(now) && now.Sub(attempts.LastFailure) > attemptRetention {
+			delete(s.state.Attempts, key)
+		}
+	}
+
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s.state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
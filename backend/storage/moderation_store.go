@@ -0,0 +1,277 @@
+/*
+================================================================================
+MODERATION STORAGE - P2P Academic Library "The Knowledge Exchange"
+================================================================================
+This file implements persisted storage for staff decisions about content:
+banned peers, files removed from the index, and files pinned as official
+course material.
+
+State is kept in memory and written to a JSON file in the data directory
+after every change, so bans, removals and pins survive a restart.
+
+Go Concepts Used:
+- Maps: Lookup by peer ID / CID
+- Sync.RWMutex: Thread-safe operations
+- File I/O: Atomic write via temp file + rename
+================================================================================
+*/
+
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// MODERATION RECORDS
+// ============================================================================
+
+// ModerationRecord records who banned a peer, removed or pinned a file,
+// and why (for pins, a note such as the course)
+type ModerationRecord struct {
+	ID        string    `json:"id"` // Peer ID or file CID
+	Reason    string    `json:"reason"`
+	By        string    `json:"by"` // Staff user ID
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// moderationState is the persisted form of the moderation store
+type moderationState struct {
+	BannedPeers  map[string]ModerationRecord `json:"banned_peers"`  // peerID -> ban
+	RemovedFiles map[string]ModerationRecord `json:"removed_files"` // CID -> removal
+	PinnedFiles  map[string]ModerationRecord `json:"pinned_files"`  // CID -> pin
+}
+
+// ============================================================================
+// MODERATION STORE
+// ============================================================================
+
+// ModerationStore manages peer bans, removed files and pinned files
+type ModerationStore struct {
+	path  string // Empty keeps the store in memory only
+	state moderationState
+	mu    sync.RWMutex
+}
+
+// NewModerationStore opens (or creates) a moderation store backed by a file
+// Parameters:
+//   - path: JSON file to persist to; "" keeps the store in memory
+//
+// Returns:
+//   - *ModerationStore: The loaded store
+//   - error: If an existing file can't be read or parsed
+func NewModerationStore(path string) (*ModerationStore, error) {
+	store := &ModerationStore{
+		path: path,
+		state: moderationState{
+			BannedPeers:  make(map[string]ModerationRecord),
+			RemovedFiles: make(map[string]ModerationRecord),
+			PinnedFiles:  make(map[string]ModerationRecord),
+		},
+	}
+
+	if path == "" {
+		return store, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &store.state); err != nil {
+		return nil, err
+	}
+
+	if store.state.BannedPeers == nil {
+		store.state.BannedPeers = make(map[string]ModerationRecord)
+	}
+	if store.state.RemovedFiles == nil {
+		store.state.RemovedFiles = make(map[string]ModerationRecord)
+	}
+	if store.state.PinnedFiles == nil {
+		store.state.PinnedFiles = make(map[string]ModerationRecord)
+	}
+
+	return store, nil
+}
+
+// ============================================================================
+// PEER BANS
+// ============================================================================
+
+// BanPeer bans a peer; banning it again updates the reason
+func (s *ModerationStore) BanPeer(peerID, reason, by string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state.BannedPeers[peerID] = ModerationRecord{
+		ID:        peerID,
+		Reason:    reason,
+		By:        by,
+		CreatedAt: time.Now(),
+	}
+	return s.saveLocked()
+}
+
+// UnbanPeer lifts a peer's ban
+func (s *ModerationStore) UnbanPeer(peerID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, banned := s.state.BannedPeers[peerID]; !banned {
+		return errors.New("peer is not banned")
+	}
+	delete(s.state.BannedPeers, peerID)
+	return s.saveLocked()
+}
+
+// IsPeerBanned checks if a peer is banned
+func (s *ModerationStore) IsPeerBanned(peerID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, banned := s.state.BannedPeers[peerID]
+	return banned
+}
+
+// ListBans returns every peer ban, newest first
+func (s *ModerationStore) ListBans() []ModerationRecord {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return sortedRecords(s.state.BannedPeers)
+}
+
+// ============================================================================
+// REMOVED FILES
+// ============================================================================
+
+// RemoveFile records that a file was removed from the index
+func (s *ModerationStore) RemoveFile(cid, reason, by string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state.RemovedFiles[cid] = ModerationRecord{
+		ID:        cid,
+		Reason:    reason,
+		By:        by,
+		CreatedAt: time.Now(),
+	}
+	delete(s.state.PinnedFiles, cid)
+	return s.saveLocked()
+}
+
+// IsFileRemoved checks if a file was removed by staff
+func (s *ModerationStore) IsFileRemoved(cid string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, removed := s.state.RemovedFiles[cid]
+	return removed
+}
+
+// ListRemovedFiles returns every removed file, newest first
+func (s *ModerationStore) ListRemovedFiles() []ModerationRecord {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return sortedRecords(s.state.RemovedFiles)
+}
+
+// ============================================================================
+// PINNED FILES
+// ============================================================================
+
+// PinFile marks a file as official material; pinning it again updates
+// the note
+func (s *ModerationStore) PinFile(cid, note, by string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, removed := s.state.RemovedFiles[cid]; removed {
+		return errors.New("file was removed")
+	}
+	s.state.PinnedFiles[cid] = ModerationRecord{
+		ID:        cid,
+		Reason:    note,
+		By:        by,
+		CreatedAt: time.Now(),
+	}
+	return s.saveLocked()
+}
+
+// UnpinFile removes a file's pin
+func (s *ModerationStore) UnpinFile(cid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, pinned := s.state.PinnedFiles[cid]; !pinned {
+		return errors.New("file is not pinned")
+	}
+	delete(s.state.PinnedFiles, cid)
+	return s.saveLocked()
+}
+
+// GetPin returns a file's pin, if it is pinned
+func (s *ModerationStore) GetPin(cid string) (ModerationRecord, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	pin, pinned := s.state.PinnedFiles[cid]
+	return pin, pinned
+}
+
+// ListPinnedFiles returns every pinned file, newest first
+func (s *ModerationStore) ListPinnedFiles() []ModerationRecord {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return sortedRecords(s.state.PinnedFiles)
+}
+
+// sortedRecords returns the records in a map, newest first
+func sortedRecords(records map[string]ModerationRecord) []ModerationRecord {
+	list := make([]ModerationRecord, 0, len(records))
+	for _, record := range records {
+		list = append(list, record)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.After(list[j].CreatedAt)
+	})
+	return list
+}
+
+// ============================================================================
+// PERSISTENCE
+// ============================================================================
+
+// saveLocked writes the store to disk
+// Caller must hold s.mu
+func (s *ModerationStore) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s.state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,199 @@
+/*
+================================================================================
+REPORT STORAGE - P2P Academic Library "The Knowledge Exchange"
+================================================================================
+This file implements persisted storage for reports of files and peers.
+
+State is kept in memory and written to a JSON file in the data directory
+after every change. Handled reports are kept as a record of moderator
+decisions.
+
+Go Concepts Used:
+- Maps: Lookup by report ID
+- Sync.RWMutex: Thread-safe operations
+- File I/O: Atomic write via temp file + rename
+================================================================================
+*/
+
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"knowledge-exchange/models"
+
+	"github.com/google/uuid"
+)
+
+// ============================================================================
+// REPORT STORE
+// ============================================================================
+
+// ReportStore manages reports
+type ReportStore struct {
+	path    string // Empty keeps the store in memory only
+	reports map[string]*models.Report
+	mu      sync.RWMutex
+}
+
+// NewReportStore opens (or creates) a report store backed by a file
+// Parameters:
+//   - path: JSON file to persist to; "" keeps the store in memory
+//
+// Returns:
+//   - *ReportStore: The loaded store
+//   - error: If an existing file can't be read or parsed
+func NewReportStore(path string) (*ReportStore, error) {
+	store := &ReportStore{
+		path:    path,
+		reports: make(map[string]*models.Report),
+	}
+
+	if path == "" {
+		return store, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var stored []*models.Report
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, err
+	}
+	for _, report := range stored {
+		store.reports[report.ID] = report
+	}
+
+	return store, nil
+}
+
+// ============================================================================
+// OPERATIONS
+// ============================================================================
+
+// Create files a new open report
+// A reporter can have only one open report per target
+// Returns the stored report (with its ID)
+func (s *ReportStore) Create(report models.Report) (models.Report, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.reports {
+		if existing.IsOpen() && existing.ReporterID == report.ReporterID &&
+			existing.TargetType == report.TargetType && existing.TargetID == report.TargetID {
+			return models.Report{}, errors.New("you already reported this; a moderator will review it")
+		}
+	}
+
+	report.ID = uuid.New().String()
+	report.Status = models.ReportOpen
+	report.CreatedAt = time.Now()
+	s.reports[report.ID] = &report
+	return report, s.saveLocked()
+}
+
+// Get returns a report by ID
+func (s *ReportStore) Get(id string) (models.Report, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	report, exists := s.reports[id]
+	if !exists {
+		return models.Report{}, false
+	}
+	return *report, true
+}
+
+// List returns reports with a status ("" for all), oldest first so the
+// queue is worked in order
+func (s *ReportStore) List(status string) []models.Report {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]models.Report, 0)
+	for _, report := range s.reports {
+		if status == "" || report.Status == status {
+			list = append(list, *report)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+	return list
+}
+
+// Resolve closes an open report
+// Parameters:
+//   - id: The report
+//   - status: models.ReportResolved or models.ReportDismissed
+//   - action: What was done (models.ReportAction*)
+//   - note: Moderator's note
+//   - by: Moderator's user ID
+//
+// Returns:
+//   - models.Report: The closed report
+//   - error: If the report doesn't exist or was already handled
+func (s *ReportStore) Resolve(id, status, action, note, by string) (models.Report, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report, exists := s.reports[id]
+	if !exists {
+		return models.Report{}, errors.New("report not found")
+	}
+	if !report.IsOpen() {
+		return models.Report{}, errors.New("report was already handled")
+	}
+
+	report.Status = status
+	report.Action = action
+	report.Note = note
+	report.HandledBy = by
+	report.HandledAt = time.Now()
+	return *report, s.saveLocked()
+}
+
+// ============================================================================
+// PERSISTENCE
+// ============================================================================
+
+// saveLocked writes all reports to disk
+// Caller must hold s.mu
+func (s *ReportStore) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+
+	stored := make([]*models.Report, 0, len(s.reports))
+	for _, report := range s.reports {
+		stored = append(stored, report)
+	}
+	sort.Slice(stored, func(i, j int) bool {
+		return stored[i].CreatedAt.Before(stored[j].CreatedAt)
+	})
+
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,213 @@
+/*
+================================================================================
+ROLE STORAGE - P2P Academic Library "The Knowledge Exchange"
+================================================================================
+This file implements persisted storage for roles and their permissions.
+
+The built-in roles are added on first start (and whenever one is missing),
+so existing data directories pick them up. The admin role always holds
+every permission, whatever the file says.
+
+Go Concepts Used:
+- Maps: Lookup by role name
+- Sync.RWMutex: Thread-safe operations
+- File I/O: Atomic write via temp file + rename
+================================================================================
+*/
+
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"knowledge-exchange/models"
+)
+
+// ============================================================================
+// ROLE STORE
+// ============================================================================
+
+// RoleStore manages roles
+type RoleStore struct {
+	path  string // Empty keeps the store in memory only
+	roles map[string]*models.Role
+	mu    sync.RWMutex
+}
+
+// NewRoleStore opens (or creates) a role store backed by a file
+// Parameters:
+//   - path: JSON file to persist to; "" keeps the store in memory
+//
+// Returns:
+//   - *RoleStore: The loaded store, with any missing built-in roles added
+//   - error: If an existing file can't be read or parsed
+func NewRoleStore(path string) (*RoleStore, error) {
+	store := &RoleStore{
+		path:  path,
+		roles: make(map[string]*models.Role),
+	}
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if err == nil {
+			var stored []*models.Role
+			if err := json.Unmarshal(data, &stored); err != nil {
+				return nil, err
+			}
+			for _, role := range stored {
+				store.roles[role.Name] = role
+			}
+		}
+	}
+
+	added := false
+	for _, role := range models.DefaultRoles() {
+		role := role
+		role.BuiltIn = true
+		if existing, exists := store.roles[role.Name]; exists {
+			existing.BuiltIn = true
+			continue
+		}
+		store.roles[role.Name] = &role
+		added = true
+	}
+	store.roles[models.RoleAdmin].Permissions = models.AllPermissions()
+
+	if added {
+		if err := store.saveLocked(); err != nil {
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
+// ============================================================================
+// OPERATIONS
+// ============================================================================
+
+// Get returns a role by name
+func (s *RoleStore) Get(name string) (models.Role, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	role, exists := s.roles[name]
+	if !exists {
+		return models.Role{}, false
+	}
+	return copyRole(role), true
+}
+
+// List returns every role, built-in roles first
+func (s *RoleStore) List() []models.Role {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]models.Role, 0, len(s.roles))
+	for _, role := range s.roles {
+		list = append(list, copyRole(role))
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].BuiltIn != list[j].BuiltIn {
+			return list[i].BuiltIn
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// HasPermission checks if a role grants a permission
+// Unknown roles grant nothing
+func (s *RoleStore) HasPermission(name, permission string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	role, exists := s.roles[name]
+	return exists && role.HasPermission(permission)
+}
+
+// Save creates a role or replaces its description and permissions
+// The admin role can't be changed
+func (s *RoleStore) Save(role models.Role) error {
+	if err := role.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if role.Name == models.RoleAdmin {
+		return errors.New("the admin role always has every permission")
+	}
+
+	existing, exists := s.roles[role.Name]
+	role.BuiltIn = exists && existing.BuiltIn
+	s.roles[role.Name] = &role
+	return s.saveLocked()
+}
+
+// Delete removes a role that isn't built in
+// The caller checks that no user still has it
+func (s *RoleStore) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	role, exists := s.roles[name]
+	if !exists {
+		return errors.New("role not found")
+	}
+	if role.BuiltIn {
+		return errors.New("built-in roles can't be deleted")
+	}
+
+	delete(s.roles, name)
+	return s.saveLocked()
+}
+
+// copyRole returns a copy that doesn't share the permission slice
+func copyRole(role *models.Role) models.Role {
+	c := *role
+	c.Permissions = append([]string{}, role.Permissions...)
+	return c
+}
+
+// ============================================================================
+// PERSISTENCE
+// ============================================================================
+
+// saveLocked writes all roles to disk
+// Caller must hold s.mu
+func (s *RoleStore) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+
+	stored := make([]*models.Role, 0, len(s.roles))
+	for _, role := range s.roles {
+		stored = append(stored, role)
+	}
+	sort.Slice(stored, func(i, j int) bool {
+		return stored[i].Name < stored[j].Name
+	})
+
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,283 @@
+/*
+================================================================================
+TOKEN STORAGE - P2P Academic Library "The Knowledge Exchange"
+================================================================================
+This file implements persisted storage for refresh tokens, revoked
+access tokens and single-use action tokens.
+
+State is kept in memory and written to a JSON file in the data directory
+after every change, so sessions and revocations survive a restart.
+Expired entries are pruned on each write.
+
+Go Concepts Used:
+- Maps: Token lookup by hash / ID
+- Sync.RWMutex: Thread-safe operations
+- File I/O: Atomic write via temp file + rename
+================================================================================
+*/
+
+package storage
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"knowledge-exchange/models"
+)
+
+// ============================================================================
+// TOKEN STORE
+// ============================================================================
+
+// tokenState is the persisted form of the token store
+type tokenState struct {
+	RefreshTokens map[string]*models.RefreshToken `json:"refresh_tokens"` // hash -> token
+	RevokedAccess map[string]time.Time            `json:"revoked_access"` // jti -> token expiry
+	UserCutoffs   map[string]time.Time            `json:"user_cutoffs"`   // userID -> tokens issued before are revoked
+	ActionTokens  map[string]*models.ActionToken  `json:"action_tokens"`  // hash -> token
+}
+
+// TokenStore manages refresh tokens and the access token revocation list
+type TokenStore struct {
+	path  string // Empty keeps the store in memory only
+	state tokenState
+	mu    sync.RWMutex
+}
+
+// NewTokenStore opens (or creates) a token store backed by a file
+// Parameters:
+//   - path: JSON file to persist to; "" keeps the store in memory
+//
+// Returns:
+//   - *TokenStore: The loaded store
+//   - error: If an existing file can't be read or parsed
+func NewTokenStore(path string) (*TokenStore, error) {
+	store := &TokenStore{
+		path: path,
+		state: tokenState{
+			RefreshTokens: make(map[string]*models.RefreshToken),
+			RevokedAccess: make(map[string]time.Time),
+			UserCutoffs:   make(map[string]time.Time),
+			ActionTokens:  make(map[string]*models.ActionToken),
+		},
+	}
+
+	if path == "" {
+		return store, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &store.state); err != nil {
+		return nil, err
+	}
+
+	// Files written by older versions may lack some maps
+	if store.state.RefreshTokens == nil {
+		store.state.RefreshTokens = make(map[string]*models.RefreshToken)
+	}
+	if store.state.RevokedAccess == nil {
+		store.state.RevokedAccess = make(map[string]time.Time)
+	}
+	if store.state.UserCutoffs == nil {
+		store.state.UserCutoffs = make(map[string]time.Time)
+	}
+	if store.state.ActionTokens == nil {
+		store.state.ActionTokens = make(map[string]*models.ActionToken)
+	}
+
+	return store, nil
+}
+
+// ============================================================================
+// REFRESH TOKENS
+// ============================================================================
+
+// SaveRefreshToken stores a newly issued refresh token
+func (s *TokenStore) SaveRefreshToken(token models.RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state.RefreshTokens[token.TokenHash] = &token
+	return s.saveLocked()
+}
+
+// GetRefreshToken retrieves a refresh token by hash
+func (s *TokenStore) GetRefreshToken(hash string) (models.RefreshToken, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	token, exists := s.state.RefreshTokens[hash]
+	if !exists {
+		return models.RefreshToken{}, false
+	}
+	return *token, true
+}
+
+// RotateRefreshToken marks a refresh token used and stores its replacement
+// The check and update happen under one lock, so two concurrent uses of the
+// same token can't both succeed
+// Returns:
+//   - bool: False if the token was already used, revoked or is unknown
+//   - error: If the store couldn't be written
+func (s *TokenStore) RotateRefreshToken(usedHash string, next models.RefreshToken) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	used, exists := s.state.RefreshTokens[usedHash]
+	if !exists || used.IsUsed() || used.Revoked {
+		return false, nil
+	}
+
+	used.ReplacedBy = next.TokenHash
+	s.state.RefreshTokens[next.TokenHash] = &next
+	return true, s.saveLocked()
+}
+
+// RevokeRefreshFamily revokes every refresh token descended from one login
+func (s *TokenStore) RevokeRefreshFamily(familyID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, token := range s.state.RefreshTokens {
+		if token.FamilyID == familyID {
+			token.Revoked = true
+		}
+	}
+	return s.saveLocked()
+}
+
+// ============================================================================
+// REVOCATION
+// ============================================================================
+
+// RevokeUser revokes all of a user's refresh tokens and every access token
+// issued before a cutoff
+func (s *TokenStore) RevokeUser(userID string, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, token := range s.state.RefreshTokens {
+		if token.UserID == userID {
+			token.Revoked = true
+		}
+	}
+
+	// Token issue times have second precision
+	s.state.UserCutoffs[userID] = before.Truncate(time.Second)
+	return s.saveLocked()
+}
+
+// RevokeAccessToken adds a single access token to the revocation list
+// Parameters:
+//   - jti: The token's ID
+//   - expiresAt: When the token expires (after which the entry is dropped)
+func (s *TokenStore) RevokeAccessToken(jti string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state.RevokedAccess[jti] = expiresAt
+	return s.saveLocked()
+}
+
+// IsAccessRevoked checks an access token against the revocation list
+func (s *TokenStore) IsAccessRevoked(jti, userID string, issuedAt time.Time) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, revoked := s.state.RevokedAccess[jti]; revoked {
+		return true
+	}
+
+	cutoff, exists := s.state.UserCutoffs[userID]
+	return exists && issuedAt.Before(cutoff)
+}
+
+// ============================================================================
+// ACTION TOKENS
+// ============================================================================
+
+// SaveActionToken stores a new action token, replacing any earlier token
+// the user holds for the same purpose
+func (s *TokenStore) SaveActionToken(token models.ActionToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for hash, existing := range s.state.ActionTokens {
+		if existing.UserID == token.UserID && existing.Purpose == token.Purpose {
+			delete(s.state.ActionTokens, hash)
+		}
+	}
+	s.state.ActionTokens[token.TokenHash] = &token
+	return s.saveLocked()
+}
+
+// ConsumeActionToken looks up an action token and deletes it
+// Lookup and delete happen under one lock, so a token works only once
+// Returns:
+//   - models.ActionToken: The token
+//   - bool: False if the token is unknown, expired or for another purpose
+//   - error: If the store couldn't be written
+func (s *TokenStore) ConsumeActionToken(hash, purpose string) (models.ActionToken, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, exists := s.state.ActionTokens[hash]
+	if !exists || token.Purpose != purpose || token.IsExpired() {
+		return models.ActionToken{}, false, nil
+	}
+
+	delete(s.state.ActionTokens, hash)
+	return *token, true, s.saveLocked()
+}
+
+// ============================================================================
+// PERSISTENCE
+// ============================================================================
+
+// saveLocked prunes expired entries and writes the store to disk
+// Caller must hold s.mu
+func (s *TokenStore) saveLocked() error {
+	now := time.Now()
+	for hash, token := range s.state.RefreshTokens {
+		if now.After(token.ExpiresAt) {
+			delete(s.state.RefreshTokens, hash)
+		}
+	}
+	for jti, expiresAt := range s.state.RevokedAccess {
+		if now.After(expiresAt) {
+			delete(s.state.RevokedAccess, jti)
+		}
+	}
+	for hash, token := range s.state.ActionTokens {
+		if token.IsExpired() {
+			delete(s.state.ActionTokens, hash)
+		}
+	}
+
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s.state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -2,20 +2,34 @@
 ================================================================================
 USER STORAGE - P2P Academic Library "The Knowledge Exchange"
 ================================================================================
-This file implements in-memory storage for user data.
+This file implements storage for user data.
+
+Users are kept in memory and, when the store is opened from a file,
+written back after every change so accounts survive a restart. The
+development admin is never written back, so it only exists while the
+server runs in development mode.
+
+Lookups return copies of the stored users. Changes go through the store
+(Modify, or the specific methods below), which apply them under the lock
+and only keep them once they are saved, so memory and disk agree.
 
 Go Concepts Used:
 - Maps: In-memory data storage
 - Sync.RWMutex: Thread-safe operations
 - UUID: Unique identifiers
+- File I/O: Atomic write via temp file + rename
 ================================================================================
 */
 
 package storage
 
 import (
+	"encoding/json"
 	"errors"
 	"log"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -30,51 +44,141 @@ import (
 // USER STORE
 // ============================================================================
 
-// UserStore manages user data in memory
+// Well-known development admin credentials
+const (
+	devAdminEmail    = "admin@knowledge-exchange.com"
+	devAdminPassword = "admin123"
+)
+
+// UserStore manages user data
 type UserStore struct {
 	users      map[string]*models.User // userID -> User
 	emailIndex map[string]string       // email -> userID (for lookups)
+	peerIndex  map[string]string       // peerID -> userID (peer bindings)
+	ssoIndex   map[string]string       // issuer + subject -> userID (SSO)
+	devAdminID string                  // Development admin, never saved
+	path       string                  // Empty keeps users in memory only
 	mu         sync.RWMutex
 }
 
-// NewUserStore creates a new user store
+// NewUserStore creates an empty in-memory user store
 func NewUserStore() *UserStore {
-	store := &UserStore{
+	return &UserStore{
 		users:      make(map[string]*models.User),
 		emailIndex: make(map[string]string),
+		peerIndex:  make(map[string]string),
+		ssoIndex:   make(map[string]string),
 	}
+}
 
-	// Create default admin user
-	store.createDefaultAdmin()
+// OpenUserStore opens (or creates) a user store backed by a file
+// Parameters:
+//   - path: JSON file to persist to
+//
+// Returns:
+//   - *UserStore: The loaded store
+//   - error: If an existing file can't be read or parsed
+func OpenUserStore(path string) (*UserStore, error) {
+	store := NewUserStore()
+	store.path = path
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
 
-	return store
+	var stored []storedUser
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, err
+	}
+	for _, su := range stored {
+		user := su.User
+		user.PasswordHash = su.PasswordHash
+		user.TwoFactor = su.TwoFactor
+		store.users[user.ID] = user
+		store.emailIndex[strings.ToLower(user.Email)] = user.ID
+		for _, peerID := range user.PeerIDs {
+			store.peerIndex[peerID] = user.ID
+		}
+		for _, identity := range user.Identities {
+			store.ssoIndex[identityKey(identity.Issuer, identity.Subject)] = user.ID
+		}
+	}
+
+	return store, nil
 }
 
-// createDefaultAdmin creates a default admin user for testing
-func (s *UserStore) createDefaultAdmin() {
-	adminID := uuid.New().String()
+// CreateDevAdmin creates the well-known development admin account
+// (admin@knowledge-exchange.com / admin123) unless it already exists
+// The account is kept in memory only and is gone after a restart without
+// development mode. Never call this on a real deployment.
+func (s *UserStore) CreateDevAdmin() error {
+	if _, err := s.GetByEmail(devAdminEmail); err == nil {
+		return nil
+	}
 
 	// Generate password hash for "admin123" at runtime
-	passwordHash, err := bcrypt.GenerateFromPassword([]byte("admin123"), bcrypt.DefaultCost)
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(devAdminPassword), bcrypt.DefaultCost)
 	if err != nil {
-		log.Printf("Warning: Failed to create default admin user: %v", err)
-		return
+		return err
 	}
 
 	admin := &models.User{
-		ID:           adminID,
-		Email:        "admin@knowledge-exchange.com",
+		ID:           uuid.New().String(),
+		Email:        devAdminEmail,
 		Username:     "admin",
 		PasswordHash: string(passwordHash),
 		Role:         models.RoleAdmin,
-		CreatedAt:    time.Now(),
-		IsActive:     true,
-		Reputation:   10.0,
 	}
 
-	s.users[adminID] = admin
-	s.emailIndex[strings.ToLower(admin.Email)] = adminID
-	log.Printf("✓ Default admin user created (admin@knowledge-exchange.com / admin123)")
+	// Mark the account before Create saves, so it is never written out
+	s.mu.Lock()
+	s.devAdminID = admin.ID
+	s.mu.Unlock()
+	if err := s.Create(admin); err != nil {
+		s.mu.Lock()
+		s.devAdminID = ""
+		s.mu.Unlock()
+		return err
+	}
+
+	log.Printf("⚠ Development admin user created (%s / %s)", devAdminEmail, devAdminPassword)
+	return nil
+}
+
+// DisableDevAdmin deactivates a development admin saved by an older
+// version, identified by its well-known email and password
+// Call it when not in development mode.
+// Returns true if an account was deactivated
+func (s *UserStore) DisableDevAdmin() (bool, error) {
+	user, err := s.GetByEmail(devAdminEmail)
+	if err != nil || !user.IsActive {
+		return false, nil
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(devAdminPassword)) != nil {
+		return false, nil // Someone's real account
+	}
+
+	if err := s.SetActive(user.ID, false); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// HasAdmin checks if any active admin account exists
+func (s *UserStore) HasAdmin() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, user := range s.users {
+		if user.IsActive && user.IsAdmin() {
+			return true
+		}
+	}
+	return false
 }
 
 // ============================================================================
@@ -82,6 +186,8 @@ func (s *UserStore) createDefaultAdmin() {
 // ============================================================================
 
 // Create creates a new user
+// The ID, creation time and active flag are set on user; the store keeps
+// its own copy
 func (s *UserStore) Create(user *models.User) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -106,15 +212,17 @@ func (s *UserStore) Create(user *models.User) error {
 	if user.CreatedAt.IsZero() {
 		user.CreatedAt = time.Now()
 	}
-	if user.Reputation == 0 {
-		user.Reputation = 5.0 // Default starting reputation
-	}
 	user.IsActive = true
 
 	// Store user
-	s.users[user.ID] = user
+	s.users[user.ID] = user.Clone()
 	s.emailIndex[emailLower] = user.ID
 
+	if err := s.saveLocked(); err != nil {
+		delete(s.users, user.ID)
+		delete(s.emailIndex, emailLower)
+		return err
+	}
 	return nil
 }
 
@@ -128,7 +236,7 @@ func (s *UserStore) GetByID(userID string) (*models.User, error) {
 		return nil, errors.New("user not found")
 	}
 
-	return user, nil
+	return user.Clone(), nil
 }
 
 // GetByEmail retrieves a user by email
@@ -142,27 +250,47 @@ func (s *UserStore) GetByEmail(email string) (*models.User, error) {
 		return nil, errors.New("user not found")
 	}
 
-	user := s.users[userID]
-	return user, nil
+	return s.users[userID].Clone(), nil
 }
 
-// Update updates a user
-func (s *UserStore) Update(user *models.User) error {
+// Modify changes a user under the store lock
+// change is given a copy of the stored user. The copy replaces the stored
+// user only if change returns nil, the result is valid and it is saved.
+// Parameters:
+//   - userID: The user to change
+//   - change: Edits the user; an error discards the change and is returned
+//
+// Returns:
+//   - *models.User: A copy of the changed user
+//   - error: From change, validation or saving
+func (s *UserStore) Modify(userID string, change func(user *models.User) error) (*models.User, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, exists := s.users[user.ID]; !exists {
-		return errors.New("user not found")
+	current, exists := s.users[userID]
+	if !exists {
+		return nil, errors.New("user not found")
 	}
 
-	// Validate user
-	if err := user.Validate(); err != nil {
-		return err
+	updated := current.Clone()
+	if err := change(updated); err != nil {
+		return nil, err
 	}
 
-	s.users[user.ID] = user
+	// The store's indexes can't follow these through a plain change
+	updated.ID = current.ID
+	updated.Email = current.Email
+	updated.PeerIDs = current.PeerIDs
+	updated.Identities = current.Identities
 
-	return nil
+	if err := updated.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := s.replaceLocked(updated); err != nil {
+		return nil, err
+	}
+	return updated.Clone(), nil
 }
 
 // Delete deletes a user (soft delete by setting IsActive to false)
@@ -175,9 +303,10 @@ func (s *UserStore) Delete(userID string) error {
 		return errors.New("user not found")
 	}
 
-	user.IsActive = false
+	updated := user.Clone()
+	updated.IsActive = false
 
-	return nil
+	return s.replaceLocked(updated)
 }
 
 // List returns all users
@@ -188,7 +317,7 @@ func (s *UserStore) List() []*models.User {
 	users := make([]*models.User, 0, len(s.users))
 	for _, user := range s.users {
 		if user.IsActive {
-			users = append(users, user)
+			users = append(users, user.Clone())
 		}
 	}
 
@@ -215,6 +344,7 @@ func (s *UserStore) Count() int {
 // ============================================================================
 
 // UpdateRole updates a user's role (admin only operation)
+// The caller checks that the role exists
 func (s *UserStore) UpdateRole(userID, newRole string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -224,17 +354,176 @@ func (s *UserStore) UpdateRole(userID, newRole string) error {
 		return errors.New("user not found")
 	}
 
-	if newRole != models.RoleUser && newRole != models.RoleAdmin {
+	if newRole == "" {
 		return errors.New("invalid role")
 	}
 
-	user.Role = newRole
+	updated := user.Clone()
+	updated.Role = newRole
+
+	return s.replaceLocked(updated)
+}
+
+// SetActive deactivates or reactivates an account
+// Deactivated accounts keep their data and peer bindings but can't log in
+func (s *UserStore) SetActive(userID string, active bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, exists := s.users[userID]
+	if !exists {
+		return errors.New("user not found")
+	}
+
+	updated := user.Clone()
+	updated.IsActive = active
+
+	return s.replaceLocked(updated)
+}
+
+// CountAdmins returns the number of active admin accounts
+func (s *UserStore) CountAdmins() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := 0
+	for _, user := range s.users {
+		if user.IsActive && user.IsAdmin() {
+			count++
+		}
+	}
+
+	return count
+}
+
+// CountWithRole returns the number of accounts (active or not) with a role
+func (s *UserStore) CountWithRole(role string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := 0
+	for _, user := range s.users {
+		if user.Role == role {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Search returns users whose email or username contains the query
+// (case-insensitive), oldest account first
+// Parameters:
+//   - query: Text to match; "" matches everyone
+//   - includeInactive: Also return deactivated accounts
+func (s *UserStore) Search(query string, includeInactive bool) []*models.User {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query = strings.ToLower(query)
+	users := make([]*models.User, 0)
+	for _, user := range s.users {
+		if !user.IsActive && !includeInactive {
+			continue
+		}
+		if query != "" &&
+			!strings.Contains(strings.ToLower(user.Email), query) &&
+			!strings.Contains(strings.ToLower(user.Username), query) {
+			continue
+		}
+		users = append(users, user.Clone())
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].CreatedAt.Before(users[j].CreatedAt)
+	})
+	return users
+}
+
+// ============================================================================
+// PEER BINDINGS
+// ============================================================================
+
+// BindPeer binds a peer to a user account
+// A peer belongs to at most one user; binding it again to the same user
+// is a no-op
+func (s *UserStore) BindPeer(userID, peerID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, exists := s.users[userID]
+	if !exists {
+		return errors.New("user not found")
+	}
+
+	if owner, bound := s.peerIndex[peerID]; bound {
+		if owner == userID {
+			return nil
+		}
+		return errors.New("peer is bound to another user")
+	}
+
+	updated := user.Clone()
+	updated.PeerIDs = append(updated.PeerIDs, peerID)
+	if err := s.replaceLocked(updated); err != nil {
+		return err
+	}
+	s.peerIndex[peerID] = userID
 
 	return nil
 }
 
-// UpdateReputation updates a user's reputation
-func (s *UserStore) UpdateReputation(userID string, delta float64) error {
+// UnbindPeer removes a peer from a user account
+func (s *UserStore) UnbindPeer(userID, peerID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, exists := s.users[userID]
+	if !exists {
+		return errors.New("user not found")
+	}
+
+	if s.peerIndex[peerID] != userID {
+		return errors.New("peer is not bound to this user")
+	}
+
+	updated := user.Clone()
+	remaining := make([]string, 0, len(user.PeerIDs))
+	for _, id := range user.PeerIDs {
+		if id != peerID {
+			remaining = append(remaining, id)
+		}
+	}
+	updated.PeerIDs = remaining
+	if err := s.replaceLocked(updated); err != nil {
+		return err
+	}
+	delete(s.peerIndex, peerID)
+
+	return nil
+}
+
+// GetByPeerID retrieves the user a peer is bound to
+func (s *UserStore) GetByPeerID(peerID string) (*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	userID, bound := s.peerIndex[peerID]
+	if !bound {
+		return nil, errors.New("peer is not bound to a user")
+	}
+
+	return s.users[userID].Clone(), nil
+}
+
+// ============================================================================
+// SINGLE SIGN-ON IDENTITIES
+// ============================================================================
+
+// LinkIdentity links an identity provider account to a user
+// An identity belongs to at most one user; linking it again to the same
+// user is a no-op
+func (s *UserStore) LinkIdentity(userID string, identity models.LinkedIdentity) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -243,15 +532,106 @@ func (s *UserStore) UpdateReputation(userID string, delta float64) error {
 		return errors.New("user not found")
 	}
 
-	user.Reputation += delta
+	key := identityKey(identity.Issuer, identity.Subject)
+	if owner, linked := s.ssoIndex[key]; linked {
+		if owner == userID {
+			return nil
+		}
+		return errors.New("identity is linked to another user")
+	}
 
-	// Ensure reputation stays within bounds
-	if user.Reputation < 0 {
-		user.Reputation = 0
+	if identity.LinkedAt.IsZero() {
+		identity.LinkedAt = time.Now()
 	}
-	if user.Reputation > 10 {
-		user.Reputation = 10
+	updated := user.Clone()
+	updated.Identities = append(updated.Identities, identity)
+	if err := s.replaceLocked(updated); err != nil {
+		return err
 	}
+	s.ssoIndex[key] = userID
 
 	return nil
 }
+
+// GetByIdentity retrieves the user an identity provider account is linked to
+func (s *UserStore) GetByIdentity(issuer, subject string) (*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	userID, linked := s.ssoIndex[identityKey(issuer, subject)]
+	if !linked {
+		return nil, errors.New("identity is not linked to a user")
+	}
+
+	return s.users[userID].Clone(), nil
+}
+
+// identityKey is the index key for an identity
+// Subjects are only unique per issuer
+func identityKey(issuer, subject string) string {
+	return issuer + " " + subject
+}
+
+// ============================================================================
+// PERSISTENCE
+// ============================================================================
+
+// storedUser is the on-disk form of a user
+// PasswordHash and TwoFactor are hidden from API responses, so they are
+// stored separately
+type storedUser struct {
+	*models.User
+	PasswordHash string            `json:"password_hash"`
+	TwoFactor    *models.TwoFactor `json:"two_factor,omitempty"`
+}
+
+// replaceLocked swaps in a changed copy of a stored user and saves
+// The previous user is put back if saving fails
+// Caller must hold s.mu
+func (s *UserStore) replaceLocked(updated *models.User) error {
+	previous := s.users[updated.ID]
+	s.users[updated.ID] = updated
+
+	if err := s.saveLocked(); err != nil {
+		s.users[updated.ID] = previous
+		return err
+	}
+	return nil
+}
+
+// saveLocked writes all users to disk (owner-readable only)
+// Caller must hold s.mu
+func (s *UserStore) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+
+	stored := make([]storedUser, 0, len(s.users))
+	for _, user := range s.users {
+		if user.ID == s.devAdminID {
+			continue
+		}
+		stored = append(stored, storedUser{
+			User:         user,
+			PasswordHash: user.PasswordHash,
+			TwoFactor:    user.TwoFactor,
+		})
+	}
+	sort.Slice(stored, func(i, j int) bool {
+		return stored[i].CreatedAt.Before(stored[j].CreatedAt)
+	})
+
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -32,18 +32,6 @@ const (
 	DefaultServerPort = 8080
 	DefaultAPIPort    = 3000
 
-	// Reputation System
-	MinReputationToDownload = 3.0
-	MaxReputation           = 10.0
-	DefaultReputation       = 5.0
-	ReputationGainPerUpload = 0.5
-	ReputationLossPerLeech  = 0.2
-
-	// Throttling
-	LeecherBandwidthLimit = 100 * 1024       // 100 KB/s for leechers
-	NormalBandwidthLimit  = 1024 * 1024      // 1 MB/s for normal users
-	PremiumBandwidthLimit = 10 * 1024 * 1024 // 10 MB/s for high reputation
-
 	// File Limits
 	MaxFileSizeBytes       = 100 * 1024 * 1024 // 100 MB
 	MaxConcurrentUploads   = 5
@@ -53,6 +41,7 @@ const (
 	PeerTimeoutSeconds       = 30
 	TransferTimeoutSeconds   = 300
 	HeartbeatIntervalSeconds = 10
+	TrustGossipSeconds       = 300
 
 	// Storage
 	DefaultDataDir = "./data"
@@ -81,12 +70,43 @@ type Config struct {
 	TempDir        string `json:"temp_dir"`
 
 	// Reputation Settings
-	MinReputation float64 `json:"min_reputation"`
-	MaxReputation float64 `json:"max_reputation"`
-
-	// Throttling Settings
-	LeecherBandwidth int64 `json:"leecher_bandwidth"`
-	NormalBandwidth  int64 `json:"normal_bandwidth"`
+	// Thresholds, event weights and bandwidth tiers live in a reputation
+	// policy file; empty uses the built-in default policy
+	ReputationPolicyFile string `json:"reputation_policy_file"`
+
+	// Authentication
+	// DevMode creates the well-known development admin account; never
+	// enable it on a real deployment
+	DevMode bool `json:"dev_mode"`
+
+	// JWTSecret pins a shared HS256 secret (e.g. from KX_JWT_SECRET); when
+	// empty, signing keys of JWTAlgorithm are generated and kept in DataDir
+	JWTSecret    string `json:"jwt_secret,omitempty"`
+	JWTAlgorithm string `json:"jwt_algorithm"`
+
+	// RequireAdminTwoFactor keeps admin endpoints closed to admins who
+	// haven't enabled two-factor authentication
+	RequireAdminTwoFactor bool `json:"require_admin_two_factor"`
+
+	// Mail (password reset and email verification)
+	// With SMTPHost empty, messages are written to DataDir/mail and logged
+	SMTPHost     string `json:"smtp_host,omitempty"`
+	SMTPPort     int    `json:"smtp_port"`
+	SMTPUsername string `json:"smtp_username,omitempty"`
+	SMTPPassword string `json:"smtp_password,omitempty"` // e.g. from KX_SMTP_PASSWORD
+	MailFrom     string `json:"mail_from"`
+
+	// PublicURL is the frontend address used in emailed links
+	PublicURL string `json:"public_url"`
+
+	// Single sign-on through an OpenID Connect provider (e.g. the
+	// university's); disabled while OIDCIssuer is empty
+	OIDCIssuer       string   `json:"oidc_issuer,omitempty"`
+	OIDCClientID     string   `json:"oidc_client_id,omitempty"`
+	OIDCClientSecret string   `json:"oidc_client_secret,omitempty"` // e.g. from KX_OIDC_CLIENT_SECRET
+	OIDCRedirectURL  string   `json:"oidc_redirect_url,omitempty"`  // Empty uses PublicURL + "/sso/callback"
+	OIDCDisplayName  string   `json:"oidc_display_name"`
+	OIDCScopes       []string `json:"oidc_scopes"`
 
 	// Timeouts
 	PeerTimeout     time.Duration `json:"peer_timeout"`
@@ -97,9 +117,14 @@ type Config struct {
 	MaxConcurrentTx int   `json:"max_concurrent_tx"`
 
 	// Feature Flags
-	EnableThrottling bool `json:"enable_throttling"`
-	EnableRatings    bool `json:"enable_ratings"`
-	EnableEncryption bool `json:"enable_encryption"`
+	EnableThrottling  bool `json:"enable_throttling"`
+	EnableRatings     bool `json:"enable_ratings"`
+	EnableEncryption  bool `json:"enable_encryption"`
+	EnableTrustGossip bool `json:"enable_trust_gossip"`
+
+	// Trust Gossip Settings
+	PreTrustedPeers     []string      `json:"pre_trusted_peers"`
+	TrustGossipInterval time.Duration `json:"trust_gossip_interval"`
 }
 
 // DefaultConfig returns a configuration with default values
@@ -113,10 +138,6 @@ func DefaultConfig() *Config {
 		DataDir:          DefaultDataDir,
 		SharedFilesDir:   SharedFilesDir,
 		TempDir:          TempDir,
-		MinReputation:    MinReputationToDownload,
-		MaxReputation:    MaxReputation,
-		LeecherBandwidth: LeecherBandwidthLimit,
-		NormalBandwidth:  NormalBandwidthLimit,
 		PeerTimeout:      time.Duration(PeerTimeoutSeconds) * time.Second,
 		TransferTimeout:  time.Duration(TransferTimeoutSeconds) * time.Second,
 		MaxFileSize:      MaxFileSizeBytes,
@@ -124,6 +145,20 @@ func DefaultConfig() *Config {
 		EnableThrottling: true,
 		EnableRatings:    true,
 		EnableEncryption: false,
+
+		EnableTrustGossip:   false,
+		PreTrustedPeers:     []string{},
+		TrustGossipInterval: time.Duration(TrustGossipSeconds) * time.Second,
+
+		JWTAlgorithm:          "HS256",
+		RequireAdminTwoFactor: true,
+
+		SMTPPort:  587,
+		MailFrom:  "no-reply@knowledge-exchange.local",
+		PublicURL: "http://localhost:3000",
+
+		OIDCDisplayName: "University SSO",
+		OIDCScopes:      []string{"openid", "email", "profile"},
 	}
 }
 
@@ -3,6 +3,9 @@ import { AuthProvider } from './context/AuthContext'
 import { ToastProvider } from './context/ToastContext'
 import Login from './pages/Login'
 import Signup from './pages/Signup'
+import ResetPassword from './pages/ResetPassword'
+import VerifyEmail from './pages/VerifyEmail'
+import SSOCallback from './pages/SSOCallback'
 import Home from './pages/Home'
 import Library from './pages/Library'
 import Upload from './pages/Upload'
@@ -21,6 +24,9 @@ function App() {
             {/* Public routes */}
             <Route path="/login" element={<Login />} />
             <Route path="/signup" element={<Signup />} />
+            <Route path="/reset-password" element={<ResetPassword />} />
+            <Route path="/verify-email" element={<VerifyEmail />} />
+            <Route path="/sso/callback" element={<SSOCallback />} />
             
             {/* Protected routes */}
             <Route
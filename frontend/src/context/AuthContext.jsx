@@ -33,15 +33,24 @@ export const AuthProvider = ({ children }) => {
     }
   };
 
+  const startSession = ({ user: userData, token: authToken, refresh_token: refreshToken }) => {
+    setUser(userData);
+    setToken(authToken);
+    setIsAuthenticated(true);
+    localStorage.setItem('token', authToken);
+    localStorage.setItem('refreshToken', refreshToken);
+  };
+
+  // With 2FA on, a correct password returns a challenge instead of a session
   const login = async (email, password) => {
     try {
       const response = await api.login(email, password);
       if (response.data.success) {
-        const { user: userData, token: authToken } = response.data.data;
-        setUser(userData);
-        setToken(authToken);
-        setIsAuthenticated(true);
-        localStorage.setItem('token', authToken);
+        const data = response.data.data;
+        if (data.two_factor_required) {
+          return { success: true, twoFactorRequired: true, challengeToken: data.challenge_token };
+        }
+        startSession(data);
         return { success: true };
       }
     } catch (error) {
@@ -50,6 +59,38 @@ export const AuthProvider = ({ children }) => {
     }
   };
 
+  const completeTwoFactor = async (challengeToken, code) => {
+    try {
+      const response = await api.loginTwoFactor(challengeToken, code);
+      if (response.data.success) {
+        startSession(response.data.data);
+        return { success: true, message: response.data.message };
+      }
+    } catch (error) {
+      const message = error.response?.data?.error || 'Login failed';
+      return { success: false, error: message };
+    }
+  };
+
+  // Finishes a login at an identity provider; like a password login it
+  // may still need the second factor
+  const completeSSO = async (state, code) => {
+    try {
+      const response = await api.completeSSO(state, code);
+      if (response.data.success) {
+        const data = response.data.data;
+        if (data.two_factor_required) {
+          return { success: true, twoFactorRequired: true, challengeToken: data.challenge_token };
+        }
+        startSession(data);
+        return { success: true };
+      }
+    } catch (error) {
+      const message = error.response?.data?.error || 'Sign-in failed';
+      return { success: false, error: message };
+    }
+  };
+
   const signup = async (email, username, password) => {
     try {
       const response = await api.register(email, username, password);
@@ -63,24 +104,36 @@ export const AuthProvider = ({ children }) => {
   };
 
   const logout = () => {
+    // Revoke server-side before the token is cleared from storage
+    const refreshToken = localStorage.getItem('refreshToken');
+    api.logout(refreshToken).catch(() => {}).finally(() => {
+      localStorage.removeItem('token');
+      localStorage.removeItem('refreshToken');
+    });
     setUser(null);
     setToken(null);
     setIsAuthenticated(false);
-    localStorage.removeItem('token');
-    api.logout().catch(() => {});
   };
 
   const isAdmin = () => {
     return user?.role === 'admin';
   };
 
+  // Permissions come from the user's role (see /auth/me)
+  const hasPermission = (permission) => {
+    return isAdmin() || (user?.permissions || []).includes(permission);
+  };
+
   const value = {
     user,
     token,
     isAuthenticated,
     isLoading,
     isAdmin,
+    hasPermission,
     login,
+    completeTwoFactor,
+    completeSSO,
     signup,
     logout,
   };
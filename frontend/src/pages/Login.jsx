@@ -1,23 +1,37 @@
-import { useState } from 'react';
-import { useNavigate, Link } from 'react-router-dom';
+import { useState, useEffect } from 'react';
+import { useNavigate, useLocation, Link } from 'react-router-dom';
 import { useAuth } from '../context/AuthContext';
 import { useToast } from '../context/ToastContext';
+import api from '../services/api';
 
 const Login = () => {
   const [email, setEmail] = useState('');
   const [password, setPassword] = useState('');
+  const location = useLocation();
+  // A single sign-on that needs the second factor lands here mid-login
+  const [challengeToken, setChallengeToken] = useState(location.state?.challengeToken || null);
+  const [code, setCode] = useState('');
   const [loading, setLoading] = useState(false);
+  const [providers, setProviders] = useState([]);
   const navigate = useNavigate();
-  const { login } = useAuth();
+  const { login, completeTwoFactor } = useAuth();
   const { showToast } = useToast();
 
+  useEffect(() => {
+    api.getSSOProviders()
+      .then((response) => setProviders(response.data.data || []))
+      .catch(() => setProviders([]));
+  }, []);
+
   const handleSubmit = async (e) => {
     e.preventDefault();
     setLoading(true);
 
     const result = await login(email, password);
     
-    if (result.success) {
+    if (result.twoFactorRequired) {
+      setChallengeToken(result.challengeToken);
+    } else if (result.success) {
       showToast('Welcome back! Login successful.', 'success');
       navigate('/home');
     } else {
@@ -27,6 +41,42 @@ const Login = () => {
     setLoading(false);
   };
 
+  // Sends the browser to the identity provider; SSOCallback finishes the
+  // login and checks the state it comes back with
+  const handleSSO = async (provider) => {
+    setLoading(true);
+    try {
+      const response = await api.startSSO(provider);
+      const { authorization_url: url, state } = response.data.data;
+      sessionStorage.setItem('ssoState', state);
+      window.location.assign(url);
+    } catch (error) {
+      showToast(error.response?.data?.error || 'Single sign-on is unavailable', 'error');
+      setLoading(false);
+    }
+  };
+
+  // Second step: a code from the authenticator app or a recovery code.
+  // A challenge is single-use, so a wrong code goes back to the password.
+  const handleCode = async (e) => {
+    e.preventDefault();
+    setLoading(true);
+
+    const result = await completeTwoFactor(challengeToken, code);
+
+    if (result.success) {
+      showToast(result.message || 'Welcome back! Login successful.', 'success');
+      navigate('/home');
+    } else {
+      showToast(result.error || 'Login failed', 'error');
+      setChallengeToken(null);
+      setCode('');
+      setPassword('');
+    }
+
+    setLoading(false);
+  };
+
   return (
     <div className="auth-page">
       {/* Animated background orbs */}
@@ -47,61 +97,102 @@ const Login = () => {
             <p>Sign in to The Knowledge Exchange</p>
           </div>
 
-          <form onSubmit={handleSubmit} className="auth-form">
-            <div className="form-group">
-              <label htmlFor="email">Email Address</label>
-              <input
-                type="email"
-                id="email"
-                value={email}
-                onChange={(e) => setEmail(e.target.value)}
-                required
-                placeholder="you@example.com"
-                autoComplete="email"
-              />
-            </div>
+          {challengeToken ? (
+            <form onSubmit={handleCode} className="auth-form">
+              <div className="form-group">
+                <label htmlFor="code">Authentication Code</label>
+                <input
+                  type="text"
+                  id="code"
+                  value={code}
+                  onChange={(e) => setCode(e.target.value)}
+                  required
+                  autoFocus
+                  placeholder="123456 or a recovery code"
+                  autoComplete="one-time-code"
+                />
+              </div>
 
-            <div className="form-group">
-              <label htmlFor="password">Password</label>
-              <input
-                type="password"
-                id="password"
-                value={password}
-                onChange={(e) => setPassword(e.target.value)}
-                required
-                placeholder="••••••••"
-                autoComplete="current-password"
-              />
-            </div>
+              <button type="submit" className="btn btn-primary btn-block btn-lg" disabled={loading}>
+                {loading ? 'Verifying...' : '🔐 Verify'}
+              </button>
+            </form>
+          ) : (
+            <form onSubmit={handleSubmit} className="auth-form">
+              <div className="form-group">
+                <label htmlFor="email">Email Address</label>
+                <input
+                  type="email"
+                  id="email"
+                  value={email}
+                  onChange={(e) => setEmail(e.target.value)}
+                  required
+                  placeholder="you@example.com"
+                  autoComplete="email"
+                />
+              </div>
+
+              <div className="form-group">
+                <label htmlFor="password">Password</label>
+                <input
+                  type="password"
+                  id="password"
+                  value={password}
+                  onChange={(e) => setPassword(e.target.value)}
+                  required
+                  placeholder="••••••••"
+                  autoComplete="current-password"
+                />
+              </div>
+
+              <button 
+                type="submit" 
+                className="btn btn-primary btn-block btn-lg" 
+                disabled={loading}
+              >
+                {loading ? (
+                  <>
+                    <span className="loading-spinner" style={{width: '20px', height: '20px', marginBottom: 0}}></span>
+                    Signing in...
+                  </>
+                ) : (
+                  <>
+                    🚀 Sign In
+                  </>
+                )}
+              </button>
+            </form>
+          )}
 
-            <button 
-              type="submit" 
-              className="btn btn-primary btn-block btn-lg" 
-              disabled={loading}
-            >
-              {loading ? (
-                <>
-                  <span className="loading-spinner" style={{width: '20px', height: '20px', marginBottom: 0}}></span>
-                  Signing in...
-                </>
-              ) : (
-                <>
-                  🚀 Sign In
-                </>
-              )}
-            </button>
-          </form>
+          {!challengeToken && providers.length > 0 && (
+            <div className="auth-form">
+              {providers.map((provider) => (
+                <button
+                  key={provider.name}
+                  type="button"
+                  className="btn btn-secondary btn-block"
+                  onClick={() => handleSSO(provider.name)}
+                  disabled={loading}
+                >
+                  🎓 Sign in with {provider.display_name}
+                </button>
+              ))}
+            </div>
+          )}
 
           <div className="auth-footer">
             <p>Don't have an account? <Link to="/signup">Create one</Link></p>
+            <p><Link to="/reset-password">Forgot your password?</Link></p>
           </div>
 
-          <div className="auth-demo">
-            <p><strong>🔐 Demo Credentials</strong></p>
-            <p style={{marginTop: '0.5rem'}}>
-              <code>admin@knowledge-exchange.com</code> / <code>admin123</code>
-            </p>
-          </div>
+          {import.meta.env.DEV && (
+            <div className="auth-demo">
+              <p><strong>🔐 Demo Credentials</strong> (backend started with <code>-dev</code>)</p>
+              <p style={{marginTop: '0.5rem'}}>
+                <code>admin@knowledge-exchange.com</code> / <code>admin123</code>
+              </p>
+            </div>
+          )}
         </div>
 
         <div className="auth-features">
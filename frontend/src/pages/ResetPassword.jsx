@@ -0,0 +1,127 @@
+import { useState } from 'react';
+import { useNavigate, useSearchParams, Link } from 'react-router-dom';
+import { useToast } from '../context/ToastContext';
+import api from '../services/api';
+
+// Without a token: ask for a reset link. With ?token=: choose a new password.
+const ResetPassword = () => {
+  const [searchParams] = useSearchParams();
+  const token = searchParams.get('token');
+  const [email, setEmail] = useState('');
+  const [password, setPassword] = useState('');
+  const [confirmPassword, setConfirmPassword] = useState('');
+  const [sent, setSent] = useState(false);
+  const [loading, setLoading] = useState(false);
+  const navigate = useNavigate();
+  const { showToast } = useToast();
+
+  const handleRequest = async (e) => {
+    e.preventDefault();
+    setLoading(true);
+    try {
+      await api.forgotPassword(email);
+      setSent(true);
+    } catch (error) {
+      showToast(error.response?.data?.error || 'Failed to request reset', 'error');
+    }
+    setLoading(false);
+  };
+
+  const handleReset = async (e) => {
+    e.preventDefault();
+    if (password !== confirmPassword) {
+      showToast('Passwords do not match', 'error');
+      return;
+    }
+    setLoading(true);
+    try {
+      await api.resetPassword(token, password);
+      showToast('Password reset. Please sign in.', 'success');
+      navigate('/login');
+    } catch (error) {
+      showToast(error.response?.data?.error || 'Failed to reset password', 'error');
+    }
+    setLoading(false);
+  };
+
+  return (
+    <div className="auth-page">
+      <div className="auth-container">
+        <div className="auth-card">
+          <div className="auth-header">
+            <div className="auth-logo">
+              <span className="logo-icon">🔑</span>
+            </div>
+            <h2>{token ? 'Choose a New Password' : 'Reset Password'}</h2>
+            <p>
+              {token
+                ? 'Enter a new password for your account'
+                : "We'll email you a link to reset your password"}
+            </p>
+          </div>
+
+          {token ? (
+            <form onSubmit={handleReset} className="auth-form">
+              <div className="form-group">
+                <label htmlFor="password">New Password</label>
+                <input
+                  type="password"
+                  id="password"
+                  value={password}
+                  onChange={(e) => setPassword(e.target.value)}
+                  required
+                  minLength={6}
+                  placeholder="••••••••"
+                  autoComplete="new-password"
+                />
+              </div>
+              <div className="form-group">
+                <label htmlFor="confirmPassword">Confirm Password</label>
+                <input
+                  type="password"
+                  id="confirmPassword"
+                  value={confirmPassword}
+                  onChange={(e) => setConfirmPassword(e.target.value)}
+                  required
+                  placeholder="••••••••"
+                  autoComplete="new-password"
+                />
+              </div>
+              <button type="submit" className="btn btn-primary btn-block btn-lg" disabled={loading}>
+                {loading ? 'Saving...' : 'Reset Password'}
+              </button>
+            </form>
+          ) : sent ? (
+            <p style={{textAlign: 'center'}}>
+              If <strong>{email}</strong> is registered, a reset link is on its way.
+            </p>
+          ) : (
+            <form onSubmit={handleRequest} className="auth-form">
+              <div className="form-group">
+                <label htmlFor="email">Email Address</label>
+                <input
+                  type="email"
+                  id="email"
+                  value={email}
+                  onChange={(e) => setEmail(e.target.value)}
+                  required
+                  placeholder="you@example.com"
+                  autoComplete="email"
+                />
+              </div>
+              <button type="submit" className="btn btn-primary btn-block btn-lg" disabled={loading}>
+                {loading ? 'Sending...' : 'Send Reset Link'}
+              </button>
+            </form>
+          )}
+
+          <div className="auth-footer">
+            <p>Remembered it? <Link to="/login">Sign in</Link></p>
+          </div>
+        </div>
+      </div>
+    </div>
+  );
+};
+
+export default ResetPassword;
@@ -0,0 +1,70 @@
+import { useEffect, useRef, useState } from 'react';
+import { useSearchParams, useNavigate, Link } from 'react-router-dom';
+import { useAuth } from '../context/AuthContext';
+import { useToast } from '../context/ToastContext';
+
+// The identity provider redirects here with ?code=&state= (or ?error=)
+const SSOCallback = () => {
+  const [searchParams] = useSearchParams();
+  const [error, setError] = useState('');
+  const submitted = useRef(false);
+  const navigate = useNavigate();
+  const { completeSSO } = useAuth();
+  const { showToast } = useToast();
+
+  useEffect(() => {
+    // Codes are single-use, so only submit once
+    if (submitted.current) return;
+    submitted.current = true;
+
+    const state = searchParams.get('state');
+    const code = searchParams.get('code');
+    const expected = sessionStorage.getItem('ssoState');
+    sessionStorage.removeItem('ssoState');
+
+    if (searchParams.get('error')) {
+      setError(searchParams.get('error_description') || 'Sign-in was cancelled');
+      return;
+    }
+    // Only finish a login this browser started
+    if (!state || !code || state !== expected) {
+      setError('Sign-in link is invalid or was already used');
+      return;
+    }
+
+    completeSSO(state, code).then((result) => {
+      if (result.twoFactorRequired) {
+        navigate('/login', { replace: true, state: { challengeToken: result.challengeToken } });
+      } else if (result.success) {
+        showToast('Welcome! Login successful.', 'success');
+        navigate('/home', { replace: true });
+      } else {
+        setError(result.error || 'Sign-in failed');
+      }
+    });
+  }, [searchParams]);
+
+  return (
+    <div className="auth-page">
+      <div className="auth-container">
+        <div className="auth-card">
+          <div className="auth-header">
+            <div className="auth-logo">
+              <span className="logo-icon">{error ? '⚠️' : '🎓'}</span>
+            </div>
+            <h2>{error ? 'Sign-in Failed' : 'Signing in...'}</h2>
+            {error && <p>{error}</p>}
+          </div>
+
+          {error && (
+            <div className="auth-footer">
+              <p><Link to="/login">Back to sign in</Link></p>
+            </div>
+          )}
+        </div>
+      </div>
+    </div>
+  );
+};
+
+export default SSOCallback;
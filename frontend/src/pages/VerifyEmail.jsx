@@ -0,0 +1,49 @@
+import { useEffect, useRef, useState } from 'react';
+import { useSearchParams, Link } from 'react-router-dom';
+import api from '../services/api';
+
+// Confirms the email address from the ?token= in a verification link
+const VerifyEmail = () => {
+  const [searchParams] = useSearchParams();
+  const token = searchParams.get('token');
+  const [status, setStatus] = useState(token ? 'verifying' : 'error');
+  const [message, setMessage] = useState(token ? '' : 'Verification link is missing its token');
+  const submitted = useRef(false);
+
+  useEffect(() => {
+    // Tokens are single-use, so only submit once
+    if (!token || submitted.current) return;
+    submitted.current = true;
+
+    api.confirmVerification(token)
+      .then(() => setStatus('verified'))
+      .catch((error) => {
+        setStatus('error');
+        setMessage(error.response?.data?.error || 'Verification failed');
+      });
+  }, [token]);
+
+  return (
+    <div className="auth-page">
+      <div className="auth-container">
+        <div className="auth-card">
+          <div className="auth-header">
+            <div className="auth-logo">
+              <span className="logo-icon">{status === 'verified' ? '✅' : status === 'error' ? '⚠️' : '✉️'}</span>
+            </div>
+            <h2>
+              {status === 'verified' ? 'Email Verified' : status === 'error' ? 'Verification Failed' : 'Verifying...'}
+            </h2>
+            {status === 'error' && <p>{message}</p>}
+          </div>
+
+          <div className="auth-footer">
+            <p><Link to="/login">Continue to sign in</Link></p>
+          </div>
+        </div>
+      </div>
+    </div>
+  );
+};
+
+export default VerifyEmail;
@@ -19,21 +19,117 @@ apiClient.interceptors.request.use((config) => {
     return config;
 });
 
+// On 401, trade the refresh token for a new pair once and retry
+let refreshing = null;
+apiClient.interceptors.response.use(
+    (response) => response,
+    async (error) => {
+        const original = error.config;
+        const refreshToken = localStorage.getItem('refreshToken');
+        if (error.response?.status !== 401 || !refreshToken || original._retried
+            || original.url === '/auth/refresh') {
+            return Promise.reject(error);
+        }
+        original._retried = true;
+
+        try {
+            refreshing = refreshing || apiClient.post('/auth/refresh', { refresh_token: refreshToken });
+            const response = await refreshing;
+            const { token, refresh_token: nextRefreshToken } = response.data.data;
+            localStorage.setItem('token', token);
+            localStorage.setItem('refreshToken', nextRefreshToken);
+            return apiClient(original);
+        } catch (refreshError) {
+            localStorage.removeItem('token');
+            localStorage.removeItem('refreshToken');
+            return Promise.reject(error);
+        } finally {
+            refreshing = null;
+        }
+    }
+);
+
 // API Service
 const api = {
     // Authentication
+    getSetupStatus: () =>
+        apiClient.get('/auth/setup/status'),
+
+    completeSetup: (setupToken, email, username, password) =>
+        apiClient.post('/auth/setup', { setup_token: setupToken, email, username, password }),
+
     register: (email, username, password) =>
         apiClient.post('/auth/register', { email, username, password }),
 
     login: (email, password) =>
         apiClient.post('/auth/login', { email, password }),
 
-    logout: () =>
-        apiClient.post('/auth/logout'),
+    loginTwoFactor: (challengeToken, code) =>
+        apiClient.post('/auth/login/2fa', { challenge_token: challengeToken, code }),
+
+    // Single sign-on (OpenID Connect)
+    getSSOProviders: () =>
+        apiClient.get('/auth/sso'),
+
+    startSSO: (provider) =>
+        apiClient.post('/auth/sso/start', { provider }),
+
+    completeSSO: (state, code) =>
+        apiClient.post('/auth/sso/callback', { state, code }),
+
+    logout: (refreshToken) =>
+        apiClient.post('/auth/logout', { refresh_token: refreshToken }),
+
+    refreshSession: (refreshToken) =>
+        apiClient.post('/auth/refresh', { refresh_token: refreshToken }),
+
+    changePassword: (currentPassword, newPassword) =>
+        apiClient.post('/auth/password', { current_password: currentPassword, new_password: newPassword }),
+
+    forgotPassword: (email) =>
+        apiClient.post('/auth/password/forgot', { email }),
+
+    resetPassword: (token, newPassword) =>
+        apiClient.post('/auth/password/reset', { token, new_password: newPassword }),
+
+    requestVerification: () =>
+        apiClient.post('/auth/verify/request'),
+
+    confirmVerification: (token) =>
+        apiClient.post('/auth/verify/confirm', { token }),
+
+    // Two-factor authentication
+    getTwoFactorStatus: () =>
+        apiClient.get('/auth/2fa'),
+
+    setupTwoFactor: (password) =>
+        apiClient.post('/auth/2fa/setup', { password }),
+
+    enableTwoFactor: (code) =>
+        apiClient.post('/auth/2fa/enable', { code }),
+
+    disableTwoFactor: (password, code) =>
+        apiClient.post('/auth/2fa/disable', { password, code }),
+
+    regenerateRecoveryCodes: (code) =>
+        apiClient.post('/auth/2fa/recovery-codes', { code }),
+
+    // API keys (for scripts; sent as the X-API-Key header)
+    listAPIKeys: () =>
+        apiClient.get('/auth/keys'),
+
+    createAPIKey: (name, scopes, expiresInDays) =>
+        apiClient.post('/auth/keys/create', { name, scopes, expires_in_days: expiresInDays }),
+
+    revokeAPIKey: (id) =>
+        apiClient.post('/auth/keys/revoke', { id }),
 
     getCurrentUser: () =>
         apiClient.get('/auth/me'),
 
+    unbindPeer: (peerId) =>
+        apiClient.post('/auth/peers/unbind', { peer_id: peerId }),
+
     // Health & Stats
     checkHealth: () =>
         apiClient.get('/health'),
@@ -63,28 +159,104 @@ const api = {
             headers: { 'Content-Type': 'multipart/form-data' },
         }),
 
-    downloadFile: (cid, requesterId) =>
-        apiClient.get(`/files/download?cid=${cid}&requester_id=${requesterId}`),
+    getTopFiles: (params = {}) =>
+        apiClient.get('/files/top', { params }),
+
+    downloadFile: (cid) =>
+        apiClient.get(`/files/download?cid=${cid}`),
+
+    // Official course material (pinning needs the files:pin permission)
+    getPinnedFiles: () =>
+        apiClient.get('/files/pinned'),
+
+    pinFile: (cid, note) =>
+        apiClient.post('/files/pin', { cid, note }),
+
+    unpinFile: (cid) =>
+        apiClient.post('/files/unpin', { cid }),
+
+    // Reports and moderation
+    reportContent: (targetType, targetId, reason) =>
+        apiClient.post('/reports', { target_type: targetType, target_id: targetId, reason }),
+
+    listReports: (status) =>
+        apiClient.get('/moderation/reports', { params: status ? { status } : {} }),
+
+    resolveReport: (id, action, note) =>
+        apiClient.post('/moderation/reports/resolve', { id, action, note }),
 
     // Reputation
+    // Omit peerId to get the logged-in account's combined reputation
     getReputation: (peerId) =>
-        apiClient.get(`/reputation?peer_id=${peerId}`),
+        apiClient.get('/reputation', { params: peerId ? { peer_id: peerId } : {} }),
 
     getReputationHistory: (peerId) =>
         apiClient.get(`/reputation/history?peer_id=${peerId}`),
 
-    getTopContributors: () =>
-        apiClient.get('/reputation/top'),
+    getTopContributors: (params = {}) =>
+        apiClient.get('/reputation/top', { params }),
+
+    // Ratings (the rater is the logged-in user's peer)
+    rateFile: (fileCid, score, comment) =>
+        apiClient.post('/ratings/file', { file_cid: fileCid, score, comment }),
+
+    ratePeer: (targetId, score, comment) =>
+        apiClient.post('/ratings/peer', { target_id: targetId, score, comment }),
 
-    // Ratings
-    rateFile: (raterId, fileCid, score, comment) =>
-        apiClient.post('/ratings/file', { rater_id: raterId, file_cid: fileCid, score, comment }),
+    updateRating: (ratingId, score, comment) =>
+        apiClient.post('/ratings/update', { rating_id: ratingId, score, comment }),
 
-    ratePeer: (raterId, targetId, score, comment) =>
-        apiClient.post('/ratings/peer', { rater_id: raterId, target_id: targetId, score, comment }),
+    deleteRating: (ratingId) =>
+        apiClient.post('/ratings/delete', { rating_id: ratingId }),
 
     getRatings: (targetId, type) =>
         apiClient.get(`/ratings?target_id=${targetId}&type=${type}`),
+
+    // Admin
+    listUsers: (params = {}) =>
+        apiClient.get('/admin/users', { params }),
+
+    setUserRole: (userId, role) =>
+        apiClient.post('/admin/users/role', { user_id: userId, role }),
+
+    deactivateUser: (userId) =>
+        apiClient.post('/admin/users/deactivate', { user_id: userId }),
+
+    reactivateUser: (userId) =>
+        apiClient.post('/admin/users/reactivate', { user_id: userId }),
+
+    resetUserTwoFactor: (userId) =>
+        apiClient.post('/admin/users/2fa/reset', { user_id: userId }),
+
+    listRoles: () =>
+        apiClient.get('/admin/roles'),
+
+    saveRole: (name, description, permissions) =>
+        apiClient.post('/admin/roles/save', { name, description, permissions }),
+
+    deleteRole: (name) =>
+        apiClient.post('/admin/roles/delete', { name }),
+
+    getLockouts: (limit) =>
+        apiClient.get('/admin/auth/lockouts', { params: { limit } }),
+
+    unlockLogin: (target) =>
+        apiClient.post('/admin/auth/unlock', target),
+
+    adjustReputation: (peerId, delta, reason) =>
+        apiClient.post('/admin/reputation/adjust', { peer_id: peerId, delta, reason }),
+
+    removeFile: (cid, reason) =>
+        apiClient.post('/admin/files/remove', { cid, reason }),
+
+    kickPeer: (peerId, reason) =>
+        apiClient.post('/admin/peers/kick', { peer_id: peerId, reason }),
+
+    banPeer: (peerId, reason) =>
+        apiClient.post('/admin/peers/ban', { peer_id: peerId, reason }),
+
+    unbanPeer: (peerId) =>
+        apiClient.post('/admin/peers/unban', { peer_id: peerId }),
 };
 
 export default api;